@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,6 +16,8 @@ import (
 	"video-player-backend/internal/database"
 	"video-player-backend/internal/handlers"
 	"video-player-backend/internal/middleware"
+	"video-player-backend/internal/services"
+	"video-player-backend/internal/storage"
 
 	"github.com/joho/godotenv"
 )
@@ -48,6 +51,30 @@ func loadEnv() {
 	log.Printf("Loaded env files: %v", loaded)
 }
 
+// newSubtitleStore creates the store for subtitle files from STORAGE_BACKEND (local, memory or s3).
+// The s3 backend works with any S3-compatible service such as AWS S3, MinIO or R2.
+func newSubtitleStore(signer *storage.URLSigner) (storage.BlobStore, error) {
+	switch backend := strings.ToLower(os.Getenv("STORAGE_BACKEND")); backend {
+	case "", "local":
+		return storage.NewLocalStorage("./uploads/vtt", services.SubtitleURLPrefix, signer), nil
+	case "memory":
+		log.Println("Subtitle files are kept in memory and will be lost on restart")
+		return storage.NewMemoryStorage(services.SubtitleURLPrefix, signer), nil
+	case "s3":
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			Prefix:          os.Getenv("S3_PREFIX"),
+			PathStyle:       os.Getenv("S3_PATH_STYLE") == "true",
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
 func main() {
 	// Load environment variables from .env files for local development
 	loadEnv()
@@ -74,12 +101,68 @@ func main() {
 	videoRepo := database.NewVideoRepository(db)
 	userRepo := database.NewUserRepository(db)
 	vocabRepo := database.NewVocabularyRepository(db)
-	vocabIndexRepo := database.NewVocabularyIndexRepository(db.Database)
+	vocabIndexRepo := database.NewVocabularyIndexRepository(db)
+	vocabVersionRepo := database.NewVocabularyVersionRepository(db)
 	watchHistoryRepo := database.NewWatchHistoryRepository(db)
 	playlistRepo := database.NewPlaylistRepository(db)
+	mediaRepo := database.NewMediaRepository(db)
+	subtitleVersionRepo := database.NewSubtitleVersionRepository(db)
+	sentencePairRepo := database.NewSentencePairRepository(db)
+	cueIndexRepo := database.NewCueIndexRepository(db)
+	jobRepo := database.NewJobRepository(db)
+
+	// Create subtitle file storage
+	subtitleStore, err := newSubtitleStore(storage.NewURLSigner(cfg.JWT.Secret))
+	if err != nil {
+		log.Fatal("Failed to create subtitle storage:", err)
+	}
+
+	// Create services
+	jobRunner := services.NewJobRunner(jobRepo, services.DefaultJobWorkers)
+	indexService := services.NewVocabularyIndexService(vocabRepo, vocabIndexRepo, videoRepo, sentencePairRepo, cueIndexRepo, subtitleStore, db.ActiveVocabulary)
+	indexService.RegisterJobs(jobRunner)
+	versionService := services.NewVocabularyVersionService(db, vocabVersionRepo, vocabRepo, indexService, jobRunner)
+	versionService.RegisterJobs(jobRunner)
+	trashService := services.NewVideoTrashService(db, videoRepo, vocabIndexRepo, playlistRepo, watchHistoryRepo, subtitleVersionRepo, sentencePairRepo, cueIndexRepo, subtitleStore, services.DefaultTrashRetention)
+
+	initCtx, cancelInit := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := db.EnsureIndexes(initCtx); err != nil {
+		log.Printf("Failed to create database indexes: %v", err)
+	}
+
+	// Record the existing vocabulary as the first version and fail builds whose job is gone
+	if err := versionService.Init(initCtx); err != nil {
+		log.Printf("Failed to initialise vocabulary versions: %v", err)
+	}
+	// Transcript index entries written by older releases aren't found by searches until rebuilt
+	if job, err := indexService.QueueTranscriptUpgrade(initCtx, jobRunner); err != nil {
+		log.Printf("Failed to queue transcript index upgrade: %v", err)
+	} else if job != nil {
+		log.Printf("Rebuilding transcript index entries from an older release in job %s", job.ID)
+	}
+	cancelInit()
+
+	// Start background purge of videos that have been in the trash past the retention period
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go trashService.Run(backgroundCtx, 24*time.Hour)
+
+	// Subtitles uploaded to S3 by older releases aren't found by content until their hashes are indexed
+	if s3Store, ok := subtitleStore.(*storage.S3Storage); ok {
+		go func() {
+			if added, err := s3Store.IndexHashes(backgroundCtx); err != nil {
+				log.Printf("Failed to index subtitle hashes: %v", err)
+			} else if added > 0 {
+				log.Printf("Indexed the hashes of %d subtitle files", added)
+			}
+		}()
+	}
+
+	// Start the workers that run queued jobs, including any a previous run left unfinished
+	jobRunner.Start()
 
 	// Setup routes
-	router := handlers.SetupRoutes(cfg, db, videoRepo, userRepo, vocabRepo, vocabIndexRepo, watchHistoryRepo, playlistRepo)
+	router := handlers.SetupRoutes(cfg, db, videoRepo, userRepo, vocabRepo, vocabIndexRepo, watchHistoryRepo, playlistRepo, mediaRepo, subtitleVersionRepo, sentencePairRepo, cueIndexRepo, subtitleStore, trashService, indexService, versionService, jobRunner)
 
 	// Create server
 	server := &http.Server{
@@ -102,12 +185,25 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	// Stop background jobs
+	stopBackground()
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		// Jobs are still drained below so they are queued again rather than lost
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+
+	// Let running jobs finish; those that don't in time are queued again for the next start.
+	// The drain has its own deadline so a slow HTTP shutdown doesn't leave it no time.
+	jobCtx, cancelJobs := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelJobs()
+
+	if err := jobRunner.Shutdown(jobCtx); err != nil {
+		log.Printf("Interrupted jobs that were still running: %v", err)
 	}
 
 	log.Println("Server exited")
@@ -9,6 +9,7 @@ require (
 	github.com/mailgun/mailgun-go/v4 v4.12.0
 	go.mongodb.org/mongo-driver v1.15.0
 	golang.org/x/crypto v0.17.0
+	golang.org/x/text v0.14.0
 )
 
 require (
@@ -25,5 +26,4 @@ require (
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
 	golang.org/x/sync v0.1.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"regexp"
+
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CueIndexRepository interface for the full-text transcript index
+type CueIndexRepository interface {
+	FindByTokens(ctx context.Context, tokens []string, language string, videoIDs []string, limit int) ([]*models.IndexedCue, error)
+	GetByCueIndexes(ctx context.Context, videoID, trackID string, cueIndexes []int) ([]*models.IndexedCue, error)
+	ReplaceForVideo(ctx context.Context, videoID string, cues []*models.IndexedCue) error
+	DeleteByVideoID(ctx context.Context, videoID string) error
+	CountOutdated(ctx context.Context) (int64, error)
+}
+
+// cueIndexRepository implements CueIndexRepository
+type cueIndexRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCueIndexRepository creates a new cue index repository
+func NewCueIndexRepository(db *MongoDB) CueIndexRepository {
+	return &cueIndexRepository{
+		collection: db.CueIndexCollection,
+	}
+}
+
+// FindByTokens retrieves the cues of the given videos containing all of the given words, in
+// transcript order. An empty language matches every track. A limit of 0 returns every cue.
+func (r *cueIndexRepository) FindByTokens(ctx context.Context, tokens []string, language string, videoIDs []string, limit int) ([]*models.IndexedCue, error) {
+	if len(videoIDs) == 0 {
+		return nil, nil
+	}
+
+	filter := bson.M{
+		"tokens":   bson.M{"$all": tokens},
+		"video_id": bson.M{"$in": videoIDs},
+	}
+	if language != "" {
+		// Match regional variants too, e.g. "en-NZ" for "en"
+		filter["language"] = bson.M{"$regex": "^" + regexp.QuoteMeta(language) + "(-|$)", "$options": "i"}
+	}
+
+	return r.find(ctx, filter, limit)
+}
+
+// GetByCueIndexes retrieves specific cues of a track
+func (r *cueIndexRepository) GetByCueIndexes(ctx context.Context, videoID, trackID string, cueIndexes []int) ([]*models.IndexedCue, error) {
+	return r.find(ctx, bson.M{"video_id": videoID, "track_id": trackID, "cue_index": bson.M{"$in": cueIndexes}}, 0)
+}
+
+// find runs a query sorted by video, track and cue position, returning at most limit cues unless it is 0
+func (r *cueIndexRepository) find(ctx context.Context, filter bson.M, limit int) ([]*models.IndexedCue, error) {
+	opts := options.Find().SetSort(bson.D{
+		{Key: "video_id", Value: 1},
+		{Key: "track_id", Value: 1},
+		{Key: "cue_index", Value: 1},
+	})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var cues []*models.IndexedCue
+	if err := cursor.All(ctx, &cues); err != nil {
+		return nil, err
+	}
+	return cues, nil
+}
+
+// ReplaceForVideo replaces all of a video's indexed cues
+func (r *cueIndexRepository) ReplaceForVideo(ctx context.Context, videoID string, cues []*models.IndexedCue) error {
+	if err := r.DeleteByVideoID(ctx, videoID); err != nil {
+		return err
+	}
+	if len(cues) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(cues))
+	for i, cue := range cues {
+		cue.GenerateID()
+		docs[i] = cue
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// CountOutdated counts the indexed cues whose tokens were written before the current CueTokensVersion
+func (r *cueIndexRepository) CountOutdated(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"tokens_version": bson.M{"$not": bson.M{"$gte": models.CueTokensVersion}}})
+}
+
+// DeleteByVideoID deletes all indexed cues of a video
+func (r *cueIndexRepository) DeleteByVideoID(ctx context.Context, videoID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID})
+	return err
+}
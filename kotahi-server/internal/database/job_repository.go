@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobRepository interface for background job records
+type JobRepository interface {
+	Create(ctx context.Context, job *models.Job) error
+	GetByID(ctx context.Context, id string) (*models.Job, error)
+	List(ctx context.Context, jobType, status string, limit int) ([]*models.Job, error)
+	Finish(ctx context.Context, job *models.Job, attempt int) (bool, error)
+	Claim(ctx context.Context, types []string) (*models.Job, error)
+	Heartbeat(ctx context.Context, id string, progress models.JobProgress) (cancelRequested bool, err error)
+	RequestCancel(ctx context.Context, id string) (*models.Job, error)
+	RequeueStale(ctx context.Context, before time.Time) (int, error)
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// jobRepository implements JobRepository
+type jobRepository struct {
+	collection *mongo.Collection
+}
+
+// NewJobRepository creates a new job repository
+func NewJobRepository(db *MongoDB) JobRepository {
+	return &jobRepository{
+		collection: db.JobCollection,
+	}
+}
+
+// Create stores a new job
+func (r *jobRepository) Create(ctx context.Context, job *models.Job) error {
+	job.GenerateID()
+	_, err := r.collection.InsertOne(ctx, job)
+	return err
+}
+
+// GetByID retrieves a job by ID
+func (r *jobRepository) GetByID(ctx context.Context, id string) (*models.Job, error) {
+	var job models.Job
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List retrieves the newest jobs, optionally only those of a type or status
+func (r *jobRepository) List(ctx context.Context, jobType, status string, limit int) ([]*models.Job, error) {
+	filter := bson.M{}
+	if jobType != "" {
+		filter["type"] = jobType
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []*models.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// Finish records the outcome of an attempt: the job's status, error, result, progress and
+// timings. Nothing else is written, so a cancellation requested meanwhile is kept. The job is
+// only updated while it is still running the given attempt, not once it has been requeued and
+// claimed again, and it is only put back in the queue if no cancellation was requested. It
+// reports whether the job was updated.
+func (r *jobRepository) Finish(ctx context.Context, job *models.Job, attempt int) (bool, error) {
+	filter := bson.M{"_id": job.ID, "status": models.JobRunning, "attempts": attempt}
+	if job.Status == models.JobPending {
+		filter["cancel_requested"] = bson.M{"$ne": true}
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      job.Status,
+			"error":       job.Error,
+			"result":      job.Result,
+			"progress":    job.Progress,
+			"attempts":    job.Attempts,
+			"run_at":      job.RunAt,
+			"finished_at": job.FinishedAt,
+		},
+		"$unset": bson.M{"heartbeat_at": ""},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Claim marks the oldest due pending job of one of the types as running and returns it, counting
+// the attempt. The update is atomic, so a job is only claimed by one worker even across servers.
+// It returns mongo.ErrNoDocuments when no job is due.
+func (r *jobRepository) Claim(ctx context.Context, types []string) (*models.Job, error) {
+	now := time.Now()
+	filter := bson.M{
+		"status": models.JobPending,
+		"type":   bson.M{"$in": types},
+		"run_at": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       models.JobRunning,
+			"started_at":   now,
+			"heartbeat_at": now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}, {Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Heartbeat records that a running job is still alive along with its progress, and reports
+// whether an admin has asked for it to be cancelled
+func (r *jobRepository) Heartbeat(ctx context.Context, id string, progress models.JobProgress) (bool, error) {
+	filter := bson.M{"_id": id, "status": models.JobRunning}
+	update := bson.M{"$set": bson.M{"heartbeat_at": time.Now(), "progress": progress}}
+	opts := options.FindOneAndUpdate().
+		SetProjection(bson.M{"cancel_requested": 1}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		return false, err
+	}
+	return job.CancelRequested, nil
+}
+
+// RequestCancel cancels a pending job straight away and flags a running one so its worker stops
+// it. Finished jobs are left as they are. The job is returned as it is after the request.
+func (r *jobRepository) RequestCancel(ctx context.Context, id string) (*models.Job, error) {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.JobPending},
+		bson.M{"$set": bson.M{"status": models.JobCancelled, "cancel_requested": true, "finished_at": now}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.JobRunning},
+		bson.M{"$set": bson.M{"cancel_requested": true}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// RequeueStale puts running jobs whose worker hasn't been heard from since before back in the
+// queue, such as jobs left behind by a server that crashed
+func (r *jobRepository) RequeueStale(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"status": models.JobRunning, "heartbeat_at": bson.M{"$lt": before}},
+		bson.M{
+			"$set":   bson.M{"status": models.JobPending, "run_at": time.Now()},
+			"$unset": bson.M{"heartbeat_at": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
+
+// DeleteFinishedBefore removes jobs that finished before the given time
+func (r *jobRepository) DeleteFinishedBefore(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"status":      bson.M{"$in": []string{models.JobCompleted, models.JobFailed, models.JobCancelled}},
+		"finished_at": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
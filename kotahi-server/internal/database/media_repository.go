@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MediaRepository interface for uploaded media operations
+type MediaRepository interface {
+	GetAll(ctx context.Context, kind string) ([]*models.Media, error)
+	GetByID(ctx context.Context, id string) (*models.Media, error)
+	Create(ctx context.Context, media *models.Media) error
+	Delete(ctx context.Context, id string) error
+}
+
+// mediaRepository implements MediaRepository
+type mediaRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMediaRepository creates a new media repository
+func NewMediaRepository(db *MongoDB) MediaRepository {
+	return &mediaRepository{
+		collection: db.MediaCollection,
+	}
+}
+
+// GetAll retrieves all media, optionally filtered by kind
+func (r *mediaRepository) GetAll(ctx context.Context, kind string) ([]*models.Media, error) {
+	filter := bson.M{}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var media []*models.Media
+	if err := cursor.All(ctx, &media); err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// GetByID retrieves a media item by ID
+func (r *mediaRepository) GetByID(ctx context.Context, id string) (*models.Media, error) {
+	var media models.Media
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&media)
+	if err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// Create creates a new media item
+func (r *mediaRepository) Create(ctx context.Context, media *models.Media) error {
+	media.GenerateID()
+	_, err := r.collection.InsertOne(ctx, media)
+	return err
+}
+
+// Delete deletes a media item by ID
+func (r *mediaRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -2,10 +2,13 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"video-player-backend/internal/config"
 	"video-player-backend/internal/models"
+	"video-player-backend/internal/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,14 +18,21 @@ import (
 
 // MongoDB represents the MongoDB connection and collection
 type MongoDB struct {
-	Client                 *mongo.Client
-	Database               *mongo.Database
-	Collection             *mongo.Collection
-	UserCollection         *mongo.Collection
-	VocabularyCollection   *mongo.Collection
-	WatchHistoryCollection *mongo.Collection
-	LearningListCollection *mongo.Collection
-	PlaylistCollection     *mongo.Collection
+	Client                      *mongo.Client
+	Database                    *mongo.Database
+	Collection                  *mongo.Collection
+	UserCollection              *mongo.Collection
+	VocabularyVersionCollection *mongo.Collection
+	VocabularyEditCollection    *mongo.Collection
+	ActiveVocabulary            *ActiveVocabulary // Collections of the live vocabulary version
+	WatchHistoryCollection      *mongo.Collection
+	LearningListCollection      *mongo.Collection
+	PlaylistCollection          *mongo.Collection
+	MediaCollection             *mongo.Collection
+	SubtitleVersionCollection   *mongo.Collection
+	SentencePairCollection      *mongo.Collection
+	CueIndexCollection          *mongo.Collection
+	JobCollection               *mongo.Collection
 }
 
 // NewMongoDB creates a new MongoDB connection
@@ -43,23 +53,77 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 	database := client.Database(cfg.Database.Database)
 	collection := database.Collection("videos")
 	userCollection := database.Collection("users")
-	vocabularyCollection := database.Collection("vocabulary")
+	vocabularyVersionCollection := database.Collection("vocabulary_versions")
+	vocabularyEditCollection := database.Collection("vocabulary_edits")
 	watchHistoryCollection := database.Collection("watch_history")
 	learningListCollection := database.Collection("learning_list")
 	playlistCollection := database.Collection("playlists")
+	mediaCollection := database.Collection("media")
+	subtitleVersionCollection := database.Collection("subtitle_versions")
+	sentencePairCollection := database.Collection("sentence_pairs")
+	cueIndexCollection := database.Collection("cue_index")
+	jobCollection := database.Collection("jobs")
 
 	return &MongoDB{
-		Client:                 client,
-		Database:               database,
-		Collection:             collection,
-		UserCollection:         userCollection,
-		VocabularyCollection:   vocabularyCollection,
-		WatchHistoryCollection: watchHistoryCollection,
-		LearningListCollection: learningListCollection,
-		PlaylistCollection:     playlistCollection,
+		Client:                      client,
+		Database:                    database,
+		Collection:                  collection,
+		UserCollection:              userCollection,
+		VocabularyVersionCollection: vocabularyVersionCollection,
+		VocabularyEditCollection:    vocabularyEditCollection,
+		ActiveVocabulary:            NewActiveVocabulary(database),
+		WatchHistoryCollection:      watchHistoryCollection,
+		LearningListCollection:      learningListCollection,
+		PlaylistCollection:          playlistCollection,
+		MediaCollection:             mediaCollection,
+		SubtitleVersionCollection:   subtitleVersionCollection,
+		SentencePairCollection:      sentencePairCollection,
+		CueIndexCollection:          cueIndexCollection,
+		JobCollection:               jobCollection,
 	}, nil
 }
 
+// EnsureIndexes creates the indexes the repositories rely on. Creating an index that already
+// exists does nothing, so it runs on every start. An index that can't be created, such as a
+// unique index over existing duplicates, doesn't stop the others.
+func (m *MongoDB) EnsureIndexes(ctx context.Context) error {
+	indexes := []struct {
+		collection *mongo.Collection
+		model      mongo.IndexModel
+	}{
+		// Two versions must never share a number, or they would share collections too
+		{m.VocabularyVersionCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "number", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}},
+		{m.VocabularyEditCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "version_id", Value: 1}, {Key: "revision", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}},
+		// Concurrent saves of a subtitle track both take the next number; only the first is stored
+		{m.SubtitleVersionCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "video_id", Value: 1}, {Key: "track_id", Value: 1}, {Key: "version", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}},
+		// Concordance searches match cues on their tokens and read them in transcript order;
+		// neighbouring cues are looked up by position
+		{m.CueIndexCollection, mongo.IndexModel{
+			Keys: bson.D{{Key: "tokens", Value: 1}},
+		}},
+		{m.CueIndexCollection, mongo.IndexModel{
+			Keys: bson.D{{Key: "video_id", Value: 1}, {Key: "track_id", Value: 1}, {Key: "cue_index", Value: 1}},
+		}},
+	}
+
+	var errs []error
+	for _, index := range indexes {
+		if _, err := index.collection.Indexes().CreateOne(ctx, index.model); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", index.collection.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Close closes the MongoDB connection
 func (m *MongoDB) Close(ctx context.Context) error {
 	return m.Client.Disconnect(ctx)
@@ -181,19 +245,27 @@ type VocabularyRepository interface {
 
 // vocabularyRepository implements VocabularyRepository
 type vocabularyRepository struct {
-	collection *mongo.Collection
+	collection collectionFunc
 }
 
-// NewVocabularyRepository creates a new vocabulary repository
+// NewVocabularyRepository creates a new vocabulary repository for the active vocabulary version
 func NewVocabularyRepository(db *MongoDB) VocabularyRepository {
 	return &vocabularyRepository{
-		collection: db.VocabularyCollection,
+		collection: db.ActiveVocabulary.Vocabulary,
+	}
+}
+
+// NewVocabularyRepositoryForVersion creates a vocabulary repository for a specific vocabulary version
+func NewVocabularyRepositoryForVersion(db *MongoDB, version *models.VocabularyVersion) VocabularyRepository {
+	collection := db.Database.Collection(version.Collection)
+	return &vocabularyRepository{
+		collection: func(context.Context) *mongo.Collection { return collection },
 	}
 }
 
 // GetAll retrieves all vocabulary items
 func (r *vocabularyRepository) GetAll(ctx context.Context) ([]*models.Vocabulary, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection(ctx).Find(ctx, bson.M{})
 	if err != nil {
 		return nil, err
 	}
@@ -210,7 +282,7 @@ func (r *vocabularyRepository) GetAll(ctx context.Context) ([]*models.Vocabulary
 // GetByID retrieves a vocabulary item by ID
 func (r *vocabularyRepository) GetByID(ctx context.Context, id string) (*models.Vocabulary, error) {
 	var vocabulary models.Vocabulary
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&vocabulary)
+	err := r.collection(ctx).FindOne(ctx, bson.M{"_id": id}).Decode(&vocabulary)
 	if err != nil {
 		return nil, err
 	}
@@ -221,7 +293,7 @@ func (r *vocabularyRepository) GetByID(ctx context.Context, id string) (*models.
 // Create creates a new vocabulary item
 func (r *vocabularyRepository) Create(ctx context.Context, vocabulary *models.Vocabulary) error {
 	vocabulary.GenerateID()
-	_, err := r.collection.InsertOne(ctx, vocabulary)
+	_, err := r.collection(ctx).InsertOne(ctx, vocabulary)
 	return err
 }
 
@@ -242,14 +314,14 @@ func (r *vocabularyRepository) CreateBatch(ctx context.Context, vocabularies []*
 		docs[i] = vocab
 	}
 
-	_, err := r.collection.InsertMany(ctx, docs)
+	_, err := r.collection(ctx).InsertMany(ctx, docs)
 	return err
 }
 
 // CheckExisting checks if a vocabulary item with the given Māori text already exists
 func (r *vocabularyRepository) CheckExisting(ctx context.Context, maoriText string) (*models.Vocabulary, error) {
 	var vocabulary models.Vocabulary
-	err := r.collection.FindOne(ctx, bson.M{"maori": maoriText}).Decode(&vocabulary)
+	err := r.collection(ctx).FindOne(ctx, bson.M{"maori": maoriText}).Decode(&vocabulary)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil // Not found, but not an error
@@ -278,17 +350,12 @@ func (r *vocabularyRepository) UpsertBatch(ctx context.Context, vocabularies []*
 		}
 
 		if existing != nil {
-			// Update existing item
-			existing.UpdateFromRequest(&models.VocabularyRequest{
-				Maori:       vocab.Maori,
-				English:     vocab.English,
-				Description: vocab.Description,
-			})
-
-			if err := r.Update(ctx, existing.ID, existing); err != nil {
+			// Update existing item, keeping its ID
+			vocab.ID = existing.ID
+			if err := r.Update(ctx, existing.ID, vocab); err != nil {
 				return nil, nil, err
 			}
-			updated = append(updated, existing)
+			updated = append(updated, vocab)
 		} else {
 			// Create new item
 			if err := r.Create(ctx, vocab); err != nil {
@@ -305,13 +372,21 @@ func (r *vocabularyRepository) UpsertBatch(ctx context.Context, vocabularies []*
 func (r *vocabularyRepository) Update(ctx context.Context, id string, vocabulary *models.Vocabulary) error {
 	update := bson.M{
 		"$set": bson.M{
-			"maori":       vocabulary.Maori,
-			"english":     vocabulary.English,
-			"description": vocabulary.Description,
+			"maori":          vocabulary.Maori,
+			"english":        vocabulary.English,
+			"description":    vocabulary.Description,
+			"part_of_speech": vocabulary.PartOfSpeech,
+			"variants":       vocabulary.Variants,
+			"forms":          vocabulary.Forms,
+			"senses":         vocabulary.Senses,
+			"examples":       vocabulary.Examples,
+			"tags":           vocabulary.Tags,
+			"difficulty":     vocabulary.Difficulty,
+			"audio_url":      vocabulary.AudioURL,
 		},
 	}
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	result, err := r.collection(ctx).UpdateOne(ctx, bson.M{"_id": id}, update)
 	if err != nil {
 		return err
 	}
@@ -325,7 +400,7 @@ func (r *vocabularyRepository) Update(ctx context.Context, id string, vocabulary
 
 // Delete deletes a vocabulary item by ID
 func (r *vocabularyRepository) Delete(ctx context.Context, id string) error {
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	result, err := r.collection(ctx).DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
 		return err
 	}
@@ -339,21 +414,26 @@ func (r *vocabularyRepository) Delete(ctx context.Context, id string) error {
 
 // DeleteAll deletes all vocabulary items
 func (r *vocabularyRepository) DeleteAll(ctx context.Context) error {
-	_, err := r.collection.DeleteMany(ctx, bson.M{})
+	_, err := r.collection(ctx).DeleteMany(ctx, bson.M{})
 	return err
 }
 
-// Search searches vocabulary items by Māori or English text
+// Search searches vocabulary items by Māori or English text. Māori spellings with or without
+// macrons or doubled vowels all match.
 func (r *vocabularyRepository) Search(ctx context.Context, query string) ([]*models.Vocabulary, error) {
+	pattern := utils.MaoriSearchPattern(query)
 	filter := bson.M{
 		"$or": []bson.M{
-			{"maori": bson.M{"$regex": query, "$options": "i"}},
-			{"english": bson.M{"$regex": query, "$options": "i"}},
-			{"description": bson.M{"$regex": query, "$options": "i"}},
+			{"maori": bson.M{"$regex": pattern, "$options": "i"}},
+			{"english": bson.M{"$regex": pattern, "$options": "i"}},
+			{"description": bson.M{"$regex": pattern, "$options": "i"}},
+			{"variants": bson.M{"$regex": pattern, "$options": "i"}},
+			{"forms.form": bson.M{"$regex": pattern, "$options": "i"}},
+			{"senses": bson.M{"$regex": pattern, "$options": "i"}},
 		},
 	}
 
-	cursor, err := r.collection.Find(ctx, filter)
+	cursor, err := r.collection(ctx).Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -375,26 +455,39 @@ type VocabularyIndexRepository interface {
 	SearchByVocabulary(ctx context.Context, vocabulary string) ([]*models.VocabularyIndex, error)
 	SearchByEnglish(ctx context.Context, english string) ([]*models.VocabularyIndex, error)
 	DeleteByVideoID(ctx context.Context, videoID string) error
+	DeleteByVocabularyIDs(ctx context.Context, vocabularyIDs []string) error
 	DeleteAll(ctx context.Context) error
 	GetAll(ctx context.Context) ([]*models.VocabularyIndex, error)
+	GetVideoIDs(ctx context.Context) ([]string, error)
+	Count(ctx context.Context) (int, error)
+	CopyTo(ctx context.Context, collection string, excludeVocabularyIDs []string) error
 	GetStats(ctx context.Context) (map[string]interface{}, error)
+	GetTermCounts(ctx context.Context) (map[string]map[string]int, error)
 }
 
 type vocabularyIndexRepository struct {
-	collection *mongo.Collection
+	collection collectionFunc
 }
 
-// NewVocabularyIndexRepository creates a new vocabulary index repository
-func NewVocabularyIndexRepository(db *mongo.Database) VocabularyIndexRepository {
+// NewVocabularyIndexRepository creates a new vocabulary index repository for the active vocabulary version
+func NewVocabularyIndexRepository(db *MongoDB) VocabularyIndexRepository {
 	return &vocabularyIndexRepository{
-		collection: db.Collection("vocabulary_index"),
+		collection: db.ActiveVocabulary.Index,
+	}
+}
+
+// NewVocabularyIndexRepositoryForVersion creates a vocabulary index repository for a specific vocabulary version
+func NewVocabularyIndexRepositoryForVersion(db *MongoDB, version *models.VocabularyVersion) VocabularyIndexRepository {
+	collection := db.Database.Collection(version.IndexCollection)
+	return &vocabularyIndexRepository{
+		collection: func(context.Context) *mongo.Collection { return collection },
 	}
 }
 
 // Create creates a new vocabulary index entry
 func (r *vocabularyIndexRepository) Create(ctx context.Context, index *models.VocabularyIndex) error {
 	index.GenerateID()
-	_, err := r.collection.InsertOne(ctx, index)
+	_, err := r.collection(ctx).InsertOne(ctx, index)
 	return err
 }
 
@@ -413,13 +506,13 @@ func (r *vocabularyIndexRepository) CreateBatch(ctx context.Context, indexes []*
 		docs[i] = index
 	}
 
-	_, err := r.collection.InsertMany(ctx, docs)
+	_, err := r.collection(ctx).InsertMany(ctx, docs)
 	return err
 }
 
 // GetByVideoID retrieves all vocabulary indexes for a specific video
 func (r *vocabularyIndexRepository) GetByVideoID(ctx context.Context, videoID string) ([]*models.VocabularyIndex, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"video_id": videoID})
+	cursor, err := r.collection(ctx).Find(ctx, bson.M{"video_id": videoID})
 	if err != nil {
 		return nil, err
 	}
@@ -433,9 +526,17 @@ func (r *vocabularyIndexRepository) GetByVideoID(ctx context.Context, videoID st
 	return indexes, nil
 }
 
-// SearchByVocabulary searches for vocabulary indexes by Māori word/phrase
+// SearchByVocabulary searches for vocabulary indexes by Māori word/phrase, matching the headword or
+// the form found in the transcript with or without macrons or doubled vowels
 func (r *vocabularyIndexRepository) SearchByVocabulary(ctx context.Context, vocabulary string) ([]*models.VocabularyIndex, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"vocabulary": bson.M{"$regex": vocabulary, "$options": "i"}})
+	pattern := utils.MaoriSearchPattern(vocabulary)
+	filter := bson.M{
+		"$or": []bson.M{
+			{"vocabulary": bson.M{"$regex": pattern, "$options": "i"}},
+			{"matched_form": bson.M{"$regex": pattern, "$options": "i"}},
+		},
+	}
+	cursor, err := r.collection(ctx).Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -451,7 +552,7 @@ func (r *vocabularyIndexRepository) SearchByVocabulary(ctx context.Context, voca
 
 // SearchByEnglish searches for vocabulary indexes by English translation
 func (r *vocabularyIndexRepository) SearchByEnglish(ctx context.Context, english string) ([]*models.VocabularyIndex, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"english": bson.M{"$regex": english, "$options": "i"}})
+	cursor, err := r.collection(ctx).Find(ctx, bson.M{"english": bson.M{"$regex": english, "$options": "i"}})
 	if err != nil {
 		return nil, err
 	}
@@ -467,19 +568,69 @@ func (r *vocabularyIndexRepository) SearchByEnglish(ctx context.Context, english
 
 // DeleteByVideoID deletes all vocabulary indexes for a specific video
 func (r *vocabularyIndexRepository) DeleteByVideoID(ctx context.Context, videoID string) error {
-	_, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID})
+	_, err := r.collection(ctx).DeleteMany(ctx, bson.M{"video_id": videoID})
+	return err
+}
+
+// DeleteByVocabularyIDs deletes every index entry of the given vocabulary items
+func (r *vocabularyIndexRepository) DeleteByVocabularyIDs(ctx context.Context, vocabularyIDs []string) error {
+	if len(vocabularyIDs) == 0 {
+		return nil
+	}
+	_, err := r.collection(ctx).DeleteMany(ctx, bson.M{"vocabulary_id": bson.M{"$in": vocabularyIDs}})
 	return err
 }
 
 // DeleteAll deletes all vocabulary indexes
 func (r *vocabularyIndexRepository) DeleteAll(ctx context.Context) error {
-	_, err := r.collection.DeleteMany(ctx, bson.M{})
+	_, err := r.collection(ctx).DeleteMany(ctx, bson.M{})
 	return err
 }
 
+// GetVideoIDs returns the IDs of every video with index entries
+func (r *vocabularyIndexRepository) GetVideoIDs(ctx context.Context) ([]string, error) {
+	values, err := r.collection(ctx).Distinct(ctx, "video_id", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Count returns the number of index entries
+func (r *vocabularyIndexRepository) Count(ctx context.Context) (int, error) {
+	count, err := r.collection(ctx).CountDocuments(ctx, bson.M{})
+	return int(count), err
+}
+
+// CopyTo copies the index entries into another collection of the same database, leaving out the
+// entries of the given vocabulary items. The copy is done by the database without loading the entries.
+func (r *vocabularyIndexRepository) CopyTo(ctx context.Context, collection string, excludeVocabularyIDs []string) error {
+	match := bson.M{}
+	if len(excludeVocabularyIDs) > 0 {
+		match["vocabulary_id"] = bson.M{"$nin": excludeVocabularyIDs}
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$merge": bson.M{"into": collection}},
+	}
+
+	cursor, err := r.collection(ctx).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
 // GetAll retrieves all vocabulary indexes
 func (r *vocabularyIndexRepository) GetAll(ctx context.Context) ([]*models.VocabularyIndex, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection(ctx).Find(ctx, bson.M{})
 	if err != nil {
 		return nil, err
 	}
@@ -495,7 +646,7 @@ func (r *vocabularyIndexRepository) GetAll(ctx context.Context) ([]*models.Vocab
 
 // GetStats retrieves statistics about vocabulary indexes
 func (r *vocabularyIndexRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
-	totalCount, err := r.collection.CountDocuments(ctx, bson.M{})
+	totalCount, err := r.collection(ctx).CountDocuments(ctx, bson.M{})
 	if err != nil {
 		return nil, err
 	}
@@ -507,7 +658,7 @@ func (r *vocabularyIndexRepository) GetStats(ctx context.Context) (map[string]in
 	}
 
 	var uniqueVocabResult []bson.M
-	cursor, err := r.collection.Aggregate(ctx, uniqueVocabPipeline)
+	cursor, err := r.collection(ctx).Aggregate(ctx, uniqueVocabPipeline)
 	if err != nil {
 		return nil, err
 	}
@@ -531,7 +682,7 @@ func (r *vocabularyIndexRepository) GetStats(ctx context.Context) (map[string]in
 	}
 
 	var uniqueVideoResult []bson.M
-	cursor, err = r.collection.Aggregate(ctx, uniqueVideoPipeline)
+	cursor, err = r.collection(ctx).Aggregate(ctx, uniqueVideoPipeline)
 	if err != nil {
 		return nil, err
 	}
@@ -555,6 +706,43 @@ func (r *vocabularyIndexRepository) GetStats(ctx context.Context) (map[string]in
 	}, nil
 }
 
+// GetTermCounts returns how many times each vocabulary word occurs in each video, keyed by video ID
+func (r *vocabularyIndexRepository) GetTermCounts(ctx context.Context) (map[string]map[string]int, error) {
+	pipeline := []bson.M{
+		{"$group": bson.M{
+			"_id":   bson.M{"video_id": "$video_id", "vocabulary": "$vocabulary"},
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+
+	cursor, err := r.collection(ctx).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ID struct {
+			VideoID    string `bson:"video_id"`
+			Vocabulary string `bson:"vocabulary"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]map[string]int)
+	for _, result := range results {
+		if counts[result.ID.VideoID] == nil {
+			counts[result.ID.VideoID] = make(map[string]int)
+		}
+		counts[result.ID.VideoID][result.ID.Vocabulary] = result.Count
+	}
+
+	return counts, nil
+}
+
 // WatchHistoryRepository interface for watch history operations
 type WatchHistoryRepository interface {
 	GetByUserID(ctx context.Context, userID string) ([]*models.WatchHistory, error)
@@ -563,6 +751,7 @@ type WatchHistoryRepository interface {
 	Update(ctx context.Context, id string, watchHistory *models.WatchHistory) error
 	Delete(ctx context.Context, id string) error
 	DeleteByUserAndVideo(ctx context.Context, userID, videoID string) error
+	DeleteByVideoID(ctx context.Context, videoID string) error
 	GetRecentWatched(ctx context.Context, userID string, limit int) ([]*models.WatchHistory, error)
 	GetCompletedVideos(ctx context.Context, userID string) ([]*models.WatchHistory, error)
 	GetUserProgress(ctx context.Context, userID string) (map[string]interface{}, error)
@@ -690,6 +879,12 @@ func (r *watchHistoryRepository) DeleteByUserAndVideo(ctx context.Context, userI
 	return nil
 }
 
+// DeleteByVideoID deletes all users' watch history for a specific video
+func (r *watchHistoryRepository) DeleteByVideoID(ctx context.Context, videoID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID})
+	return err
+}
+
 // GetRecentWatched retrieves recently watched videos for a user
 func (r *watchHistoryRepository) GetRecentWatched(ctx context.Context, userID string, limit int) ([]*models.WatchHistory, error) {
 	filter := bson.M{"user_id": userID}
@@ -945,6 +1140,12 @@ func (m *MongoDB) GetLearningListByVideoID(ctx context.Context, userID primitive
 	return items, nil
 }
 
+// ClearLearningListVideo removes a video reference from all learning list items, keeping the items
+func (m *MongoDB) ClearLearningListVideo(ctx context.Context, videoID string) error {
+	_, err := m.LearningListCollection.UpdateMany(ctx, bson.M{"video_id": videoID}, bson.M{"$unset": bson.M{"video_id": ""}})
+	return err
+}
+
 // PlaylistRepository interface for playlist operations
 type PlaylistRepository interface {
 	GetAll(ctx context.Context) ([]*models.Playlist, error)
@@ -956,6 +1157,7 @@ type PlaylistRepository interface {
 	Delete(ctx context.Context, id string) error
 	AddVideo(ctx context.Context, id, videoID string) error
 	RemoveVideo(ctx context.Context, id, videoID string) error
+	RemoveVideoFromAll(ctx context.Context, videoID string) error
 	ReorderVideos(ctx context.Context, id string, videoIDs []string) error
 	Search(ctx context.Context, query string) ([]*models.Playlist, error)
 }
@@ -1119,6 +1321,17 @@ func (r *playlistRepository) RemoveVideo(ctx context.Context, id, videoID string
 	return nil
 }
 
+// RemoveVideoFromAll removes a video from every playlist that contains it
+func (r *playlistRepository) RemoveVideoFromAll(ctx context.Context, videoID string) error {
+	update := bson.M{
+		"$pull": bson.M{"video_ids": videoID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	_, err := r.collection.UpdateMany(ctx, bson.M{"video_ids": videoID}, update)
+	return err
+}
+
 // ReorderVideos reorders videos in a playlist
 func (r *playlistRepository) ReorderVideos(ctx context.Context, id string, videoIDs []string) error {
 	update := bson.M{
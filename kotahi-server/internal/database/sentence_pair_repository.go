@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SentencePairRepository interface for aligned Māori and English transcript lines
+type SentencePairRepository interface {
+	GetByVideoID(ctx context.Context, videoID string) ([]*models.SentencePair, error)
+	GetByVideoIDs(ctx context.Context, videoIDs []string) (map[string][]*models.SentencePair, error)
+	ReplaceForVideo(ctx context.Context, videoID string, pairs []*models.SentencePair) error
+	DeleteByVideoID(ctx context.Context, videoID string) error
+}
+
+// sentencePairRepository implements SentencePairRepository
+type sentencePairRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSentencePairRepository creates a new sentence pair repository
+func NewSentencePairRepository(db *MongoDB) SentencePairRepository {
+	return &sentencePairRepository{
+		collection: db.SentencePairCollection,
+	}
+}
+
+// GetByVideoID retrieves a video's sentence pairs in transcript order
+func (r *sentencePairRepository) GetByVideoID(ctx context.Context, videoID string) ([]*models.SentencePair, error) {
+	pairs, err := r.GetByVideoIDs(ctx, []string{videoID})
+	if err != nil {
+		return nil, err
+	}
+	return pairs[videoID], nil
+}
+
+// GetByVideoIDs retrieves the sentence pairs of several videos, grouped by video ID
+func (r *sentencePairRepository) GetByVideoIDs(ctx context.Context, videoIDs []string) (map[string][]*models.SentencePair, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "video_id", Value: 1}, {Key: "index", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"video_id": bson.M{"$in": videoIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var pairs []*models.SentencePair
+	if err := cursor.All(ctx, &pairs); err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[string][]*models.SentencePair)
+	for _, pair := range pairs {
+		grouped[pair.VideoID] = append(grouped[pair.VideoID], pair)
+	}
+	return grouped, nil
+}
+
+// ReplaceForVideo replaces all of a video's sentence pairs
+func (r *sentencePairRepository) ReplaceForVideo(ctx context.Context, videoID string, pairs []*models.SentencePair) error {
+	if err := r.DeleteByVideoID(ctx, videoID); err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(pairs))
+	for i, pair := range pairs {
+		pair.GenerateID()
+		docs[i] = pair
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// DeleteByVideoID deletes all sentence pairs of a video
+func (r *sentencePairRepository) DeleteByVideoID(ctx context.Context, videoID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID})
+	return err
+}
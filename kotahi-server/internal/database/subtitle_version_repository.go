@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SubtitleVersionRepository interface for subtitle track version history
+type SubtitleVersionRepository interface {
+	GetByTrack(ctx context.Context, videoID, trackID string) ([]*models.SubtitleVersion, error)
+	GetByVersion(ctx context.Context, videoID, trackID string, version int) (*models.SubtitleVersion, error)
+	GetLatest(ctx context.Context, videoID, trackID string) (*models.SubtitleVersion, error)
+	Create(ctx context.Context, version *models.SubtitleVersion) error
+	Delete(ctx context.Context, id string) error
+	DeleteByVideoID(ctx context.Context, videoID string) error
+}
+
+// subtitleVersionRepository implements SubtitleVersionRepository
+type subtitleVersionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSubtitleVersionRepository creates a new subtitle version repository
+func NewSubtitleVersionRepository(db *MongoDB) SubtitleVersionRepository {
+	return &subtitleVersionRepository{
+		collection: db.SubtitleVersionCollection,
+	}
+}
+
+// GetByTrack lists a track's versions, newest first, without their content
+func (r *subtitleVersionRepository) GetByTrack(ctx context.Context, videoID, trackID string) ([]*models.SubtitleVersion, error) {
+	filter := bson.M{"video_id": videoID, "track_id": trackID}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{"content": 0})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var versions []*models.SubtitleVersion
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetByVersion retrieves a single version of a track
+func (r *subtitleVersionRepository) GetByVersion(ctx context.Context, videoID, trackID string, version int) (*models.SubtitleVersion, error) {
+	var v models.SubtitleVersion
+	filter := bson.M{"video_id": videoID, "track_id": trackID, "version": version}
+	if err := r.collection.FindOne(ctx, filter).Decode(&v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetLatest retrieves the newest version of a track
+func (r *subtitleVersionRepository) GetLatest(ctx context.Context, videoID, trackID string) (*models.SubtitleVersion, error) {
+	var v models.SubtitleVersion
+	filter := bson.M{"video_id": videoID, "track_id": trackID}
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	if err := r.collection.FindOne(ctx, filter, opts).Decode(&v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Create stores a new version. A track's version numbers are unique, so storing a number that
+// is already taken fails with a duplicate key error.
+func (r *subtitleVersionRepository) Create(ctx context.Context, version *models.SubtitleVersion) error {
+	version.GenerateID()
+	_, err := r.collection.InsertOne(ctx, version)
+	return err
+}
+
+// Delete deletes a single version
+func (r *subtitleVersionRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// DeleteByVideoID deletes the version history of every track of a video
+func (r *subtitleVersionRepository) DeleteByVideoID(ctx context.Context, videoID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID})
+	return err
+}
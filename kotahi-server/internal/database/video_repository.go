@@ -3,10 +3,13 @@ package database
 import (
 	"context"
 	"fmt"
+	"time"
 	"video-player-backend/internal/models"
+	"video-player-backend/internal/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // VideoRepository interface for video operations
@@ -16,10 +19,21 @@ type VideoRepository interface {
 	Create(ctx context.Context, video *models.Video) error
 	Update(ctx context.Context, id string, video *models.Video) error
 	Delete(ctx context.Context, id string) error
+	SoftDelete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	GetTrashed(ctx context.Context) ([]*models.Video, error)
+	GetTrashedByID(ctx context.Context, id string) (*models.Video, error)
+	GetTrashedBefore(ctx context.Context, cutoff time.Time) ([]*models.Video, error)
 	FindBySubtitleFilename(ctx context.Context, filename string) ([]*models.Video, error)
 	Search(ctx context.Context, query string) ([]*models.Video, error)
 }
 
+// notTrashed is the deleted_at condition for videos that have not been moved to the trash
+var notTrashed = bson.M{"$exists": false}
+
+// inTrash is the deleted_at condition for videos that are in the trash
+var inTrash = bson.M{"$exists": true}
+
 // NewVideoRepository creates a new video repository
 func NewVideoRepository(db *MongoDB) VideoRepository {
 	return &videoRepository{
@@ -27,9 +41,9 @@ func NewVideoRepository(db *MongoDB) VideoRepository {
 	}
 }
 
-// GetAll retrieves all videos
+// GetAll retrieves all videos that are not in the trash
 func (r *videoRepository) GetAll(ctx context.Context) ([]*models.Video, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, bson.M{"deleted_at": notTrashed})
 	if err != nil {
 		return nil, err
 	}
@@ -43,10 +57,10 @@ func (r *videoRepository) GetAll(ctx context.Context) ([]*models.Video, error) {
 	return videos, nil
 }
 
-// GetByID retrieves a video by ID
+// GetByID retrieves a video by ID, treating trashed videos as not found
 func (r *videoRepository) GetByID(ctx context.Context, id string) (*models.Video, error) {
 	var video models.Video
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&video)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": notTrashed}).Decode(&video)
 	if err != nil {
 		return nil, err
 	}
@@ -69,11 +83,16 @@ func (r *videoRepository) Update(ctx context.Context, id string, video *models.V
 			"thumbnail":   video.Thumbnail,
 			"video":       video.Video,
 			"subtitle":    video.Subtitle,
+			"tracks":      video.Tracks,
+			"tags":        video.Tags,
+			"series":      video.Series,
 			"duration":    video.Duration,
+			"source":      video.Source,
+			"source_url":  video.SourceURL,
 		},
 	}
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": notTrashed}, update)
 	if err != nil {
 		return err
 	}
@@ -85,7 +104,7 @@ func (r *videoRepository) Update(ctx context.Context, id string, video *models.V
 	return nil
 }
 
-// Delete deletes a video by ID
+// Delete permanently deletes a video by ID
 func (r *videoRepository) Delete(ctx context.Context, id string) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
@@ -99,11 +118,93 @@ func (r *videoRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// SoftDelete moves a video to the trash
+func (r *videoRepository) SoftDelete(ctx context.Context, id string) error {
+	update := bson.M{
+		"$set": bson.M{"deleted_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": notTrashed}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Restore moves a video out of the trash
+func (r *videoRepository) Restore(ctx context.Context, id string) error {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": inTrash}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// GetTrashed retrieves all videos in the trash, most recently deleted first
+func (r *videoRepository) GetTrashed(ctx context.Context) ([]*models.Video, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"deleted_at": inTrash}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var videos []*models.Video
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+
+	return videos, nil
+}
+
+// GetTrashedByID retrieves a video in the trash by ID
+func (r *videoRepository) GetTrashedByID(ctx context.Context, id string) (*models.Video, error) {
+	var video models.Video
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": inTrash}).Decode(&video)
+	if err != nil {
+		return nil, err
+	}
+	return &video, nil
+}
+
+// GetTrashedBefore retrieves videos that were moved to the trash before the cutoff
+func (r *videoRepository) GetTrashedBefore(ctx context.Context, cutoff time.Time) ([]*models.Video, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var videos []*models.Video
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+
+	return videos, nil
+}
+
 // FindBySubtitleFilename finds videos whose subtitle path contains the given VTT filename
 func (r *videoRepository) FindBySubtitleFilename(ctx context.Context, filename string) ([]*models.Video, error) {
 	// Use a case-insensitive regex to match the filename within the subtitle path or URL
 	fmt.Println(filename)
-	filter := bson.M{"subtitle": bson.M{"$regex": filename, "$options": "i"}}
+	filter := bson.M{
+		"subtitle":   bson.M{"$regex": filename, "$options": "i"},
+		"deleted_at": notTrashed,
+	}
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
@@ -117,13 +218,15 @@ func (r *videoRepository) FindBySubtitleFilename(ctx context.Context, filename s
 	return videos, nil
 }
 
-// Search searches videos by title or description
+// Search searches videos by title or description, ignoring differences in macrons and doubled vowels
 func (r *videoRepository) Search(ctx context.Context, query string) ([]*models.Video, error) {
+	pattern := utils.MaoriSearchPattern(query)
 	filter := bson.M{
 		"$or": []bson.M{
-			{"title": bson.M{"$regex": query, "$options": "i"}},
-			{"description": bson.M{"$regex": query, "$options": "i"}},
+			{"title": bson.M{"$regex": pattern, "$options": "i"}},
+			{"description": bson.M{"$regex": pattern, "$options": "i"}},
 		},
+		"deleted_at": notTrashed,
 	}
 
 	cursor, err := r.collection.Find(ctx, filter)
@@ -0,0 +1,346 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collections that held the vocabulary and its index before versioning. They stay in use
+// until another version is activated.
+const (
+	DefaultVocabularyCollection      = "vocabulary"
+	DefaultVocabularyIndexCollection = "vocabulary_index"
+)
+
+// activeVocabularyStateID is the ID of the document naming the active vocabulary version
+const activeVocabularyStateID = "active"
+
+// versionCounterID is the ID of the document holding the last vocabulary version number handed out
+const versionCounterID = "version_number"
+
+// revisionCounterPrefix starts the ID of the document counting the edits made to a version. It is
+// kept apart from the version record so saving a record read before an edit can't undo the count.
+const revisionCounterPrefix = "revision:"
+
+// ActiveVocabularyRefresh is how long the active version is cached before it is read again,
+// so activations made by another server are picked up
+const ActiveVocabularyRefresh = 10 * time.Second
+
+// collectionFunc returns the collection a repository should use
+type collectionFunc func(ctx context.Context) *mongo.Collection
+
+// activeVocabularyState names the active vocabulary version and its collections. It is a single
+// document, so activation switches the entries and the index in one write.
+type activeVocabularyState struct {
+	ID              string    `bson:"_id"`
+	VersionID       string    `bson:"version_id"`
+	PreviousID      string    `bson:"previous_id,omitempty"` // Version that was active before, used for rollback
+	Collection      string    `bson:"collection"`
+	IndexCollection string    `bson:"index_collection"`
+	ActivatedAt     time.Time `bson:"activated_at"`
+}
+
+// ActiveVocabulary tracks which vocabulary version is live and hands out its collections
+type ActiveVocabulary struct {
+	database *mongo.Database
+	state    *mongo.Collection
+	mu       sync.RWMutex // Guards current and loadedAt
+	current  activeVocabularyState
+	loadedAt time.Time
+}
+
+// NewActiveVocabulary creates a tracker that starts out on the default collections
+func NewActiveVocabulary(database *mongo.Database) *ActiveVocabulary {
+	return &ActiveVocabulary{
+		database: database,
+		state:    database.Collection("vocabulary_state"),
+		current: activeVocabularyState{
+			ID:              activeVocabularyStateID,
+			Collection:      DefaultVocabularyCollection,
+			IndexCollection: DefaultVocabularyIndexCollection,
+		},
+	}
+}
+
+// Vocabulary returns the collection holding the active version's entries
+func (a *ActiveVocabulary) Vocabulary(ctx context.Context) *mongo.Collection {
+	return a.database.Collection(a.get(ctx).Collection)
+}
+
+// Index returns the collection holding the active version's video index
+func (a *ActiveVocabulary) Index(ctx context.Context) *mongo.Collection {
+	return a.database.Collection(a.get(ctx).IndexCollection)
+}
+
+// VersionID returns the ID of the active version, empty before any version has been activated
+func (a *ActiveVocabulary) VersionID(ctx context.Context) string {
+	return a.get(ctx).VersionID
+}
+
+// PreviousVersionID returns the ID of the version that was active before the current one
+func (a *ActiveVocabulary) PreviousVersionID(ctx context.Context) string {
+	return a.get(ctx).PreviousID
+}
+
+// Activate makes a version live. Readers switch to its entries and index together.
+func (a *ActiveVocabulary) Activate(ctx context.Context, version *models.VocabularyVersion) error {
+	current, err := a.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	state := activeVocabularyState{
+		ID:              activeVocabularyStateID,
+		VersionID:       version.ID,
+		PreviousID:      current.VersionID,
+		Collection:      version.Collection,
+		IndexCollection: version.IndexCollection,
+		ActivatedAt:     time.Now(),
+	}
+	if current.VersionID == version.ID {
+		state.PreviousID = current.PreviousID
+	}
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := a.state.ReplaceOne(ctx, bson.M{"_id": activeVocabularyStateID}, state, opts); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.current = state
+	a.loadedAt = time.Now()
+	a.mu.Unlock()
+	return nil
+}
+
+// get returns the cached active version, reading it again once the cache is stale.
+// If it can't be read the last known version keeps being used.
+func (a *ActiveVocabulary) get(ctx context.Context) activeVocabularyState {
+	a.mu.RLock()
+	current, fresh := a.current, time.Since(a.loadedAt) < ActiveVocabularyRefresh
+	a.mu.RUnlock()
+	if fresh {
+		return current
+	}
+
+	state, err := a.load(ctx)
+	if err != nil {
+		log.Printf("Failed to read the active vocabulary version: %v", err)
+		return current
+	}
+	return state
+}
+
+// load reads the active version from the database and caches it
+func (a *ActiveVocabulary) load(ctx context.Context) (activeVocabularyState, error) {
+	a.mu.RLock()
+	state := a.current
+	a.mu.RUnlock()
+
+	var stored activeVocabularyState
+	err := a.state.FindOne(ctx, bson.M{"_id": activeVocabularyStateID}).Decode(&stored)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return state, err
+	}
+	if err == nil {
+		state = stored
+	}
+
+	a.mu.Lock()
+	a.current = state
+	a.loadedAt = time.Now()
+	a.mu.Unlock()
+	return state, nil
+}
+
+// VocabularyVersionRepository interface for vocabulary version records
+type VocabularyVersionRepository interface {
+	GetAll(ctx context.Context) ([]*models.VocabularyVersion, error)
+	GetByID(ctx context.Context, id string) (*models.VocabularyVersion, error)
+	GetLatest(ctx context.Context) (*models.VocabularyVersion, error)
+	NextNumber(ctx context.Context) (int, error)
+	Revision(ctx context.Context, versionID string) (int, error)
+	RecordEdit(ctx context.Context, versionID, vocabularyID string, after *models.Vocabulary) error
+	GetEditsSince(ctx context.Context, versionID string, revision int) ([]*models.VocabularyEdit, error)
+	DeleteEdits(ctx context.Context, versionID string) error
+	Create(ctx context.Context, version *models.VocabularyVersion) error
+	Update(ctx context.Context, version *models.VocabularyVersion) error
+	Delete(ctx context.Context, id string) error
+}
+
+// vocabularyVersionRepository implements VocabularyVersionRepository
+type vocabularyVersionRepository struct {
+	collection *mongo.Collection
+	edits      *mongo.Collection
+	state      *mongo.Collection
+}
+
+// NewVocabularyVersionRepository creates a new vocabulary version repository
+func NewVocabularyVersionRepository(db *MongoDB) VocabularyVersionRepository {
+	return &vocabularyVersionRepository{
+		collection: db.VocabularyVersionCollection,
+		edits:      db.VocabularyEditCollection,
+		state:      db.ActiveVocabulary.state,
+	}
+}
+
+// GetAll lists every version, newest first
+func (r *vocabularyVersionRepository) GetAll(ctx context.Context) ([]*models.VocabularyVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "number", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var versions []*models.VocabularyVersion
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetByID retrieves a version by ID
+func (r *vocabularyVersionRepository) GetByID(ctx context.Context, id string) (*models.VocabularyVersion, error) {
+	var version models.VocabularyVersion
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// GetLatest retrieves the version with the highest number
+func (r *vocabularyVersionRepository) GetLatest(ctx context.Context) (*models.VocabularyVersion, error) {
+	var version models.VocabularyVersion
+	opts := options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}})
+	if err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// NextNumber hands out the number of a new version. The number is taken from a counter in one
+// atomic update, so versions published at the same time never get the same number. The counter
+// is first raised to the highest existing number, which also starts it off on databases that
+// predate it.
+func (r *vocabularyVersionRepository) NextNumber(ctx context.Context) (int, error) {
+	latest := 0
+	if version, err := r.GetLatest(ctx); err == nil {
+		latest = version.Number
+	} else if err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+
+	filter := bson.M{"_id": versionCounterID}
+	if _, err := r.state.UpdateOne(ctx, filter, bson.M{"$max": bson.M{"value": latest}}, options.Update().SetUpsert(true)); err != nil {
+		return 0, err
+	}
+
+	var counter versionCounter
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := r.state.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"value": 1}}, opts).Decode(&counter); err != nil {
+		return 0, err
+	}
+	return counter.Value, nil
+}
+
+// Create stores a new version record
+func (r *vocabularyVersionRepository) Create(ctx context.Context, version *models.VocabularyVersion) error {
+	version.GenerateID()
+	_, err := r.collection.InsertOne(ctx, version)
+	return err
+}
+
+// Update saves a version record
+func (r *vocabularyVersionRepository) Update(ctx context.Context, version *models.VocabularyVersion) error {
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": version.ID}, version)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// versionCounter is a document in the state collection holding a counter
+type versionCounter struct {
+	Value int `bson:"value"`
+}
+
+// Revision returns the number of edits recorded for a version
+func (r *vocabularyVersionRepository) Revision(ctx context.Context, versionID string) (int, error) {
+	var counter versionCounter
+	err := r.state.FindOne(ctx, bson.M{"_id": revisionCounterPrefix + versionID}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return counter.Value, err
+}
+
+// RecordEdit records a single entry created, edited or deleted in a version. The version's
+// revision is raised in one atomic update and the edit is stored under the new revision. The
+// entry must already be saved, so a snapshot taken before the revision was raised may miss the
+// edit but one taken after always includes it.
+func (r *vocabularyVersionRepository) RecordEdit(ctx context.Context, versionID, vocabularyID string, after *models.Vocabulary) error {
+	var counter versionCounter
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	filter := bson.M{"_id": revisionCounterPrefix + versionID}
+	if err := r.state.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"value": 1}}, opts).Decode(&counter); err != nil {
+		return err
+	}
+
+	edit := &models.VocabularyEdit{
+		ID:           primitive.NewObjectID().Hex(),
+		VersionID:    versionID,
+		Revision:     counter.Value,
+		VocabularyID: vocabularyID,
+		After:        after,
+		CreatedAt:    time.Now(),
+	}
+	_, err := r.edits.InsertOne(ctx, edit)
+	return err
+}
+
+// GetEditsSince retrieves a version's edits after the given revision, oldest first
+func (r *vocabularyVersionRepository) GetEditsSince(ctx context.Context, versionID string, revision int) ([]*models.VocabularyEdit, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: 1}})
+	cursor, err := r.edits.Find(ctx, bson.M{"version_id": versionID, "revision": bson.M{"$gt": revision}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var edits []*models.VocabularyEdit
+	if err := cursor.All(ctx, &edits); err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// DeleteEdits removes the recorded edits of a version and its revision count
+func (r *vocabularyVersionRepository) DeleteEdits(ctx context.Context, versionID string) error {
+	if _, err := r.edits.DeleteMany(ctx, bson.M{"version_id": versionID}); err != nil {
+		return err
+	}
+	_, err := r.state.DeleteOne(ctx, bson.M{"_id": revisionCounterPrefix + versionID})
+	return err
+}
+
+// Delete removes a version record. The version's collections are not dropped.
+func (r *vocabularyVersionRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
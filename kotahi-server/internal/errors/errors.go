@@ -94,6 +94,72 @@ var (
 		Message: "Insufficient permissions",
 	}
 
+	// Media not found
+	ErrMediaNotFound = &APIError{
+		Code:    "MEDIA_NOT_FOUND",
+		Message: "Media not found",
+	}
+
+	// Subtitle track not found
+	ErrSubtitleTrackNotFound = &APIError{
+		Code:    "SUBTITLE_TRACK_NOT_FOUND",
+		Message: "Subtitle track not found",
+	}
+
+	// Cue not found
+	ErrCueNotFound = &APIError{
+		Code:    "CUE_NOT_FOUND",
+		Message: "Cue not found",
+	}
+
+	// Subtitle version not found
+	ErrSubtitleVersionNotFound = &APIError{
+		Code:    "SUBTITLE_VERSION_NOT_FOUND",
+		Message: "Subtitle version not found",
+	}
+
+	// Subtitle track saved by someone else
+	ErrVersionConflict = &APIError{
+		Code:    "VERSION_CONFLICT",
+		Message: "Subtitle track has changed since it was loaded",
+	}
+
+	// Subtitle file failed linting
+	ErrSubtitleLintFailed = &APIError{
+		Code:    "SUBTITLE_LINT_FAILED",
+		Message: "Subtitle file has lint errors",
+	}
+
+	// Imported vocabulary clashes with existing entries
+	ErrVocabularyConflict = &APIError{
+		Code:    "VOCABULARY_CONFLICT",
+		Message: "Vocabulary already exists",
+	}
+
+	// Vocabulary version not found
+	ErrVocabularyVersionNotFound = &APIError{
+		Code:    "VOCABULARY_VERSION_NOT_FOUND",
+		Message: "Vocabulary version not found",
+	}
+
+	// Background job not found
+	ErrJobNotFound = &APIError{
+		Code:    "JOB_NOT_FOUND",
+		Message: "Job not found",
+	}
+
+	// Background job already completed or failed
+	ErrJobFinished = &APIError{
+		Code:    "JOB_FINISHED",
+		Message: "Job has already finished",
+	}
+
+	// Invalid or expired media signature
+	ErrInvalidSignature = &APIError{
+		Code:    "INVALID_SIGNATURE",
+		Message: "Invalid or expired signature",
+	}
+
 	// Invalid ID
 	ErrInvalidID = &APIError{
 		Code:    "INVALID_ID",
@@ -157,17 +223,24 @@ func WriteErrorResponse(w http.ResponseWriter, err error) {
 // getStatusCodeFromError maps error codes to HTTP status codes
 func getStatusCodeFromError(err *APIError) int {
 	switch err.Code {
-	case "VIDEO_NOT_FOUND", "USER_NOT_FOUND", "VOCABULARY_NOT_FOUND", "WATCH_HISTORY_NOT_FOUND":
+	case "VIDEO_NOT_FOUND", "USER_NOT_FOUND", "VOCABULARY_NOT_FOUND", "WATCH_HISTORY_NOT_FOUND", "MEDIA_NOT_FOUND", "SUBTITLE_TRACK_NOT_FOUND",
+		"CUE_NOT_FOUND", "SUBTITLE_VERSION_NOT_FOUND", "VOCABULARY_VERSION_NOT_FOUND", "JOB_NOT_FOUND":
 		return http.StatusNotFound
-	case "INVALID_REQUEST", "VALIDATION_ERROR":
+	case "INVALID_REQUEST", "VALIDATION_ERROR", "INVALID_FILE_TYPE", "INVALID_FILENAME":
 		return http.StatusBadRequest
+	case "SUBTITLE_LINT_FAILED":
+		return http.StatusUnprocessableEntity
+	case "FILE_TOO_LARGE":
+		return http.StatusRequestEntityTooLarge
+	case "INVALID_SIGNATURE":
+		return http.StatusForbidden
 	case "UNAUTHORIZED", "INVALID_TOKEN":
 		return http.StatusUnauthorized
 	case "INVALID_CREDENTIALS":
 		return http.StatusUnauthorized
 	case "INSUFFICIENT_PERMISSIONS":
 		return http.StatusForbidden
-	case "USER_ALREADY_EXISTS":
+	case "USER_ALREADY_EXISTS", "VERSION_CONFLICT", "VOCABULARY_CONFLICT", "JOB_FINISHED":
 		return http.StatusConflict
 	case "DATABASE_ERROR", "INTERNAL_SERVER_ERROR":
 		return http.StatusInternalServerError
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/errors"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/utils"
+	"video-player-backend/internal/validation"
+)
+
+// Concordance sort orders
+const (
+	concordanceSortVideo    = "video"    // Grouped by video title, then by time within the video
+	concordanceSortPosition = "position" // By time within the video, across all videos
+)
+
+// concordanceMaxCues caps how many matching cues a concordance search reads. Searches for very
+// common words stop there and report that the results were truncated.
+const concordanceMaxCues = 5000
+
+// languageCodeRegex matches the primary subtag of a language code, e.g. "mi" or "en"
+var languageCodeRegex = regexp.MustCompile(`^[A-Za-z]{2,3}$`)
+
+// ConcordanceHandler handles keyword-in-context searches over the transcript index
+type ConcordanceHandler struct {
+	videoRepo    database.VideoRepository
+	cueIndexRepo database.CueIndexRepository
+}
+
+// NewConcordanceHandler creates a new concordance handler
+func NewConcordanceHandler(videoRepo database.VideoRepository, cueIndexRepo database.CueIndexRepository) *ConcordanceHandler {
+	return &ConcordanceHandler{
+		videoRepo:    videoRepo,
+		cueIndexRepo: cueIndexRepo,
+	}
+}
+
+// concordanceHit is a match before its context has been filled in
+type concordanceHit struct {
+	line  models.ConcordanceLine
+	cue   *models.IndexedCue
+	span  utils.TextSpan
+	title string
+}
+
+// GetConcordance handles GET /transcripts/concordance?q={word or phrase}
+func (h *ConcordanceHandler) GetConcordance(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	params := r.URL.Query()
+	query := strings.TrimSpace(params.Get("q"))
+	words := utils.PhraseWords(query)
+	if len([]rune(query)) < 2 || len(words) == 0 {
+		errors.WriteErrorResponse(w, errors.NewAPIError(
+			errors.ErrInvalidRequest.Code,
+			"Search query parameter 'q' must be a word or phrase of at least 2 characters",
+		))
+		return
+	}
+
+	language := params.Get("lang")
+	if language != "" && !languageCodeRegex.MatchString(language) {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid language", "lang must be a language code such as 'mi' or 'en'"))
+		return
+	}
+
+	videoID := params.Get("video_id")
+	if videoID != "" {
+		if ve := validation.ValidateVideoID(videoID); ve.HasErrors() {
+			errors.WriteValidationError(w, ve)
+			return
+		}
+	}
+
+	sortBy := params.Get("sort")
+	if sortBy == "" {
+		sortBy = concordanceSortVideo
+	}
+	if sortBy != concordanceSortVideo && sortBy != concordanceSortPosition {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid sort", "sort must be 'video' or 'position'"))
+		return
+	}
+
+	width, err := queryInt(r, "context", 40, 0, 200)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIError(errors.ErrInvalidRequest.Code, err.Error()))
+		return
+	}
+	page, err := queryInt(r, "page", 1, 1, 10000)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIError(errors.ErrInvalidRequest.Code, err.Error()))
+		return
+	}
+	limit, err := queryInt(r, "limit", 20, 1, 100)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIError(errors.ErrInvalidRequest.Code, err.Error()))
+		return
+	}
+
+	// Only active videos are searchable, trashed ones keep their index until they are purged
+	videos, err := h.videoRepo.GetAll(ctx)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+	titles := make(map[string]string, len(videos))
+	var videoIDs []string
+	for _, video := range videos {
+		titles[video.ID] = video.Title
+		if videoID == "" || video.ID == videoID {
+			videoIDs = append(videoIDs, video.ID)
+		}
+	}
+
+	// Read one cue past the cap to tell whether there were more
+	cues, err := h.cueIndexRepo.FindByTokens(ctx, words, language, videoIDs, concordanceMaxCues+1)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+	truncated := len(cues) > concordanceMaxCues
+	if truncated {
+		cues = cues[:concordanceMaxCues]
+	}
+
+	// The index only guarantees every word is in the cue, so check the words are in order
+	var hits []concordanceHit
+	for _, cue := range cues {
+		title, ok := titles[cue.VideoID]
+		if !ok {
+			continue
+		}
+		for _, span := range utils.FindPhrase(cue.Text, words) {
+			hits = append(hits, concordanceHit{
+				line: models.ConcordanceLine{
+					VideoID:    cue.VideoID,
+					VideoTitle: title,
+					TrackID:    cue.TrackID,
+					Language:   cue.Language,
+					CueIndex:   cue.CueIndex,
+					StartTime:  cue.StartTime,
+					EndTime:    cue.EndTime,
+				},
+				cue:   cue,
+				span:  span,
+				title: strings.ToLower(title),
+			})
+		}
+	}
+
+	sortConcordanceHits(hits, sortBy)
+
+	total := len(hits)
+	start := (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	pageHits := hits[start:end]
+
+	lines, err := h.fillContext(ctx, pageHits, width)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":     query,
+		"data":      lines,
+		"count":     len(lines),
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+		"truncated": truncated,
+	})
+}
+
+// fillContext sets the left and right context of each hit. When the cue doesn't hold enough text
+// the context continues into the previous or next cue of the same track.
+func (h *ConcordanceHandler) fillContext(ctx context.Context, hits []concordanceHit, width int) ([]models.ConcordanceLine, error) {
+	// Collect the neighbouring cues to look up, one query per track
+	type trackKey struct{ videoID, trackID string }
+	wanted := make(map[trackKey][]int)
+	for _, hit := range hits {
+		runes := []rune(hit.cue.Text)
+		key := trackKey{hit.cue.VideoID, hit.cue.TrackID}
+		if hit.span.Start < width {
+			wanted[key] = append(wanted[key], hit.cue.CueIndex-1)
+		}
+		if len(runes)-hit.span.End < width {
+			wanted[key] = append(wanted[key], hit.cue.CueIndex+1)
+		}
+	}
+
+	neighbours := make(map[trackKey]map[int]string)
+	for key, indexes := range wanted {
+		cues, err := h.cueIndexRepo.GetByCueIndexes(ctx, key.videoID, key.trackID, indexes)
+		if err != nil {
+			return nil, err
+		}
+		neighbours[key] = make(map[int]string, len(cues))
+		for _, cue := range cues {
+			neighbours[key][cue.CueIndex] = cue.Text
+		}
+	}
+
+	lines := make([]models.ConcordanceLine, 0, len(hits))
+	for _, hit := range hits {
+		runes := []rune(hit.cue.Text)
+		key := trackKey{hit.cue.VideoID, hit.cue.TrackID}
+		left := string(runes[:hit.span.Start])
+		right := string(runes[hit.span.End:])
+		if previous, ok := neighbours[key][hit.cue.CueIndex-1]; ok {
+			left = previous + " " + left
+		}
+		if next, ok := neighbours[key][hit.cue.CueIndex+1]; ok {
+			right = right + " " + next
+		}
+
+		line := hit.line
+		line.Left = utils.ContextBefore(left, width)
+		line.Match = string(runes[hit.span.Start:hit.span.End])
+		line.Right = utils.ContextAfter(right, width)
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// sortConcordanceHits orders hits by video title or by time, keeping the other as a tie-breaker
+func sortConcordanceHits(hits []concordanceHit, sortBy string) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if sortBy == concordanceSortPosition && a.line.StartTime != b.line.StartTime {
+			return a.line.StartTime < b.line.StartTime
+		}
+		if a.title != b.title {
+			return a.title < b.title
+		}
+		if a.line.VideoID != b.line.VideoID {
+			return a.line.VideoID < b.line.VideoID
+		}
+		if a.line.TrackID != b.line.TrackID {
+			return a.line.TrackID < b.line.TrackID
+		}
+		if a.line.StartTime != b.line.StartTime {
+			return a.line.StartTime < b.line.StartTime
+		}
+		return a.span.Start < b.span.Start
+	})
+}
+
+// queryInt reads an integer query parameter, using the default when it is missing
+func queryInt(r *http.Request, name string, def, min, max int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || parsed > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+	return parsed, nil
+}
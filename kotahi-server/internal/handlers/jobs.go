@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"video-player-backend/internal/errors"
+	"video-player-backend/internal/services"
+	"video-player-backend/internal/utils"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// JobHandler handles background job status and cancellation
+type JobHandler struct {
+	jobs *services.JobRunner
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobs *services.JobRunner) *JobHandler {
+	return &JobHandler{
+		jobs: jobs,
+	}
+}
+
+// GetJobs handles GET /jobs?type=&status=&limit=, newest first
+func (h *JobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	limit, err := queryInt(r, "limit", 50, 1, 200)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIError(errors.ErrInvalidRequest.Code, err.Error()))
+		return
+	}
+
+	query := r.URL.Query()
+	jobs, err := h.jobs.List(ctx, query.Get("type"), query.Get("status"), limit)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// GetJob handles GET /jobs/{id}
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	job, err := h.jobs.Get(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": job})
+}
+
+// CancelJob handles POST /jobs/{id}/cancel. A pending job is cancelled straight away and a
+// running one is asked to stop, so its status may still be running in the response.
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	job, err := h.jobs.Cancel(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Job cancellation requested",
+		"data":    job,
+	})
+}
+
+// writeJobError maps job errors to API errors
+func writeJobError(w http.ResponseWriter, err error) {
+	switch err {
+	case mongo.ErrNoDocuments:
+		errors.WriteErrorResponse(w, errors.ErrJobNotFound)
+	case services.ErrJobFinished:
+		errors.WriteErrorResponse(w, errors.ErrJobFinished)
+	default:
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+	}
+}
@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/errors"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/storage"
+	"video-player-backend/internal/utils"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// maxVideoUploadSize is the largest video file accepted by the upload endpoint
+	maxVideoUploadSize = int64(2 << 30) // 2GB
+	// maxThumbnailUploadSize is the largest thumbnail file accepted by the upload endpoint
+	maxThumbnailUploadSize = int64(10 << 20) // 10MB
+	// defaultSignedURLTTL is how long signed media URLs stay valid by default
+	defaultSignedURLTTL = time.Hour
+	// maxSignedURLTTL is the longest validity that can be requested for a signed URL
+	maxSignedURLTTL = 7 * 24 * time.Hour
+)
+
+// mediaContentTypes maps allowed file extensions to content types for each media kind
+var mediaContentTypes = map[string]map[string]string{
+	models.MediaKindVideo: {
+		".mp4":  "video/mp4",
+		".m4v":  "video/x-m4v",
+		".webm": "video/webm",
+		".mov":  "video/quicktime",
+		".ogv":  "video/ogg",
+		".mkv":  "video/x-matroska",
+	},
+	models.MediaKindThumbnail: {
+		".jpg":  "image/jpeg",
+		".jpeg": "image/jpeg",
+		".png":  "image/png",
+		".webp": "image/webp",
+		".gif":  "image/gif",
+	},
+}
+
+// MediaHandler handles uploading and streaming of hosted video and thumbnail files
+type MediaHandler struct {
+	repo   database.MediaRepository
+	store  storage.BlobStore
+	signer *storage.URLSigner
+}
+
+// NewMediaHandler creates a new media handler
+func NewMediaHandler(repo database.MediaRepository, store storage.BlobStore, signer *storage.URLSigner) *MediaHandler {
+	return &MediaHandler{
+		repo:   repo,
+		store:  store,
+		signer: signer,
+	}
+}
+
+// UploadMedia handles POST /media/upload
+func (h *MediaHandler) UploadMedia(w http.ResponseWriter, r *http.Request) {
+	// Check if request is multipart/form-data
+	if !strings.Contains(r.Header.Get("Content-Type"), "multipart/form-data") {
+		errors.WriteErrorResponse(w, errors.ErrInvalidRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxVideoUploadSize+(1<<20))
+
+	// Parse multipart form with max memory of 32MB, larger files are spooled to disk
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		log.Printf("Failed to parse multipart form: %v", err)
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+			errors.ErrInvalidRequest.Code,
+			"Failed to parse multipart form",
+			err.Error(),
+		))
+		return
+	}
+
+	kind := r.FormValue("kind")
+	if kind == "" {
+		kind = models.MediaKindVideo
+	}
+	if _, ok := mediaContentTypes[kind]; !ok {
+		errors.WriteErrorResponse(w, errors.NewAPIError(
+			errors.ErrInvalidRequest.Code,
+			"Kind must be 'video' or 'thumbnail'",
+		))
+		return
+	}
+
+	// Media is public unless explicitly marked otherwise
+	public := true
+	if value := r.FormValue("public"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			errors.WriteErrorResponse(w, errors.NewAPIError(
+				errors.ErrInvalidRequest.Code,
+				"Public must be true or false",
+			))
+			return
+		}
+		public = parsed
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+			errors.ErrInvalidRequest.Code,
+			"File is required",
+			"Please upload a file with the 'file' field name",
+		))
+		return
+	}
+	defer file.Close()
+
+	contentType, err := h.validateMediaFile(kind, header.Filename, header.Size)
+	if err != nil {
+		errors.WriteErrorResponse(w, err)
+		return
+	}
+
+	media := &models.Media{
+		Kind:         kind,
+		OriginalName: header.Filename,
+		ContentType:  contentType,
+		Public:       public,
+		UploadedBy:   getUserIDFromContext(r.Context()),
+		CreatedAt:    time.Now(),
+	}
+	media.GenerateID()
+	media.Filename = fmt.Sprintf("%ss/%s%s", kind, media.ID, strings.ToLower(filepath.Ext(header.Filename)))
+
+	// Files of up to 2GB can take much longer than the default timeout to store, so the write
+	// only ends if the client goes away
+	info, err := h.store.Put(r.Context(), media.Filename, file)
+	if err != nil {
+		log.Printf("Failed to store media file: %v", err)
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+	media.Size = info.Size
+
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	if err := h.repo.Create(ctx, media); err != nil {
+		// Clean up the stored file so it is not orphaned, even if the database timed out
+		cleanupCtx, cancelCleanup := utils.ContextWithTimeout()
+		defer cancelCleanup()
+		if err := h.store.Delete(cleanupCtx, media.Filename); err != nil {
+			log.Printf("Failed to remove media file %s after a failed upload: %v", media.Filename, err)
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	media.URL = h.mediaURL(media, defaultSignedURLTTL)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(media)
+}
+
+// ListMedia handles GET /media
+func (h *MediaHandler) ListMedia(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	media, err := h.repo.GetAll(ctx, r.URL.Query().Get("kind"))
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	if media == nil {
+		media = []*models.Media{}
+	}
+
+	for _, m := range media {
+		m.URL = h.mediaURL(m, defaultSignedURLTTL)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  media,
+		"count": len(media),
+	})
+}
+
+// DeleteMedia handles DELETE /media/{id}
+func (h *MediaHandler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+
+	media, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrMediaNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	if err := h.repo.Delete(ctx, id); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	if err := h.store.Delete(ctx, media.Filename); err != nil {
+		log.Printf("Failed to delete media file %s: %v", media.Filename, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSignedMediaURL handles GET /media/{id}/signed-url?ttl={seconds}
+func (h *MediaHandler) GetSignedMediaURL(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+
+	ttl := defaultSignedURLTTL
+	if value := r.URL.Query().Get("ttl"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			errors.WriteErrorResponse(w, errors.NewAPIError(
+				errors.ErrInvalidRequest.Code,
+				"TTL must be a positive number of seconds",
+			))
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+		if ttl > maxSignedURLTTL {
+			ttl = maxSignedURLTTL
+		}
+	}
+
+	media, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrMediaNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         media.ID,
+		"url":        h.signer.SignURL(media.Path(), media.ID, ttl),
+		"expires_at": time.Now().Add(ttl).UTC(),
+	})
+}
+
+// StreamMedia handles GET /media/{id}, serving the file with Range and conditional request support
+func (h *MediaHandler) StreamMedia(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+
+	media, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrMediaNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	// Non-public media must be accessed through a valid signed URL
+	if !media.Public {
+		query := r.URL.Query()
+		if err := h.signer.Verify(media.ID, query.Get("expires"), query.Get("signature")); err != nil {
+			errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInvalidSignature))
+			return
+		}
+	}
+
+	obj, info, err := h.store.Get(r.Context(), media.Filename)
+	if err != nil {
+		log.Printf("Failed to open media file %s: %v", media.Filename, err)
+		errors.WriteErrorResponse(w, errors.ErrMediaNotFound)
+		return
+	}
+	defer obj.Close()
+
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%x-%x"`, media.ID, info.Size, info.ModTime.UnixNano()))
+	if media.Public {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	} else {
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+	}
+
+	// ServeContent handles Range, If-Range, If-None-Match and If-Modified-Since
+	http.ServeContent(w, r, media.Filename, info.ModTime, obj)
+}
+
+// validateMediaFile validates an uploaded media file and returns its content type
+func (h *MediaHandler) validateMediaFile(kind, filename string, size int64) (string, error) {
+	// Check if filename is empty
+	if strings.TrimSpace(filename) == "" {
+		return "", errors.NewAPIError("INVALID_FILENAME", "Filename cannot be empty.")
+	}
+
+	// Check file extension
+	ext := strings.ToLower(filepath.Ext(filename))
+	contentType, ok := mediaContentTypes[kind][ext]
+	if !ok {
+		allowed := make([]string, 0, len(mediaContentTypes[kind]))
+		for allowedExt := range mediaContentTypes[kind] {
+			allowed = append(allowed, allowedExt)
+		}
+		sort.Strings(allowed)
+		return "", errors.NewAPIErrorWithDetails("INVALID_FILE_TYPE",
+			fmt.Sprintf("Invalid file type for %s uploads.", kind),
+			"Allowed extensions: "+strings.Join(allowed, ", "))
+	}
+
+	// Check file size
+	maxSize := maxVideoUploadSize
+	if kind == models.MediaKindThumbnail {
+		maxSize = maxThumbnailUploadSize
+	}
+	if size > maxSize {
+		return "", errors.NewAPIError("FILE_TOO_LARGE", fmt.Sprintf("File too large. Maximum size is %dMB.", maxSize>>20))
+	}
+
+	return contentType, nil
+}
+
+// mediaURL returns the URL clients should use to fetch a media file
+func (h *MediaHandler) mediaURL(media *models.Media, ttl time.Duration) string {
+	if media.Public {
+		return media.Path()
+	}
+	return h.signer.SignURL(media.Path(), media.ID, ttl)
+}
@@ -71,6 +71,12 @@ func (h *PlaylistHandler) GetPlaylists(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Hide videos that are in the trash
+	if err := h.hideTrashedVideos(ctx, allPlaylists...); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(allPlaylists)
@@ -109,6 +115,12 @@ func (h *PlaylistHandler) GetPlaylist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Hide videos that are in the trash
+	if err := h.hideTrashedVideos(ctx, playlist); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(playlist)
@@ -193,10 +205,17 @@ func (h *PlaylistHandler) UpdatePlaylist(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	// Keep trashed videos in the playlist so they come back if the video is restored
+	trashedIDs, err := h.trashedVideoIDs(ctx)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
 	// Update playlist
 	playlist.Name = playlistReq.Name
 	playlist.Description = playlistReq.Description
-	playlist.VideoIDs = playlistReq.VideoIDs
+	playlist.VideoIDs = keepTrashedVideos(trashedIDs, playlist.VideoIDs, playlistReq.VideoIDs)
 	playlist.IsPublic = playlistReq.IsPublic
 	playlist.UpdatedAt = time.Now()
 
@@ -205,6 +224,8 @@ func (h *PlaylistHandler) UpdatePlaylist(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	playlist.VideoIDs = playlistReq.VideoIDs
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(playlist)
@@ -363,8 +384,15 @@ func (h *PlaylistHandler) ReorderPlaylistVideos(w http.ResponseWriter, r *http.R
 		}
 	}
 
+	// Keep trashed videos in the playlist so they come back if the video is restored
+	trashedIDs, err := h.trashedVideoIDs(ctx)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
 	// Reorder videos
-	if err := h.playlistRepo.ReorderVideos(ctx, playlistID, req.VideoIDs); err != nil {
+	if err := h.playlistRepo.ReorderVideos(ctx, playlistID, keepTrashedVideos(trashedIDs, playlist.VideoIDs, req.VideoIDs)); err != nil {
 		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
 		return
 	}
@@ -383,6 +411,12 @@ func (h *PlaylistHandler) GetUserPlaylists(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Hide videos that are in the trash
+	if err := h.hideTrashedVideos(ctx, playlists...); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(playlists)
@@ -398,6 +432,12 @@ func (h *PlaylistHandler) GetPublicPlaylists(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Hide videos that are in the trash
+	if err := h.hideTrashedVideos(ctx, playlists...); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(playlists)
@@ -428,6 +468,53 @@ func (h *PlaylistHandler) populatePlaylistVideos(ctx context.Context, playlist *
 	}, nil
 }
 
+// trashedVideoIDs returns the set of video IDs currently in the trash
+func (h *PlaylistHandler) trashedVideoIDs(ctx context.Context) (map[string]bool, error) {
+	videos, err := h.videoRepo.GetTrashed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		ids[video.ID] = true
+	}
+	return ids, nil
+}
+
+// hideTrashedVideos removes trashed videos from the playlists' video lists before they are returned
+func (h *PlaylistHandler) hideTrashedVideos(ctx context.Context, playlists ...*models.Playlist) error {
+	trashedIDs, err := h.trashedVideoIDs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(trashedIDs) == 0 {
+		return nil
+	}
+
+	for _, playlist := range playlists {
+		visible := make([]string, 0, len(playlist.VideoIDs))
+		for _, videoID := range playlist.VideoIDs {
+			if !trashedIDs[videoID] {
+				visible = append(visible, videoID)
+			}
+		}
+		playlist.VideoIDs = visible
+	}
+	return nil
+}
+
+// keepTrashedVideos appends trashed videos from the previous list to an updated list of video IDs
+func keepTrashedVideos(trashedIDs map[string]bool, previous, updated []string) []string {
+	result := append([]string{}, updated...)
+	for _, videoID := range previous {
+		if trashedIDs[videoID] {
+			result = append(result, videoID)
+		}
+	}
+	return result
+}
+
 // validatePlaylistRequest validates a playlist request
 func validatePlaylistRequest(req *models.PlaylistRequest) error {
 	if req.Name == "" {
@@ -9,13 +9,15 @@ import (
 	"video-player-backend/internal/database"
 	"video-player-backend/internal/middleware"
 	"video-player-backend/internal/services"
+	"video-player-backend/internal/sources"
+	"video-player-backend/internal/storage"
 	jwtutils "video-player-backend/internal/utils"
 
 	"github.com/gorilla/mux"
 )
 
 // SetupRoutes configures all routes for the application
-func SetupRoutes(cfg *config.Config, db *database.MongoDB, videoRepo database.VideoRepository, userRepo database.UserRepository, vocabRepo database.VocabularyRepository, vocabIndexRepo database.VocabularyIndexRepository, watchHistoryRepo database.WatchHistoryRepository, playlistRepo database.PlaylistRepository) *mux.Router {
+func SetupRoutes(cfg *config.Config, db *database.MongoDB, videoRepo database.VideoRepository, userRepo database.UserRepository, vocabRepo database.VocabularyRepository, vocabIndexRepo database.VocabularyIndexRepository, watchHistoryRepo database.WatchHistoryRepository, playlistRepo database.PlaylistRepository, mediaRepo database.MediaRepository, subtitleVersionRepo database.SubtitleVersionRepository, sentencePairRepo database.SentencePairRepository, cueIndexRepo database.CueIndexRepository, subtitleStore storage.BlobStore, trashService *services.VideoTrashService, indexService *services.VocabularyIndexService, versionService *services.VocabularyVersionService, jobRunner *services.JobRunner) *mux.Router {
 	r := mux.NewRouter()
 
 	log.Println("Setting up routes")
@@ -29,18 +31,27 @@ func SetupRoutes(cfg *config.Config, db *database.MongoDB, videoRepo database.Vi
 	// Create email service
 	emailService := services.NewEmailService(&cfg.Email)
 
+	// Create media storage and URL signer for hosted video and thumbnail files
+	mediaStore := storage.NewLocalStorage("./uploads/media", "", nil)
+	mediaSigner := storage.NewURLSigner(cfg.JWT.Secret)
+
 	// Create handlers
-	videoHandler := NewVideoHandler(videoRepo)
+	videoHandler := NewVideoHandler(videoRepo, sources.NewDefaultRegistry(), trashService, indexService, services.NewRelatedVideoService(videoRepo, vocabIndexRepo, watchHistoryRepo), jobRunner)
 	authHandler := NewAuthHandler(userRepo, jwtManager)
-	vocabularyHandler := NewVocabularyHandler(vocabRepo, vocabIndexRepo, videoRepo)
-	vocabularySearchHandler := NewVocabularySearchHandler(vocabRepo, vocabIndexRepo, videoRepo, watchHistoryRepo)
+	vocabularyHandler := NewVocabularyHandler(vocabRepo, vocabIndexRepo, videoRepo, indexService, versionService)
+	vocabularySearchHandler := NewVocabularySearchHandler(vocabRepo, vocabIndexRepo, videoRepo, watchHistoryRepo, indexService, jobRunner)
 	watchHistoryHandler := NewWatchHistoryHandler(watchHistoryRepo, videoRepo)
-	vttHandler := NewVTTUploadHandler("./uploads/vtt", vocabRepo, vocabIndexRepo, videoRepo)
+	vttHandler := NewVTTUploadHandler(subtitleStore, videoRepo, vocabRepo, indexService, jobRunner)
 	learningListHandler := NewLearningListHandler(db)
 	playlistHandler := NewPlaylistHandler(playlistRepo, videoRepo)
-	searchHandler := NewSearchHandler(videoRepo, vocabRepo, vocabIndexRepo)
+	searchHandler := NewSearchHandler(videoRepo, vocabRepo, vocabIndexRepo, sentencePairRepo)
 	feedbackHandler := NewFeedbackHandler(emailService)
 	contactHandler := NewContactHandler(emailService)
+	mediaHandler := NewMediaHandler(mediaRepo, mediaStore, mediaSigner)
+	transcriptHandler := NewTranscriptHandler(videoRepo, vocabIndexRepo, sentencePairRepo, indexService)
+	concordanceHandler := NewConcordanceHandler(videoRepo, cueIndexRepo)
+	subtitleEditorHandler := NewSubtitleEditorHandler(videoRepo, services.NewSubtitleEditorService(subtitleVersionRepo, videoRepo, indexService))
+	jobHandler := NewJobHandler(jobRunner)
 
 	// API routes
 	api := r.PathPrefix("/api/v1").Subrouter()
@@ -64,9 +75,31 @@ func SetupRoutes(cfg *config.Config, db *database.MongoDB, videoRepo database.Vi
 	// Video routes - public read access, admin-only write access
 	api.HandleFunc("/videos", videoHandler.GetVideos).Methods("GET")
 	api.HandleFunc("/videos/{id}", videoHandler.GetVideo).Methods("GET")
+	api.HandleFunc("/videos/{id}/related", videoHandler.GetRelatedVideos).Methods("GET")
+	api.HandleFunc("/videos/{id}/transcript", transcriptHandler.GetTranscript).Methods("GET")
+	api.HandleFunc("/videos/{id}/transcript/export", transcriptHandler.ExportTranscript).Methods("GET")
 	admin.HandleFunc("/videos", videoHandler.CreateVideo).Methods("POST")
+	admin.HandleFunc("/videos/trash", videoHandler.GetTrash).Methods("GET")
+	admin.HandleFunc("/videos/import", videoHandler.ImportVideos).Methods("POST")
 	admin.HandleFunc("/videos/{id}", videoHandler.UpdateVideo).Methods("PUT")
 	admin.HandleFunc("/videos/{id}", videoHandler.DeleteVideo).Methods("DELETE")
+	admin.HandleFunc("/videos/{id}/align", transcriptHandler.AlignTranscript).Methods("POST")
+	admin.HandleFunc("/videos/{id}/resolve", videoHandler.ResolveVideoSource).Methods("POST")
+	admin.HandleFunc("/videos/{id}/restore", videoHandler.RestoreVideo).Methods("POST")
+	admin.HandleFunc("/videos/{id}/purge", videoHandler.PurgeVideo).Methods("DELETE")
+
+	// Subtitle editor routes (admin-only)
+	admin.HandleFunc("/videos/{id}/tracks/{track}/cues", subtitleEditorHandler.GetCues).Methods("GET")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/cues", subtitleEditorHandler.InsertCue).Methods("POST")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/cues/{index}", subtitleEditorHandler.UpdateCue).Methods("PUT")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/cues/{index}", subtitleEditorHandler.DeleteCue).Methods("DELETE")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/cues/{index}/split", subtitleEditorHandler.SplitCue).Methods("POST")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/cues/{index}/merge", subtitleEditorHandler.MergeCues).Methods("POST")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/retime", subtitleEditorHandler.RetimeCues).Methods("POST")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/versions", subtitleEditorHandler.GetVersions).Methods("GET")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/versions/{version}", subtitleEditorHandler.GetVersion).Methods("GET")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/versions/{version}/diff", subtitleEditorHandler.DiffVersions).Methods("GET")
+	admin.HandleFunc("/videos/{id}/tracks/{track}/versions/{version}/rollback", subtitleEditorHandler.RollbackVersion).Methods("POST")
 
 	// Vocabulary routes - public read access, admin-only write access
 	api.HandleFunc("/vocabulary", vocabularyHandler.GetVocabularies).Methods("GET")
@@ -74,6 +107,10 @@ func SetupRoutes(cfg *config.Config, db *database.MongoDB, videoRepo database.Vi
 	api.HandleFunc("/vocabulary/search", vocabularyHandler.SearchVocabularies).Methods("GET")
 	admin.HandleFunc("/vocabulary", vocabularyHandler.CreateVocabulary).Methods("POST")
 	admin.HandleFunc("/vocabulary/batch-upload", vocabularyHandler.BatchVocabularyUpload).Methods("POST")
+	admin.HandleFunc("/vocabulary/versions", vocabularyHandler.GetVersions).Methods("GET")
+	admin.HandleFunc("/vocabulary/versions/rollback", vocabularyHandler.RollbackVersion).Methods("POST")
+	admin.HandleFunc("/vocabulary/versions/{id}", vocabularyHandler.GetVersion).Methods("GET")
+	admin.HandleFunc("/vocabulary/versions/{id}/activate", vocabularyHandler.ActivateVersion).Methods("POST")
 	admin.HandleFunc("/vocabulary/{id}", vocabularyHandler.UpdateVocabulary).Methods("PUT")
 	admin.HandleFunc("/vocabulary/{id}", vocabularyHandler.DeleteVocabulary).Methods("DELETE")
 
@@ -83,6 +120,7 @@ func SetupRoutes(cfg *config.Config, db *database.MongoDB, videoRepo database.Vi
 	api.HandleFunc("/vocabulary/video", vocabularySearchHandler.GetVideoVocabulary).Methods("GET")
 	api.HandleFunc("/vocabulary/stats", vocabularySearchHandler.GetVocabularyStats).Methods("GET")
 	admin.HandleFunc("/vocabulary/reindex", vocabularySearchHandler.ReindexAllVideos).Methods("POST")
+	admin.HandleFunc("/vocabulary/index/check", vocabularySearchHandler.CheckIndex).Methods("GET")
 
 	// Watch history routes (authenticated users - no admin required)
 	protected.HandleFunc("/watch-history", watchHistoryHandler.GetWatchHistory).Methods("GET")
@@ -116,6 +154,7 @@ func SetupRoutes(cfg *config.Config, db *database.MongoDB, videoRepo database.Vi
 
 	// General search route (public access)
 	api.HandleFunc("/search", searchHandler.GeneralSearch).Methods("GET")
+	api.HandleFunc("/transcripts/concordance", concordanceHandler.GetConcordance).Methods("GET")
 
 	// Contact and feedback routes (public access)
 	api.HandleFunc("/contact", contactHandler.SubmitContact).Methods("POST")
@@ -123,14 +162,29 @@ func SetupRoutes(cfg *config.Config, db *database.MongoDB, videoRepo database.Vi
 
 	// VTT file routes (admin-only)
 	admin.HandleFunc("/vtt/upload", vttHandler.UploadVTT).Methods("POST")
+	admin.HandleFunc("/vtt/lint", vttHandler.LintVTT).Methods("POST")
 	admin.HandleFunc("/vtt/list", vttHandler.ListVTTFiles).Methods("GET")
 	admin.HandleFunc("/vtt/delete", vttHandler.DeleteVTTFile).Methods("DELETE")
+	// Deprecated: use /jobs/{id}
+	admin.HandleFunc("/vtt/jobs/{id}", jobHandler.GetJob).Methods("GET")
+
+	// Media file routes - admin-only management, streaming is public or via signed URL
+	admin.HandleFunc("/media/upload", mediaHandler.UploadMedia).Methods("POST")
+	admin.HandleFunc("/media", mediaHandler.ListMedia).Methods("GET")
+	admin.HandleFunc("/media/{id}/signed-url", mediaHandler.GetSignedMediaURL).Methods("GET")
+	admin.HandleFunc("/media/{id}", mediaHandler.DeleteMedia).Methods("DELETE")
+	api.HandleFunc("/media/{id}", mediaHandler.StreamMedia).Methods("GET", "HEAD")
+
+	// Background job routes (admin-only)
+	admin.HandleFunc("/jobs", jobHandler.GetJobs).Methods("GET")
+	admin.HandleFunc("/jobs/{id}", jobHandler.GetJob).Methods("GET")
+	admin.HandleFunc("/jobs/{id}/cancel", jobHandler.CancelJob).Methods("POST")
 
 	// Admin email test route
 	admin.HandleFunc("/email/test", feedbackHandler.TestEmail).Methods("POST")
 
 	// Static file serving for uploaded VTT files
-	api.PathPrefix("/uploads/vtt/").Handler(http.StripPrefix("/api/v1/uploads/vtt/", http.FileServer(http.Dir("./uploads/vtt/"))))
+	api.HandleFunc("/uploads/vtt/{filename}", vttHandler.GetVTTFile).Methods("GET", "HEAD")
 
 	// Health check endpoint
 	r.HandleFunc("/health", healthCheck).Methods("GET")
@@ -11,6 +11,8 @@ import (
 	"video-player-backend/internal/errors"
 	"video-player-backend/internal/models"
 	"video-player-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // SearchHandler handles general search requests
@@ -18,6 +20,7 @@ type SearchHandler struct {
 	videoRepo      database.VideoRepository
 	vocabularyRepo database.VocabularyRepository
 	vocabIndexRepo database.VocabularyIndexRepository
+	pairRepo       database.SentencePairRepository
 }
 
 // NewSearchHandler creates a new search handler
@@ -25,11 +28,13 @@ func NewSearchHandler(
 	videoRepo database.VideoRepository,
 	vocabularyRepo database.VocabularyRepository,
 	vocabIndexRepo database.VocabularyIndexRepository,
+	pairRepo database.SentencePairRepository,
 ) *SearchHandler {
 	return &SearchHandler{
 		videoRepo:      videoRepo,
 		vocabularyRepo: vocabularyRepo,
 		vocabIndexRepo: vocabIndexRepo,
+		pairRepo:       pairRepo,
 	}
 }
 
@@ -211,10 +216,28 @@ func (h *SearchHandler) searchVideosByVocabulary(ctx context.Context, query stri
 	for _, videoID := range videoIDs {
 		video, err := h.videoRepo.GetByID(ctx, videoID)
 		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				// Skip videos that are in the trash or no longer exist
+				delete(vocabOccurrences, videoID)
+				continue
+			}
 			return nil, nil, err
 		}
 		videos = append(videos, video)
 	}
 
+	// Show the English sentence next to each transcript line where the tracks are aligned
+	pairs, err := h.pairRepo.GetByVideoIDs(ctx, videoIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for videoID, occurrences := range vocabOccurrences {
+		for i := range occurrences {
+			if pair := models.FindSentencePair(pairs[videoID], occurrences[i].StartTime); pair != nil {
+				occurrences[i].Translation = pair.English
+			}
+		}
+	}
+
 	return videos, vocabOccurrences, nil
 }
@@ -0,0 +1,528 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/errors"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/services"
+	"video-player-backend/internal/utils"
+	"video-player-backend/internal/validation"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SubtitleEditorHandler handles editing the cues of a video's subtitle tracks
+type SubtitleEditorHandler struct {
+	videoRepo database.VideoRepository
+	editor    *services.SubtitleEditorService
+}
+
+// NewSubtitleEditorHandler creates a new subtitle editor handler
+func NewSubtitleEditorHandler(videoRepo database.VideoRepository, editor *services.SubtitleEditorService) *SubtitleEditorHandler {
+	return &SubtitleEditorHandler{
+		videoRepo: videoRepo,
+		editor:    editor,
+	}
+}
+
+// editRequest holds the fields shared by every request that saves a track
+type editRequest struct {
+	Message     string `json:"message"`
+	BaseVersion *int   `json:"base_version"` // Rejects the save if the track was saved since this version
+}
+
+// cueEditRequest represents a request to edit a cue
+type cueEditRequest struct {
+	editRequest
+	utils.CueEdit
+}
+
+// cueInsertRequest represents a request to insert a cue
+type cueInsertRequest struct {
+	editRequest
+	ID        string  `json:"id"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Text      string  `json:"text"`
+}
+
+// cueSplitRequest represents a request to split a cue
+type cueSplitRequest struct {
+	editRequest
+	Time     float64 `json:"time"`
+	Position *int    `json:"position"` // Rune offset into the cue text, defaults to the word boundary closest to Time
+}
+
+// retimeRequest represents a request to shift or rescale cue timings
+type retimeRequest struct {
+	editRequest
+	utils.TimingAdjustment
+	Preview bool `json:"preview"` // Return the changed cues without saving
+}
+
+// GetCues handles GET /videos/{id}/tracks/{track}/cues
+func (h *SubtitleEditorHandler) GetCues(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	session, ok := h.openSession(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"track":    session.Track,
+		"version":  session.Version,
+		"data":     session.Document.Cues,
+		"count":    len(session.Document.Cues),
+		"warnings": session.Document.Warnings,
+	})
+}
+
+// InsertCue handles POST /videos/{id}/tracks/{track}/cues
+func (h *SubtitleEditorHandler) InsertCue(w http.ResponseWriter, r *http.Request) {
+	var req cueInsertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInvalidRequest))
+		return
+	}
+
+	h.saveEdit(w, r, req.editRequest, "Inserted cue", func(doc *utils.SubtitleDocument) error {
+		_, err := utils.InsertCue(doc, req.ID, req.StartTime, req.EndTime, req.Text)
+		return err
+	})
+}
+
+// UpdateCue handles PUT /videos/{id}/tracks/{track}/cues/{index}
+func (h *SubtitleEditorHandler) UpdateCue(w http.ResponseWriter, r *http.Request) {
+	index, ok := cueIndexFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req cueEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInvalidRequest))
+		return
+	}
+
+	h.saveEdit(w, r, req.editRequest, fmt.Sprintf("Edited cue %d", index), func(doc *utils.SubtitleDocument) error {
+		return utils.EditCue(doc, index, req.CueEdit)
+	})
+}
+
+// DeleteCue handles DELETE /videos/{id}/tracks/{track}/cues/{index}
+func (h *SubtitleEditorHandler) DeleteCue(w http.ResponseWriter, r *http.Request) {
+	index, ok := cueIndexFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req editRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	h.saveEdit(w, r, req, fmt.Sprintf("Deleted cue %d", index), func(doc *utils.SubtitleDocument) error {
+		return utils.DeleteCue(doc, index)
+	})
+}
+
+// SplitCue handles POST /videos/{id}/tracks/{track}/cues/{index}/split
+func (h *SubtitleEditorHandler) SplitCue(w http.ResponseWriter, r *http.Request) {
+	index, ok := cueIndexFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req cueSplitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInvalidRequest))
+		return
+	}
+
+	position := -1
+	if req.Position != nil {
+		position = *req.Position
+	}
+
+	h.saveEdit(w, r, req.editRequest, fmt.Sprintf("Split cue %d", index), func(doc *utils.SubtitleDocument) error {
+		return utils.SplitCue(doc, index, req.Time, position)
+	})
+}
+
+// MergeCues handles POST /videos/{id}/tracks/{track}/cues/{index}/merge
+func (h *SubtitleEditorHandler) MergeCues(w http.ResponseWriter, r *http.Request) {
+	index, ok := cueIndexFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req editRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	h.saveEdit(w, r, req, fmt.Sprintf("Merged cues %d and %d", index, index+1), func(doc *utils.SubtitleDocument) error {
+		return utils.MergeCues(doc, index)
+	})
+}
+
+// RetimeCues handles POST /videos/{id}/tracks/{track}/retime. It shifts every cue by offset
+// seconds and/or rescales timings between two anchors, saving a new version unless preview is set.
+func (h *SubtitleEditorHandler) RetimeCues(w http.ResponseWriter, r *http.Request) {
+	var req retimeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInvalidRequest))
+		return
+	}
+
+	if req.Offset == 0 && len(req.Anchors) == 0 {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Nothing to change", "give an offset, two anchors, or both"))
+		return
+	}
+
+	if !req.Preview {
+		message := fmt.Sprintf("Shifted timings by %+.3fs", req.Offset)
+		if len(req.Anchors) > 0 {
+			message = "Resynced timings"
+		}
+		h.saveEdit(w, r, req.editRequest, message, func(doc *utils.SubtitleDocument) error {
+			_, err := utils.RetimeCues(doc, req.TimingAdjustment)
+			return err
+		})
+		return
+	}
+
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	session, ok := h.openSession(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := utils.RetimeCues(session.Document, req.TimingAdjustment)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid timing adjustment", err.Error()))
+		return
+	}
+	if changes == nil {
+		changes = []utils.CueTimingChange{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"version": session.Version,
+		"changes": changes,
+		"count":   len(changes),
+	})
+}
+
+// GetVersions handles GET /videos/{id}/tracks/{track}/versions
+func (h *SubtitleEditorHandler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	video, track, ok := h.getTrack(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.editor.Versions(ctx, video.ID, track.ID)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+	if versions == nil {
+		versions = []*models.SubtitleVersion{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  versions,
+		"count": len(versions),
+	})
+}
+
+// GetVersion handles GET /videos/{id}/tracks/{track}/versions/{version}
+func (h *SubtitleEditorHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	video, track, ok := h.getTrack(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	number, ok := versionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := h.getVersion(ctx, w, video.ID, track.ID, number)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version)
+}
+
+// DiffVersions handles GET /videos/{id}/tracks/{track}/versions/{version}/diff?against={version}.
+// Without against the version is compared with the one before it.
+func (h *SubtitleEditorHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	video, track, ok := h.getTrack(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	number, ok := versionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	against := number - 1
+	if againstStr := r.URL.Query().Get("against"); againstStr != "" {
+		parsed, err := strconv.Atoi(againstStr)
+		if err != nil || parsed < 0 {
+			errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid against parameter", "against must be a version number"))
+			return
+		}
+		against = parsed
+	}
+	if against < 0 {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Nothing to compare against", "version 0 is the original upload; pass against to compare it with a later version"))
+		return
+	}
+
+	to, ok := h.getVersion(ctx, w, video.ID, track.ID, number)
+	if !ok {
+		return
+	}
+	from, ok := h.getVersion(ctx, w, video.ID, track.ID, against)
+	if !ok {
+		return
+	}
+
+	fromDoc, err := utils.ParseWebVTT(from.Content)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+	toDoc, err := utils.ParseWebVTT(to.Content)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+
+	changes := utils.DiffCues(fromDoc.Cues, toDoc.Cues)
+	summary := map[string]int{utils.CueAdded: 0, utils.CueRemoved: 0, utils.CueModified: 0}
+	for _, change := range changes {
+		summary[change.Op]++
+	}
+	if changes == nil {
+		changes = []utils.CueChange{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":    against,
+		"to":      number,
+		"changes": changes,
+		"summary": summary,
+	})
+}
+
+// RollbackVersion handles POST /videos/{id}/tracks/{track}/versions/{version}/rollback
+func (h *SubtitleEditorHandler) RollbackVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	number, ok := versionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req editRequest
+	if !decodeOptionalBody(w, r, &req) {
+		return
+	}
+
+	session, ok := h.openSession(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	version, err := h.editor.Rollback(ctx, session, number, getUserIDFromContext(r.Context()), req.Message, req.BaseVersion)
+	if err != nil {
+		writeEditorError(w, err)
+		return
+	}
+
+	writeSavedVersion(w, version, session.Document)
+}
+
+// saveEdit opens the requested track, applies an edit to its cues and saves the result
+func (h *SubtitleEditorHandler) saveEdit(w http.ResponseWriter, r *http.Request, req editRequest, defaultMessage string, apply func(doc *utils.SubtitleDocument) error) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	session, ok := h.openSession(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	doc := session.Document
+	if err := apply(doc); err != nil {
+		if err == utils.ErrCueNotFound {
+			errors.WriteErrorResponse(w, errors.ErrCueNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid cue edit", err.Error()))
+		return
+	}
+
+	message := req.Message
+	if message == "" {
+		message = defaultMessage
+	}
+
+	version, err := h.editor.Save(ctx, session, doc, getUserIDFromContext(r.Context()), message, req.BaseVersion)
+	if err != nil {
+		writeEditorError(w, err)
+		return
+	}
+
+	writeSavedVersion(w, version, doc)
+}
+
+// openSession loads the track named in the request for editing
+func (h *SubtitleEditorHandler) openSession(ctx context.Context, w http.ResponseWriter, r *http.Request) (*services.SubtitleEditSession, bool) {
+	video, track, ok := h.getTrack(ctx, w, r)
+	if !ok {
+		return nil, false
+	}
+
+	session, err := h.editor.Open(ctx, video, track)
+	if err != nil {
+		writeEditorError(w, err)
+		return nil, false
+	}
+	return session, true
+}
+
+// getTrack looks up the video and subtitle track named in the request
+func (h *SubtitleEditorHandler) getTrack(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.Video, *models.SubtitleTrack, bool) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Validate video ID
+	if ve := validation.ValidateVideoID(id); ve.HasErrors() {
+		errors.WriteValidationError(w, ve)
+		return nil, nil, false
+	}
+
+	video, err := h.videoRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
+			return nil, nil, false
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return nil, nil, false
+	}
+
+	track := selectTrack(video, vars["track"])
+	if track == nil {
+		errors.WriteErrorResponse(w, errors.ErrSubtitleTrackNotFound)
+		return nil, nil, false
+	}
+
+	return video, track, true
+}
+
+// getVersion retrieves a stored version, writing an error response if it doesn't exist
+func (h *SubtitleEditorHandler) getVersion(ctx context.Context, w http.ResponseWriter, videoID, trackID string, number int) (*models.SubtitleVersion, bool) {
+	version, err := h.editor.Version(ctx, videoID, trackID, number)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrSubtitleVersionNotFound)
+			return nil, false
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return nil, false
+	}
+	return version, true
+}
+
+// cueIndexFromRequest parses the 1-based cue index from the URL
+func cueIndexFromRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	index, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil || index < 1 {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid cue index", "cue index must be a positive number"))
+		return 0, false
+	}
+	return index, true
+}
+
+// versionFromRequest parses the version number from the URL
+func versionFromRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil || version < 0 {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid version", "version must be a non-negative number"))
+		return 0, false
+	}
+	return version, true
+}
+
+// decodeOptionalBody decodes a JSON body that may be empty
+func decodeOptionalBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInvalidRequest))
+		return false
+	}
+	return true
+}
+
+// writeEditorError maps subtitle editor errors to API errors
+func writeEditorError(w http.ResponseWriter, err error) {
+	switch err {
+	case services.ErrVersionConflict:
+		errors.WriteErrorResponse(w, errors.ErrVersionConflict)
+	case services.ErrTrackNotEditable:
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Subtitle track cannot be edited", err.Error()))
+	case services.ErrSubtitleFileNotFound:
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrSubtitleTrackNotFound))
+	case mongo.ErrNoDocuments:
+		errors.WriteErrorResponse(w, errors.ErrSubtitleVersionNotFound)
+	default:
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+	}
+}
+
+// writeSavedVersion writes the stored version, without its content, and the track's cues
+func writeSavedVersion(w http.ResponseWriter, version *models.SubtitleVersion, doc *utils.SubtitleDocument) {
+	saved := *version
+	saved.Content = ""
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Subtitle track saved successfully",
+		"version": saved,
+		"data":    doc.Cues,
+		"count":   len(doc.Cues),
+	})
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/errors"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/services"
+	"video-player-backend/internal/utils"
+	"video-player-backend/internal/validation"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TranscriptHandler handles structured access to video transcripts
+type TranscriptHandler struct {
+	videoRepo      database.VideoRepository
+	vocabIndexRepo database.VocabularyIndexRepository
+	pairRepo       database.SentencePairRepository
+	indexService   *services.VocabularyIndexService
+}
+
+// NewTranscriptHandler creates a new transcript handler
+func NewTranscriptHandler(
+	videoRepo database.VideoRepository,
+	vocabIndexRepo database.VocabularyIndexRepository,
+	pairRepo database.SentencePairRepository,
+	indexService *services.VocabularyIndexService,
+) *TranscriptHandler {
+	return &TranscriptHandler{
+		videoRepo:      videoRepo,
+		vocabIndexRepo: vocabIndexRepo,
+		pairRepo:       pairRepo,
+		indexService:   indexService,
+	}
+}
+
+// exportContentTypes maps each transcript export format to its content type
+var exportContentTypes = map[string]string{
+	utils.ExportFormatSRT:      "application/x-subrip; charset=utf-8",
+	utils.ExportFormatVTT:      "text/vtt; charset=utf-8",
+	utils.ExportFormatText:     "text/plain; charset=utf-8",
+	utils.ExportFormatMarkdown: "text/markdown; charset=utf-8",
+	utils.ExportFormatHTML:     "text/html; charset=utf-8",
+}
+
+// GetTranscript handles GET /videos/{id}/transcript?track={trackID}
+func (h *TranscriptHandler) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	_, transcript, _, ok := h.loadTranscript(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	pairs, err := h.pairRepo.GetByVideoID(ctx, transcript.VideoID)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+	addTranslations(transcript, pairs)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transcript)
+}
+
+// ExportTranscript handles GET /videos/{id}/transcript/export?format={srt|vtt|txt|md|html}&track={trackID}.
+// The Markdown and HTML formats are meant for printing and end with a glossary of the video's vocabulary.
+func (h *TranscriptHandler) ExportTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = utils.ExportFormatText
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid export format", "format must be one of srt, vtt, txt, md or html"))
+		return
+	}
+
+	video, transcript, doc, ok := h.loadTranscript(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	export := &utils.TranscriptExport{
+		Title: video.Title,
+		Track: transcript.Track,
+		Cues:  transcript.Cues,
+	}
+
+	var body string
+	switch format {
+	case utils.ExportFormatSRT:
+		body = utils.WriteSRT(doc)
+	case utils.ExportFormatVTT:
+		body = utils.WriteWebVTT(doc)
+	case utils.ExportFormatText:
+		body = utils.WriteTranscriptText(export)
+	default:
+		indexes, err := h.vocabIndexRepo.GetByVideoID(ctx, video.ID)
+		if err != nil {
+			errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+			return
+		}
+		export.Glossary = buildGlossary(indexes)
+
+		if format == utils.ExportFormatHTML {
+			body = utils.WriteTranscriptHTML(export)
+		} else {
+			body = utils.WriteTranscriptMarkdown(export)
+		}
+	}
+
+	// HTML opens in the browser so it can be printed, the other formats are downloaded
+	disposition := "attachment"
+	if format == utils.ExportFormatHTML {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%s-%s.%s", disposition, video.ID, transcript.Track.ID, format))
+	w.Write([]byte(body))
+}
+
+// loadTranscript reads and parses the track requested by the ?track parameter and builds its
+// transcript with vocabulary spans. It writes the error response and returns false on failure.
+func (h *TranscriptHandler) loadTranscript(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.Video, *models.Transcript, *utils.SubtitleDocument, bool) {
+	id := mux.Vars(r)["id"]
+
+	// Validate video ID
+	if ve := validation.ValidateVideoID(id); ve.HasErrors() {
+		errors.WriteValidationError(w, ve)
+		return nil, nil, nil, false
+	}
+
+	video, err := h.videoRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
+			return nil, nil, nil, false
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return nil, nil, nil, false
+	}
+
+	track := selectTrack(video, r.URL.Query().Get("track"))
+	if track == nil {
+		errors.WriteErrorResponse(w, errors.ErrSubtitleTrackNotFound)
+		return nil, nil, nil, false
+	}
+
+	content, err := h.indexService.ReadSubtitle(ctx, track.URL)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrSubtitleTrackNotFound))
+		return nil, nil, nil, false
+	}
+
+	doc, err := utils.ParseWebVTT(content)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+		return nil, nil, nil, false
+	}
+
+	// Vocabulary is only highlighted in Māori tracks
+	var indexer *utils.VocabularyIndexer
+	if track.IsMaori() {
+		indexer, err = h.indexService.NewIndexer(ctx)
+		if err != nil {
+			errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+			return nil, nil, nil, false
+		}
+	}
+
+	transcript := &models.Transcript{
+		VideoID: video.ID,
+		Track:   *track,
+		Cues:    make([]models.TranscriptCue, 0, len(doc.Cues)),
+	}
+	for _, cue := range doc.Cues {
+		transcript.Cues = append(transcript.Cues, buildTranscriptCue(cue, indexer))
+	}
+
+	return video, transcript, doc, true
+}
+
+// AlignTranscript handles POST /videos/{id}/align. It realigns the video's Māori and English
+// tracks and returns the new sentence pairs.
+func (h *TranscriptHandler) AlignTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+
+	// Validate video ID
+	if ve := validation.ValidateVideoID(id); ve.HasErrors() {
+		errors.WriteValidationError(w, ve)
+		return
+	}
+
+	video, err := h.videoRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	if video.MaoriTrack() == nil || video.EnglishTrack() == nil {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Nothing to align", "the video needs both a Māori and an English subtitle track"))
+		return
+	}
+
+	if _, err := h.indexService.AlignVideo(ctx, video); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+
+	pairs, err := h.pairRepo.GetByVideoID(ctx, video.ID)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+	if pairs == nil {
+		pairs = []*models.SentencePair{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Transcript aligned successfully",
+		"data":    pairs,
+		"count":   len(pairs),
+	})
+}
+
+// addTranslations attaches the aligned sentence in the other language to each cue of a
+// transcript of the Māori or English track the pairs were built from
+func addTranslations(transcript *models.Transcript, pairs []*models.SentencePair) {
+	if len(pairs) == 0 {
+		return
+	}
+
+	maori := pairs[0].MaoriTrackID == transcript.Track.ID
+	english := pairs[0].EnglishTrackID == transcript.Track.ID
+	if !maori && !english {
+		return
+	}
+
+	translations := make(map[int]*models.SentencePair)
+	for _, pair := range pairs {
+		cues := pair.MaoriCues
+		if english {
+			cues = pair.EnglishCues
+		}
+		for _, index := range cues {
+			translations[index] = pair
+		}
+	}
+
+	for i := range transcript.Cues {
+		pair, ok := translations[transcript.Cues[i].Index]
+		if !ok {
+			continue
+		}
+		transcript.Cues[i].Pair = pair.Index
+		if maori {
+			transcript.Cues[i].Translation = pair.English
+		} else {
+			transcript.Cues[i].Translation = pair.Maori
+		}
+	}
+	transcript.SentencePairs = pairs
+}
+
+// buildGlossary lists each vocabulary item in a video's index once, in alphabetical order
+func buildGlossary(indexes []*models.VocabularyIndex) []models.GlossaryEntry {
+	seen := make(map[string]bool)
+	glossary := []models.GlossaryEntry{}
+	for _, index := range indexes {
+		key := index.VocabularyID
+		if key == "" {
+			key = strings.ToLower(index.Vocabulary)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		glossary = append(glossary, models.GlossaryEntry{
+			Maori:       index.Vocabulary,
+			English:     index.English,
+			Description: index.Description,
+		})
+	}
+
+	sort.SliceStable(glossary, func(i, j int) bool {
+		return strings.ToLower(glossary[i].Maori) < strings.ToLower(glossary[j].Maori)
+	})
+	return glossary
+}
+
+// selectTrack returns the track with the given ID, or the video's Māori track (falling back to
+// the default track) when no ID is given
+func selectTrack(video *models.Video, trackID string) *models.SubtitleTrack {
+	tracks := video.SubtitleTracks()
+
+	if trackID != "" {
+		for i := range tracks {
+			if tracks[i].ID == trackID {
+				return &tracks[i]
+			}
+		}
+		return nil
+	}
+
+	if track := video.MaoriTrack(); track != nil {
+		return track
+	}
+	for i := range tracks {
+		if tracks[i].Default {
+			return &tracks[i]
+		}
+	}
+	return nil
+}
+
+// buildTranscriptCue converts a parsed cue into a transcript cue with vocabulary spans
+func buildTranscriptCue(cue utils.Cue, indexer *utils.VocabularyIndexer) models.TranscriptCue {
+	result := models.TranscriptCue{
+		ID:        cue.ID,
+		Index:     cue.Index,
+		StartTime: cue.StartTime,
+		EndTime:   cue.EndTime,
+		Text:      cue.Text,
+		Speaker:   cue.Speaker,
+		Spans:     []models.VocabularySpan{},
+	}
+	if result.ID == "" {
+		result.ID = strconv.Itoa(cue.Index)
+	}
+
+	if indexer == nil {
+		return result
+	}
+
+	runes := []rune(cue.Text)
+	for _, match := range indexer.FindMatches(cue.Text) {
+		result.Spans = append(result.Spans, models.VocabularySpan{
+			Start:        match.Start,
+			End:          match.End,
+			Text:         string(runes[match.Start:match.End]),
+			VocabularyID: match.Vocabulary.ID,
+			Vocabulary:   match.Vocabulary.Maori,
+			English:      match.Vocabulary.English,
+			MatchType:    match.Type,
+		})
+	}
+
+	return result
+}
@@ -3,11 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
 	"video-player-backend/internal/database"
 	"video-player-backend/internal/errors"
 	"video-player-backend/internal/models"
+	"video-player-backend/internal/services"
+	"video-player-backend/internal/sources"
 	"video-player-backend/internal/utils"
 	"video-player-backend/internal/validation"
 
@@ -17,13 +20,23 @@ import (
 
 // VideoHandler handles video-related HTTP requests
 type VideoHandler struct {
-	repo database.VideoRepository
+	repo         database.VideoRepository
+	sources      *sources.Registry
+	trash        *services.VideoTrashService
+	indexService *services.VocabularyIndexService
+	related      *services.RelatedVideoService
+	jobs         *services.JobRunner
 }
 
 // NewVideoHandler creates a new video handler
-func NewVideoHandler(repo database.VideoRepository) *VideoHandler {
+func NewVideoHandler(repo database.VideoRepository, sourceRegistry *sources.Registry, trash *services.VideoTrashService, indexService *services.VocabularyIndexService, related *services.RelatedVideoService, jobs *services.JobRunner) *VideoHandler {
 	return &VideoHandler{
-		repo: repo,
+		repo:         repo,
+		sources:      sourceRegistry,
+		trash:        trash,
+		indexService: indexService,
+		related:      related,
+		jobs:         jobs,
 	}
 }
 
@@ -42,6 +55,11 @@ func (h *VideoHandler) GetVideos(w http.ResponseWriter, r *http.Request) {
 		videos = []*models.Video{}
 	}
 
+	// List every subtitle track, including legacy single subtitles, so the player can switch between them
+	for _, video := range videos {
+		video.Tracks = video.SubtitleTracks()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(videos)
 }
@@ -70,10 +88,70 @@ func (h *VideoHandler) GetVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	video.Tracks = video.SubtitleTracks()
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(video)
 }
 
+// GetRelatedVideos handles GET /videos/{id}/related?limit={limit}
+func (h *VideoHandler) GetRelatedVideos(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	params := mux.Vars(r)
+	id := params["id"]
+
+	// Validate video ID
+	if ve := validation.ValidateVideoID(id); ve.HasErrors() {
+		errors.WriteValidationError(w, ve)
+		return
+	}
+
+	limit := 10
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 50 {
+			errors.WriteErrorResponse(w, errors.NewAPIError(
+				errors.ErrInvalidRequest.Code,
+				"Limit must be between 1 and 50",
+			))
+			return
+		}
+		limit = parsed
+	}
+
+	video, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	// Signed-in users don't get videos they have already completed; anonymous users get everything
+	userID, _ := getUserIDFromJWT(r)
+
+	related, err := h.related.Related(ctx, video, userID, limit)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	if related == nil {
+		related = []*models.RelatedVideo{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"video_id": video.ID,
+		"data":     related,
+		"count":    len(related),
+	})
+}
+
 // CreateVideo handles POST /videos
 func (h *VideoHandler) CreateVideo(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := utils.ContextWithTimeout()
@@ -97,10 +175,12 @@ func (h *VideoHandler) CreateVideo(w http.ResponseWriter, r *http.Request) {
 
 	video := videoReq.ToVideo()
 	video.GenerateID()
+	video.NormalizeTracks()
 
-	// Replace the last occurrence of dl=0 with raw=1 in the video URL
-	if video.Video != "" {
-		video.Video = replaceLastOccurrence(video.Video, "dl=0", "raw=1")
+	// Resolve the link through its source provider into a playable URL
+	if err := h.resolveSource(video, video.Video); err != nil {
+		writeVideoSourceError(w, err)
+		return
 	}
 
 	if err := h.repo.Create(ctx, video); err != nil {
@@ -145,10 +225,12 @@ func (h *VideoHandler) UpdateVideo(w http.ResponseWriter, r *http.Request) {
 
 	video := videoReq.ToVideo()
 	video.ID = id
+	video.NormalizeTracks()
 
-	// Replace the last occurrence of dl=0 with raw=1 in the video URL
-	if video.Video != "" {
-		video.Video = replaceLastOccurrence(video.Video, "dl=0", "raw=1")
+	// Resolve the link through its source provider into a playable URL
+	if err := h.resolveSource(video, video.Video); err != nil {
+		writeVideoSourceError(w, err)
+		return
 	}
 
 	if err := h.repo.Update(ctx, id, video); err != nil {
@@ -164,7 +246,7 @@ func (h *VideoHandler) UpdateVideo(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(video)
 }
 
-// DeleteVideo handles DELETE /videos/{id}
+// DeleteVideo handles DELETE /videos/{id} by moving the video to the trash
 func (h *VideoHandler) DeleteVideo(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := utils.ContextWithTimeout()
 	defer cancel()
@@ -178,7 +260,7 @@ func (h *VideoHandler) DeleteVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.repo.Delete(ctx, id); err != nil {
+	if err := h.repo.SoftDelete(ctx, id); err != nil {
 		if err == mongo.ErrNoDocuments {
 			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
 			return
@@ -190,11 +272,168 @@ func (h *VideoHandler) DeleteVideo(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// replaceLastOccurrence replaces the last occurrence of a substring in a string
-func replaceLastOccurrence(s, old, new string) string {
-	lastIndex := strings.LastIndex(s, old)
-	if lastIndex == -1 {
-		return s
+// GetTrash handles GET /videos/trash
+func (h *VideoHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	videos, err := h.repo.GetTrashed(ctx)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	type trashedVideo struct {
+		*models.Video
+		PurgeAt time.Time `json:"purge_at"`
 	}
-	return s[:lastIndex] + new + s[lastIndex+len(old):]
+
+	items := make([]trashedVideo, 0, len(videos))
+	for _, video := range videos {
+		items = append(items, trashedVideo{
+			Video:   video,
+			PurgeAt: video.DeletedAt.Add(h.trash.Retention()),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":           items,
+		"count":          len(items),
+		"retention_days": int(h.trash.Retention().Hours() / 24),
+	})
+}
+
+// RestoreVideo handles POST /videos/{id}/restore
+func (h *VideoHandler) RestoreVideo(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	params := mux.Vars(r)
+	id := params["id"]
+
+	// Validate video ID
+	if ve := validation.ValidateVideoID(id); ve.HasErrors() {
+		errors.WriteValidationError(w, ve)
+		return
+	}
+
+	if err := h.repo.Restore(ctx, id); err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	video, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(video)
+}
+
+// PurgeVideo handles DELETE /videos/{id}/purge, permanently deleting a trashed video
+func (h *VideoHandler) PurgeVideo(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	params := mux.Vars(r)
+	id := params["id"]
+
+	// Validate video ID
+	if ve := validation.ValidateVideoID(id); ve.HasErrors() {
+		errors.WriteValidationError(w, ve)
+		return
+	}
+
+	// Only videos already in the trash can be purged
+	video, err := h.repo.GetTrashedByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	if err := h.trash.Purge(ctx, video); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResolveVideoSource handles POST /videos/{id}/resolve
+func (h *VideoHandler) ResolveVideoSource(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	params := mux.Vars(r)
+	id := params["id"]
+
+	// Validate video ID
+	if ve := validation.ValidateVideoID(id); ve.HasErrors() {
+		errors.WriteValidationError(w, ve)
+		return
+	}
+
+	video, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	// Videos created before providers were stored only have the resolved URL
+	link := video.SourceURL
+	if link == "" {
+		link = video.Video
+	}
+
+	if err := h.resolveSource(video, link); err != nil {
+		writeVideoSourceError(w, err)
+		return
+	}
+
+	if err := h.repo.Update(ctx, id, video); err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(video)
+}
+
+// resolveSource resolves a link with the video's provider and updates the video's URLs
+func (h *VideoHandler) resolveSource(video *models.Video, link string) error {
+	resolved, err := h.sources.Resolve(video.Source, link)
+	if err != nil {
+		return err
+	}
+
+	video.Source = resolved.Provider
+	video.SourceURL = resolved.SourceURL
+	video.Video = resolved.VideoURL
+	if video.Thumbnail == "" {
+		video.Thumbnail = resolved.Thumbnail
+	}
+
+	return nil
+}
+
+// writeVideoSourceError writes a validation error for a link that could not be resolved
+func writeVideoSourceError(w http.ResponseWriter, err error) {
+	ve := &errors.ValidationErrors{}
+	ve.Add("video", err.Error())
+	errors.WriteValidationError(w, ve)
 }
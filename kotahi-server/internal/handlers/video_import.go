@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"video-player-backend/internal/errors"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/services"
+	"video-player-backend/internal/utils"
+	"video-player-backend/internal/validation"
+)
+
+const (
+	// maxImportUploadSize is the largest manifest or ZIP bundle accepted by the import endpoint
+	maxImportUploadSize = int64(100 << 20) // 100MB
+	// maxImportSubtitleSize is the largest subtitle file accepted inside a ZIP bundle
+	maxImportSubtitleSize = int64(10 << 20) // 10MB
+	// importTimeout bounds the whole import, which stores subtitles and creates many videos
+	importTimeout = 5 * time.Minute
+)
+
+// importRowError lists the validation errors for one manifest row
+type importRowError struct {
+	Row    int                      `json:"row"`
+	Title  string                   `json:"title,omitempty"`
+	Errors []errors.ValidationError `json:"errors"`
+}
+
+// importBundle is a parsed manifest together with any subtitle files from its ZIP archive
+type importBundle struct {
+	rows      []utils.VideoManifestRow
+	subtitles map[string][]byte // Subtitle file content keyed by file name
+}
+
+// importItem is a validated manifest row ready to be created
+type importItem struct {
+	row      utils.VideoManifestRow
+	video    *models.Video
+	subtitle string // Name of a subtitle file in the ZIP archive, empty if the subtitle is already uploaded
+	vtt      []byte // Subtitle from the archive converted to VTT
+}
+
+// ImportVideos handles POST /videos/import?dry_run={true|false}
+func (h *VideoHandler) ImportVideos(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), importTimeout)
+	defer cancel()
+
+	// Check if request is multipart/form-data
+	if !strings.Contains(r.Header.Get("Content-Type"), "multipart/form-data") {
+		errors.WriteErrorResponse(w, errors.ErrInvalidRequest)
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			errors.WriteErrorResponse(w, errors.NewAPIError(
+				errors.ErrInvalidRequest.Code,
+				"dry_run must be true or false",
+			))
+			return
+		}
+		dryRun = parsed
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUploadSize+(1<<20))
+
+	// Parse multipart form with 32MB max memory
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+			errors.ErrInvalidRequest.Code,
+			"Failed to parse multipart form",
+			err.Error(),
+		))
+		return
+	}
+
+	file, header, err := r.FormFile("manifest")
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+			errors.ErrInvalidRequest.Code,
+			"Manifest file is required",
+			"Please upload a CSV, JSON or ZIP file with the 'manifest' field name",
+		))
+		return
+	}
+	defer file.Close()
+
+	bundle, err := readImportBundle(header.Filename, file)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+			errors.ErrInvalidRequest.Code,
+			"Manifest parsing failed",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate every row before anything is created
+	items, rowErrors := h.validateImportRows(ctx, bundle)
+
+	if dryRun {
+		previews := make([]*models.Video, 0, len(items))
+		for _, item := range items {
+			previews = append(previews, item.video)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"dry_run": true,
+			"total":   len(bundle.rows),
+			"valid":   len(items),
+			"invalid": len(rowErrors),
+			"errors":  rowErrors,
+			"videos":  previews,
+		})
+		return
+	}
+
+	if len(rowErrors) > 0 {
+		writeImportErrors(w, len(bundle.rows), rowErrors)
+		return
+	}
+
+	// Store a copy of the subtitle for each row, even if several rows share a file from the archive.
+	// The subtitle editor rewrites a track's file, which must not change the other videos' subtitles.
+	var storedSubtitles []string
+	for _, item := range items {
+		if item.subtitle == "" {
+			continue
+		}
+		vttName := fmt.Sprintf("%s_row%d.vtt", strings.TrimSuffix(item.subtitle, filepath.Ext(item.subtitle)), item.row.Row)
+		url, err := h.indexService.SaveSubtitle(ctx, generateUniqueFilename(vttName), item.vtt)
+		if err != nil {
+			log.Printf("Failed to store subtitle %s: %v", item.subtitle, err)
+			h.rollbackImport(nil, storedSubtitles)
+			errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+				errors.ErrInternalServer.Code,
+				errors.ErrInternalServer.Message,
+				fmt.Sprintf("Subtitle %s could not be stored, no videos were imported", item.subtitle),
+			))
+			return
+		}
+		storedSubtitles = append(storedSubtitles, url)
+		item.video.Subtitle = url
+	}
+
+	created := make([]*models.Video, 0, len(items))
+	for _, item := range items {
+		item.video.GenerateID()
+		if err := h.repo.Create(ctx, item.video); err != nil {
+			log.Printf("Video import stopped at row %d after creating %d videos, rolling back: %v", item.row.Row, len(created), err)
+			h.rollbackImport(created, storedSubtitles)
+			errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+				errors.ErrDatabase.Code,
+				errors.ErrDatabase.Message,
+				fmt.Sprintf("Row %d could not be created, no videos were imported", item.row.Row),
+			))
+			return
+		}
+		created = append(created, item.video)
+	}
+
+	response := map[string]interface{}{
+		"message": fmt.Sprintf("Successfully imported %d videos", len(created)),
+		"created": len(created),
+		"videos":  created,
+	}
+
+	// The new videos are indexed by a background job, a failure to queue it leaves the videos in place for a later reindex
+	if len(created) > 0 {
+		ids := make([]string, len(created))
+		for i, video := range created {
+			ids[i] = video.ID
+		}
+		job := services.NewIndexVideosJob(ids, getUserIDFromContext(r.Context()))
+		if err := h.jobs.Enqueue(ctx, job); err != nil {
+			log.Printf("Warning: Failed to queue indexing of imported videos: %v", err)
+		} else {
+			response["index_job"] = job
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// rollbackImport deletes the videos and subtitle files an import created before it failed, so
+// a failed import leaves nothing behind. It has its own timeout because the import's may be spent.
+func (h *VideoHandler) rollbackImport(created []*models.Video, storedSubtitles []string) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	for _, video := range created {
+		if err := h.repo.Delete(ctx, video.ID); err != nil {
+			log.Printf("Failed to remove imported video %s during rollback: %v", video.ID, err)
+		}
+	}
+	for _, url := range storedSubtitles {
+		if err := h.indexService.RemoveUnusedSubtitle(ctx, url); err != nil {
+			log.Printf("Failed to remove imported subtitle %s during rollback: %v", url, err)
+		}
+	}
+}
+
+// validateImportRows validates each manifest row and resolves its video link and subtitle
+func (h *VideoHandler) validateImportRows(ctx context.Context, bundle *importBundle) ([]*importItem, []importRowError) {
+	var items []*importItem
+	rowErrors := []importRowError{}
+
+	for _, row := range bundle.rows {
+		req := row.Request
+		ve := &errors.ValidationErrors{}
+		item := &importItem{row: row}
+
+		// Subtitles from the archive are checked here and linked once they are stored
+		subtitle := strings.TrimSpace(req.Subtitle)
+		if content, ok := bundle.subtitles[filepath.Base(subtitle)]; ok && subtitle != "" {
+			item.subtitle = filepath.Base(subtitle)
+			vtt, _, _, err := utils.ConvertToWebVTT(item.subtitle, content)
+			if err != nil {
+				ve.Add("subtitle", fmt.Sprintf("Subtitle could not be parsed: %v", err))
+			}
+			item.vtt = []byte(vtt)
+		} else if subtitle != "" && !strings.HasPrefix(subtitle, "http://") && !strings.HasPrefix(subtitle, "https://") {
+			content, err := h.indexService.ReadSubtitle(ctx, subtitle)
+			if err != nil {
+				ve.Add("subtitle", fmt.Sprintf("Subtitle file '%s' was not found in the archive or uploaded VTT files", subtitle))
+			} else if err := checkImportSubtitle([]byte(content)); err != nil {
+				ve.Add("subtitle", err.Error())
+			} else if !strings.Contains(subtitle, "/") {
+				req.Subtitle = services.SubtitleURL(subtitle)
+			}
+		}
+
+		if rowVE := validation.ValidateVideoRequest(&req); rowVE.HasErrors() {
+			ve.Errors = append(ve.Errors, rowVE.Errors...)
+		}
+
+		if !ve.HasErrors() {
+			item.video = req.ToVideo()
+			item.video.NormalizeTracks()
+			if err := h.resolveSource(item.video, item.video.Video); err != nil {
+				ve.Add("video", err.Error())
+			}
+		}
+
+		if ve.HasErrors() {
+			rowErrors = append(rowErrors, importRowError{
+				Row:    row.Row,
+				Title:  req.Title,
+				Errors: ve.Errors,
+			})
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rowErrors
+}
+
+// readImportBundle reads a CSV or JSON manifest, or a ZIP archive containing a manifest and VTT files
+func readImportBundle(filename string, file io.Reader) (*importBundle, error) {
+	bundle := &importBundle{subtitles: make(map[string][]byte)}
+
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		bundle.rows, err = utils.ParseVideoManifestCSV(file)
+	case ".json":
+		bundle.rows, err = utils.ParseVideoManifestJSON(file)
+	case ".zip":
+		err = readImportArchive(file, bundle)
+	default:
+		return nil, fmt.Errorf("manifest must be a .csv, .json or .zip file")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// readImportArchive reads the manifest and subtitle files from a ZIP archive into the bundle
+func readImportArchive(file io.Reader, bundle *importBundle) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid ZIP archive: %w", err)
+	}
+
+	var manifest *zip.File
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		name := filepath.Base(entry.Name)
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".csv", ".json":
+			if manifest != nil {
+				return fmt.Errorf("archive contains more than one manifest (%s and %s)", manifest.Name, entry.Name)
+			}
+			manifest = entry
+		case ".vtt", ".srt", ".sbv", ".ttml", ".dfxp":
+			if entry.UncompressedSize64 > uint64(maxImportSubtitleSize) {
+				return fmt.Errorf("subtitle %s exceeds 10MB limit", name)
+			}
+			if _, exists := bundle.subtitles[name]; exists {
+				return fmt.Errorf("archive contains more than one subtitle named %s", name)
+			}
+			content, err := readZipEntry(entry)
+			if err != nil {
+				return err
+			}
+			bundle.subtitles[name] = content
+		}
+	}
+
+	if manifest == nil {
+		return fmt.Errorf("archive must contain a CSV or JSON manifest")
+	}
+
+	content, err := readZipEntry(manifest)
+	if err != nil {
+		return err
+	}
+
+	if strings.ToLower(filepath.Ext(manifest.Name)) == ".csv" {
+		bundle.rows, err = utils.ParseVideoManifestCSV(bytes.NewReader(content))
+	} else {
+		bundle.rows, err = utils.ParseVideoManifestJSON(bytes.NewReader(content))
+	}
+	return err
+}
+
+// readZipEntry reads the full content of a file in a ZIP archive
+func readZipEntry(entry *zip.File) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", entry.Name, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, maxImportUploadSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", entry.Name, err)
+	}
+	return content, nil
+}
+
+// checkImportSubtitle checks that a subtitle file contains at least one cue
+func checkImportSubtitle(content []byte) error {
+	lines, err := utils.ParseVTTToLines(string(content))
+	if err != nil {
+		return fmt.Errorf("Subtitle is not a valid VTT file: %v", err)
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("Subtitle file contains no cues")
+	}
+	return nil
+}
+
+// writeImportErrors writes the row-level errors of a rejected import
+func writeImportErrors(w http.ResponseWriter, total int, rowErrors []importRowError) {
+	apiErr := errors.NewAPIErrorWithDetails(
+		errors.ErrValidation.Code,
+		errors.ErrValidation.Message,
+		fmt.Sprintf("%d of %d rows failed validation, no videos were imported", len(rowErrors), total),
+	)
+
+	response := struct {
+		*errors.APIError
+		RowErrors []importRowError `json:"row_errors"`
+	}{
+		APIError:  apiErr,
+		RowErrors: rowErrors,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+}
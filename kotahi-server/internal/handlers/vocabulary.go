@@ -4,14 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"video-player-backend/internal/database"
 	"video-player-backend/internal/errors"
 	"video-player-backend/internal/models"
+	"video-player-backend/internal/services"
 	"video-player-backend/internal/utils"
 	"video-player-backend/internal/validation"
 
@@ -19,19 +20,29 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// vocabularyIndexTimeout bounds creating, editing or deleting a word, which reads every video to
+// find those that mention it
+const vocabularyIndexTimeout = time.Minute
+
 // VocabularyHandler handles vocabulary-related HTTP requests
 type VocabularyHandler struct {
 	repo           database.VocabularyRepository
 	vocabIndexRepo database.VocabularyIndexRepository
 	videoRepo      database.VideoRepository
+	indexService   *services.VocabularyIndexService
+	importService  *services.VocabularyImportService
+	versionService *services.VocabularyVersionService
 }
 
 // NewVocabularyHandler creates a new vocabulary handler
-func NewVocabularyHandler(repo database.VocabularyRepository, vocabIndexRepo database.VocabularyIndexRepository, videoRepo database.VideoRepository) *VocabularyHandler {
+func NewVocabularyHandler(repo database.VocabularyRepository, vocabIndexRepo database.VocabularyIndexRepository, videoRepo database.VideoRepository, indexService *services.VocabularyIndexService, versionService *services.VocabularyVersionService) *VocabularyHandler {
 	return &VocabularyHandler{
 		repo:           repo,
 		vocabIndexRepo: vocabIndexRepo,
 		videoRepo:      videoRepo,
+		indexService:   indexService,
+		importService:  services.NewVocabularyImportService(repo, versionService),
+		versionService: versionService,
 	}
 }
 
@@ -84,7 +95,8 @@ func (h *VocabularyHandler) GetVocabulary(w http.ResponseWriter, r *http.Request
 
 // CreateVocabulary handles POST /vocabulary
 func (h *VocabularyHandler) CreateVocabulary(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := utils.ContextWithTimeout()
+	// Looking for the new word in every video can take longer than the default timeout
+	ctx, cancel := context.WithTimeout(context.Background(), vocabularyIndexTimeout)
 	defer cancel()
 
 	var vocabReq models.VocabularyRequest
@@ -106,10 +118,12 @@ func (h *VocabularyHandler) CreateVocabulary(w http.ResponseWriter, r *http.Requ
 	vocabulary := vocabReq.ToVocabulary()
 	vocabulary.GenerateID()
 
-	if err := h.repo.Create(ctx, vocabulary); err != nil {
+	if err := h.versionService.CreateEntry(ctx, vocabulary); err != nil {
 		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
 		return
 	}
+	h.indexService.InvalidateIndexer()
+	h.indexVocabulary(ctx, vocabulary)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -118,7 +132,8 @@ func (h *VocabularyHandler) CreateVocabulary(w http.ResponseWriter, r *http.Requ
 
 // UpdateVocabulary handles PUT /vocabulary/{id}
 func (h *VocabularyHandler) UpdateVocabulary(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := utils.ContextWithTimeout()
+	// Looking for the edited word in every video can take longer than the default timeout
+	ctx, cancel := context.WithTimeout(context.Background(), vocabularyIndexTimeout)
 	defer cancel()
 
 	params := mux.Vars(r)
@@ -157,13 +172,16 @@ func (h *VocabularyHandler) UpdateVocabulary(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Update the vocabulary
+	// Update the vocabulary, keeping the old spellings to find where it was indexed
+	before := *existingVocabulary
 	existingVocabulary.UpdateFromRequest(&vocabReq)
 
-	if err := h.repo.Update(ctx, id, existingVocabulary); err != nil {
+	if err := h.versionService.UpdateEntry(ctx, id, existingVocabulary); err != nil {
 		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
 		return
 	}
+	h.indexService.InvalidateIndexer()
+	h.indexVocabulary(ctx, &before, existingVocabulary)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(existingVocabulary)
@@ -171,7 +189,8 @@ func (h *VocabularyHandler) UpdateVocabulary(w http.ResponseWriter, r *http.Requ
 
 // DeleteVocabulary handles DELETE /vocabulary/{id}
 func (h *VocabularyHandler) DeleteVocabulary(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := utils.ContextWithTimeout()
+	// Indexing the videos that mention the word again can take longer than the default timeout
+	ctx, cancel := context.WithTimeout(context.Background(), vocabularyIndexTimeout)
 	defer cancel()
 
 	params := mux.Vars(r)
@@ -183,7 +202,12 @@ func (h *VocabularyHandler) DeleteVocabulary(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := h.repo.Delete(ctx, id); err != nil {
+	// The deleted word's spellings are needed to find the videos it was indexed in
+	vocabulary, err := h.repo.GetByID(ctx, id)
+	if err == nil {
+		err = h.versionService.DeleteEntry(ctx, id)
+	}
+	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			errors.WriteErrorResponse(w, errors.ErrVocabularyNotFound)
 			return
@@ -191,10 +215,25 @@ func (h *VocabularyHandler) DeleteVocabulary(w http.ResponseWriter, r *http.Requ
 		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
 		return
 	}
+	h.indexService.InvalidateIndexer()
+	h.indexVocabulary(ctx, vocabulary)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// indexVocabulary updates the index for a created, edited or deleted word, given as it was before
+// and as it is after the change. The change is already saved, so a failure is only logged;
+// GET /vocabulary/index/check reports the entries it left out of date.
+func (h *VocabularyHandler) indexVocabulary(ctx context.Context, changed ...*models.Vocabulary) {
+	id := changed[0].ID
+	result, err := h.indexService.IndexVocabulary(ctx, changed...)
+	if err != nil {
+		log.Printf("Failed to index vocabulary %s: %v", id, err)
+		return
+	}
+	log.Printf("Reindexed %d videos mentioning vocabulary %s", result.ProcessedVideos, id)
+}
+
 // SearchVocabularies handles GET /vocabulary/search?q={query}
 func (h *VocabularyHandler) SearchVocabularies(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := utils.ContextWithTimeout()
@@ -239,6 +278,13 @@ func (h *VocabularyHandler) BatchVocabularyUpload(w http.ResponseWriter, r *http
 		return
 	}
 
+	mode, failOnDuplicates, err := vocabularyImportMode(r)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Invalid import mode", err.Error()))
+		return
+	}
+	dryRun := r.FormValue("dry_run") == "true"
+
 	// Get the CSV file from the form
 	file, header, err := r.FormFile("csv")
 	if err != nil {
@@ -290,130 +336,205 @@ func (h *VocabularyHandler) BatchVocabularyUpload(w http.ResponseWriter, r *http
 		return
 	}
 
-	// Delete all existing vocabularies before processing new ones
-	err = h.repo.DeleteAll(ctx)
+	diff, err := h.importService.Plan(ctx, vocabularies, mode)
 	if err != nil {
 		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
 		return
 	}
 
-	// Since we deleted all vocabularies, we can simply create all new ones
-	err = h.repo.CreateBatch(ctx, vocabularies)
-	if err != nil {
-		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+	// duplicates=error refuses files that mention any existing headword
+	if failOnDuplicates && (len(diff.Unchanged) > 0 || len(diff.Skipped) > 0) {
+		var existing []string
+		for _, vocab := range diff.Unchanged {
+			existing = append(existing, vocab.Maori)
+		}
+		for _, change := range diff.Skipped {
+			existing = append(existing, change.Before.Maori)
+		}
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+			errors.ErrVocabularyConflict.Code,
+			fmt.Sprintf("%d vocabulary items already exist", len(existing)),
+			strings.Join(existing, ", "),
+		))
 		return
 	}
 
-	// Reindex all videos with the new vocabulary
-	reindexResult, err := h.reindexAllVideos(ctx, vocabularies)
-	if err != nil {
-		// Log the error but don't fail the entire operation
-		fmt.Printf("Warning: Failed to reindex videos after vocabulary upload: %v\n", err)
+	response := map[string]interface{}{
+		"mode":      mode,
+		"dry_run":   dryRun,
+		"created":   len(diff.Added),
+		"updated":   len(diff.Changed),
+		"unchanged": len(diff.Unchanged),
+		"removed":   len(diff.Removed),
+		"skipped":   len(diff.Skipped),
+		"total":     len(vocabularies),
+		"diff":      diff,
+	}
+
+	if dryRun {
+		response["message"] = fmt.Sprintf("Importing would add %d, change %d and remove %d vocabulary items",
+			len(diff.Added), len(diff.Changed), len(diff.Removed))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
 	}
 
-	response := map[string]interface{}{
-		"message": fmt.Sprintf("Successfully uploaded %d vocabulary items", len(vocabularies)),
-		"created": len(vocabularies),
-		"total":   len(vocabularies),
-		"items":   vocabularies,
+	updatedItems := make([]*models.Vocabulary, 0, len(diff.Changed))
+	for _, change := range diff.Changed {
+		updatedItems = append(updatedItems, change.After)
 	}
+	response["created_items"] = diff.Added
+	response["updated_items"] = updatedItems
 
-	// Add reindexing results to response
-	if reindexResult != nil {
-		response["reindexing"] = reindexResult
+	version, err := h.importService.Import(ctx, diff, services.PublishOptions{
+		Source:           "import",
+		Filename:         header.Filename,
+		Note:             r.FormValue("note"),
+		PublishedBy:      getUserIDFromContext(r.Context()),
+		PublishedByEmail: userEmailFromContext(r.Context()),
+		Activate:         r.FormValue("activate") != "false",
+	})
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	if version == nil {
+		response["message"] = "Vocabulary is already up to date"
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// The new version's index is built in the background; it becomes active when it is ready
+	response["message"] = fmt.Sprintf("Imported %d vocabulary items as version %d: %d added, %d updated, %d removed",
+		len(vocabularies), version.Number, len(diff.Added), len(diff.Changed), len(diff.Removed))
+	response["version"] = version
+	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(response)
 }
 
-// reindexAllVideos reindexes all videos with the given vocabulary
-func (h *VocabularyHandler) reindexAllVideos(ctx context.Context, vocabularies []*models.Vocabulary) (map[string]interface{}, error) {
-	// Get all videos
-	videos, err := h.videoRepo.GetAll(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get videos: %w", err)
+// userEmailFromContext returns the email address the admin middleware stored in the context
+func userEmailFromContext(ctx context.Context) string {
+	if email, ok := ctx.Value("user_email").(string); ok {
+		return email
 	}
+	return ""
+}
 
-	// Clear existing indexes
-	err = h.vocabIndexRepo.DeleteAll(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to clear existing indexes: %w", err)
+// vocabularyImportMode reads the import mode from the mode field, or from the older duplicates
+// parameter (update, skip or error). Without either the file is merged into the vocabulary.
+func vocabularyImportMode(r *http.Request) (mode string, failOnDuplicates bool, err error) {
+	if mode = r.FormValue("mode"); mode != "" {
+		if !services.ValidVocabularyImportMode(mode) {
+			return "", false, fmt.Errorf("mode must be 'replace', 'merge' or 'add_only'")
+		}
+		return mode, false, nil
 	}
 
-	// Create vocabulary indexer
-	indexer := utils.NewVocabularyIndexer(vocabularies)
+	switch duplicates := r.FormValue("duplicates"); duplicates {
+	case "", "update":
+		return services.VocabularyImportMerge, false, nil
+	case "skip":
+		return services.VocabularyImportAddOnly, false, nil
+	case "error":
+		return services.VocabularyImportAddOnly, true, nil
+	default:
+		return "", false, fmt.Errorf("duplicates must be 'update', 'skip' or 'error'")
+	}
+}
 
-	totalIndexed := 0
-	processedVideos := 0
+// GetVersions handles GET /vocabulary/versions
+func (h *VocabularyHandler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
 
-	// Process each video
-	for _, video := range videos {
-		if video.Subtitle == "" {
-			continue // Skip videos without subtitles
-		}
+	versions, err := h.versionService.List(ctx)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
 
-		// Extract filename from subtitle URL/path
-		var vttFilename string
-		if strings.HasPrefix(video.Subtitle, "/api/v1/uploads/vtt/") {
-			// Extract filename from URL
-			vttFilename = strings.TrimPrefix(video.Subtitle, "/api/v1/uploads/vtt/")
-		} else if strings.Contains(video.Subtitle, "/") {
-			// Extract filename from path
-			vttFilename = filepath.Base(video.Subtitle)
-		} else {
-			// Assume it's already a filename
-			vttFilename = video.Subtitle
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  versions,
+		"count": len(versions),
+	})
+}
 
-		// Construct full path to VTT file
-		vttFilePath := filepath.Join("./uploads/vtt", vttFilename)
+// GetVersion handles GET /vocabulary/versions/{id}. Pass entries=true to include the version's entries.
+func (h *VocabularyHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
 
-		// Check if file exists
-		if _, err := os.Stat(vttFilePath); os.IsNotExist(err) {
-			fmt.Printf("VTT file not found: %s\n", vttFilePath)
-			continue // Skip videos with missing VTT files
+	version, err := h.versionService.Get(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVocabularyVersionNotFound)
+			return
 		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
 
-		// Read VTT file content
-		vttContent, err := os.ReadFile(vttFilePath)
+	response := map[string]interface{}{"data": version}
+	if r.URL.Query().Get("entries") == "true" {
+		entries, err := h.versionService.Entries(ctx, version)
 		if err != nil {
-			fmt.Printf("Error reading VTT file %s: %v\n", vttFilePath, err)
-			continue // Skip videos with unreadable VTT files
+			errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+			return
 		}
+		response["entries"] = entries
+	}
 
-		// Parse VTT content
-		transcriptLines, err := utils.ParseVTTToLines(string(vttContent))
-		if err != nil {
-			fmt.Printf("Error parsing VTT content for video %s: %v\n", video.ID, err)
-			continue // Skip videos with invalid VTT
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
 
-		// Index vocabulary for this video
-		indexes, err := indexer.IndexTranscript(video.ID, transcriptLines)
-		if err != nil {
-			fmt.Println("Error indexing vocabulary:", err)
-			continue // Skip videos that fail indexing
-		}
+// ActivateVersion handles POST /vocabulary/versions/{id}/activate
+func (h *VocabularyHandler) ActivateVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
 
-		// Save indexes to database
-		if len(indexes) > 0 {
-			err = h.vocabIndexRepo.CreateBatch(ctx, indexes)
-			if err != nil {
-				fmt.Println("Error saving indexes:", err)
-				continue // Skip videos that fail to save
-			}
-			totalIndexed += len(indexes)
-		}
+	version, err := h.versionService.Activate(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": fmt.Sprintf("Vocabulary version %d is now active", version.Number),
+		"data":    version,
+	})
+}
 
-		processedVideos++
+// RollbackVersion handles POST /vocabulary/versions/rollback, activating the previously active version
+func (h *VocabularyHandler) RollbackVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	version, err := h.versionService.Rollback(ctx)
+	if err != nil {
+		writeVersionError(w, err)
+		return
 	}
 
-	return map[string]interface{}{
-		"processed_videos": processedVideos,
-		"total_indexed":    totalIndexed,
-		"total_videos":     len(videos),
-		"total_vocabulary": len(vocabularies),
-	}, nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": fmt.Sprintf("Rolled back to vocabulary version %d", version.Number),
+		"data":    version,
+	})
+}
+
+// writeVersionError maps vocabulary version errors to API errors
+func writeVersionError(w http.ResponseWriter, err error) {
+	switch err {
+	case mongo.ErrNoDocuments:
+		errors.WriteErrorResponse(w, errors.ErrVocabularyVersionNotFound)
+	case services.ErrVocabularyVersionNotReady, services.ErrNoPreviousVocabulary:
+		errors.WriteErrorResponse(w, errors.NewAPIError(errors.ErrVocabularyConflict.Code, err.Error()))
+	default:
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+	}
 }
@@ -5,23 +5,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"video-player-backend/internal/database"
 	"video-player-backend/internal/errors"
 	"video-player-backend/internal/models"
+	"video-player-backend/internal/services"
 	"video-player-backend/internal/utils"
 )
 
+// reindexTimeout bounds an index check, which reads and indexes the subtitles of every video
+const reindexTimeout = 10 * time.Minute
+
 // VocabularySearchHandler handles vocabulary search operations
 type VocabularySearchHandler struct {
 	vocabRepo        database.VocabularyRepository
 	vocabIndexRepo   database.VocabularyIndexRepository
 	videoRepo        database.VideoRepository
 	watchHistoryRepo database.WatchHistoryRepository
+	indexService     *services.VocabularyIndexService
+	jobs             *services.JobRunner
 }
 
 // NewVocabularySearchHandler creates a new vocabulary search handler
@@ -30,12 +33,16 @@ func NewVocabularySearchHandler(
 	vocabIndexRepo database.VocabularyIndexRepository,
 	videoRepo database.VideoRepository,
 	watchHistoryRepo database.WatchHistoryRepository,
+	indexService *services.VocabularyIndexService,
+	jobs *services.JobRunner,
 ) *VocabularySearchHandler {
 	return &VocabularySearchHandler{
 		vocabRepo:        vocabRepo,
 		vocabIndexRepo:   vocabIndexRepo,
 		videoRepo:        videoRepo,
 		watchHistoryRepo: watchHistoryRepo,
+		indexService:     indexService,
+		jobs:             jobs,
 	}
 }
 
@@ -122,7 +129,7 @@ func (h *VocabularySearchHandler) SearchVocabulary(w http.ResponseWriter, r *htt
 	}
 
 	// Extract user ID from JWT token in Authorization header
-	userID, err := h.getUserIDFromJWT(r)
+	userID, err := getUserIDFromJWT(r)
 	var totalExposures, recentExposures int
 
 	if err != nil {
@@ -171,7 +178,7 @@ func (h *VocabularySearchHandler) SearchByEnglish(w http.ResponseWriter, r *http
 	defer cancel()
 
 	// Extract user ID from JWT token in Authorization header
-	userID, err := h.getUserIDFromJWT(r)
+	userID, err := getUserIDFromJWT(r)
 	if err != nil {
 		fmt.Printf("English vocabulary search request - could not extract user ID: %v\n", err)
 	} else {
@@ -236,7 +243,7 @@ func (h *VocabularySearchHandler) GetVideoVocabulary(w http.ResponseWriter, r *h
 	defer cancel()
 
 	// Extract user ID from JWT token in Authorization header
-	userID, err := h.getUserIDFromJWT(r)
+	userID, err := getUserIDFromJWT(r)
 	if err != nil {
 		fmt.Printf("Get video vocabulary request - could not extract user ID: %v\n", err)
 	} else {
@@ -285,7 +292,7 @@ func (h *VocabularySearchHandler) GetVocabularyStats(w http.ResponseWriter, r *h
 	defer cancel()
 
 	// Extract user ID from JWT token in Authorization header
-	userID, err := h.getUserIDFromJWT(r)
+	userID, err := getUserIDFromJWT(r)
 	if err != nil {
 		fmt.Printf("Get vocabulary stats request - could not extract user ID: %v\n", err)
 	} else {
@@ -309,123 +316,46 @@ func (h *VocabularySearchHandler) GetVocabularyStats(w http.ResponseWriter, r *h
 	json.NewEncoder(w).Encode(response)
 }
 
-// ReindexAllVideos handles POST /api/v1/vocabulary/reindex
+// ReindexAllVideos handles POST /api/v1/vocabulary/reindex. The reindex runs as a background
+// job; follow it with GET /api/v1/jobs/{id}.
 func (h *VocabularySearchHandler) ReindexAllVideos(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := utils.ContextWithTimeout()
 	defer cancel()
 
-	// Extract user ID from JWT token in Authorization header
-	userID, err := h.getUserIDFromJWT(r)
-	if err != nil {
-		fmt.Printf("Reindex all videos request - could not extract user ID: %v\n", err)
-	} else {
-		fmt.Printf("Reindex all videos request from user ID: %s\n", userID)
-	}
-
-	// Get all videos
-	videos, err := h.videoRepo.GetAll(ctx)
-	if err != nil {
+	job := services.NewReindexVocabularyJob(getUserIDFromContext(r.Context()))
+	if err := h.jobs.Enqueue(ctx, job); err != nil {
 		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
 		return
 	}
 
-	// Get all vocabulary
-	vocabularies, err := h.vocabRepo.GetAll(ctx)
-	if err != nil {
-		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
-		return
+	response := map[string]interface{}{
+		"message": "Reindexing started",
+		"job":     job,
 	}
 
-	// Clear existing indexes
-	err = h.vocabIndexRepo.DeleteAll(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CheckIndex handles GET /api/v1/vocabulary/index/check
+func (h *VocabularySearchHandler) CheckIndex(w http.ResponseWriter, r *http.Request) {
+	// Every video's subtitles are indexed again to compare, which takes as long as a reindex
+	ctx, cancel := context.WithTimeout(context.Background(), reindexTimeout)
+	defer cancel()
+
+	check, err := h.indexService.CheckIndex(ctx)
 	if err != nil {
 		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
 		return
 	}
 
-	// Create vocabulary indexer
-	indexer := utils.NewVocabularyIndexer(vocabularies)
-
-	totalIndexed := 0
-	processedVideos := 0
-
-	// Process each video
-	for _, video := range videos {
-		if video.Subtitle == "" {
-			continue // Skip videos without subtitles
-		}
-
-		// Extract filename from subtitle URL/path
-		var vttFilename string
-		if strings.HasPrefix(video.Subtitle, "/api/v1/uploads/vtt/") {
-			// Extract filename from URL
-			vttFilename = strings.TrimPrefix(video.Subtitle, "/api/v1/uploads/vtt/")
-		} else if strings.Contains(video.Subtitle, "/") {
-			// Extract filename from path
-			vttFilename = filepath.Base(video.Subtitle)
-		} else {
-			// Assume it's already a filename
-			vttFilename = video.Subtitle
-		}
-
-		// Construct full path to VTT file
-		vttFilePath := filepath.Join("./uploads/vtt", vttFilename)
-
-		// Check if file exists
-		if _, err := os.Stat(vttFilePath); os.IsNotExist(err) {
-			fmt.Printf("VTT file not found: %s\n", vttFilePath)
-			continue // Skip videos with missing VTT files
-		}
-
-		// Read VTT file content
-		vttContent, err := os.ReadFile(vttFilePath)
-		if err != nil {
-			fmt.Printf("Error reading VTT file %s: %v\n", vttFilePath, err)
-			continue // Skip videos with unreadable VTT files
-		}
-
-		// Parse VTT content
-		transcriptLines, err := utils.ParseVTTToLines(string(vttContent))
-		if err != nil {
-			fmt.Printf("Error parsing VTT content for video %s: %v\n", video.ID, err)
-			continue // Skip videos with invalid VTT
-		}
-
-		// Index vocabulary for this video
-		indexes, err := indexer.IndexTranscript(video.ID, transcriptLines)
-		if err != nil {
-			fmt.Println("Error indexing vocabulary:", err)
-			continue // Skip videos that fail indexing
-		}
-
-		// Save indexes to database
-		if len(indexes) > 0 {
-			err = h.vocabIndexRepo.CreateBatch(ctx, indexes)
-			if err != nil {
-				fmt.Println("Error saving indexes:", err)
-				continue // Skip videos that fail to save
-			}
-			totalIndexed += len(indexes)
-		}
-
-		processedVideos++
-	}
-
-	response := map[string]interface{}{
-		"message":          "Reindexing completed",
-		"processed_videos": processedVideos,
-		"total_indexed":    totalIndexed,
-		"total_videos":     len(videos),
-		"total_vocabulary": len(vocabularies),
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(check)
 }
 
 // getUserIDFromJWT extracts user ID from JWT token in Authorization header
-func (h *VocabularySearchHandler) getUserIDFromJWT(r *http.Request) (string, error) {
+func getUserIDFromJWT(r *http.Request) (string, error) {
 	// Get Authorization header
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
@@ -1,138 +1,373 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"video-player-backend/internal/database"
 	"video-player-backend/internal/errors"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/services"
+	"video-player-backend/internal/storage"
 	"video-player-backend/internal/utils"
+	"video-player-backend/internal/validation"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // VTTUploadHandler handles VTT file uploads
 type VTTUploadHandler struct {
-	uploadPath     string
-	vocabRepo      database.VocabularyRepository
-	vocabIndexRepo database.VocabularyIndexRepository
-	videoRepo      database.VideoRepository
+	store        storage.BlobStore
+	videoRepo    database.VideoRepository
+	vocabRepo    database.VocabularyRepository
+	indexService *services.VocabularyIndexService
+	jobs         *services.JobRunner
 }
 
 // NewVTTUploadHandler creates a new VTT upload handler
 func NewVTTUploadHandler(
-	uploadPath string,
-	vocabRepo database.VocabularyRepository,
-	vocabIndexRepo database.VocabularyIndexRepository,
+	store storage.BlobStore,
 	videoRepo database.VideoRepository,
+	vocabRepo database.VocabularyRepository,
+	indexService *services.VocabularyIndexService,
+	jobs *services.JobRunner,
 ) *VTTUploadHandler {
-	// Ensure upload directory exists
-	if err := os.MkdirAll(uploadPath, 0755); err != nil {
-		log.Printf("Failed to create upload directory: %v", err)
-	}
-
 	return &VTTUploadHandler{
-		uploadPath:     uploadPath,
-		vocabRepo:      vocabRepo,
-		vocabIndexRepo: vocabIndexRepo,
-		videoRepo:      videoRepo,
+		store:        store,
+		videoRepo:    videoRepo,
+		vocabRepo:    vocabRepo,
+		indexService: indexService,
+		jobs:         jobs,
 	}
 }
 
-// UploadVTT handles VTT file uploads
+// UploadVTT handles subtitle file uploads. SRT, SBV and TTML files are converted to VTT.
+// If a video_id is given the file replaces that video's subtitle track and the video is reindexed.
 func (h *VTTUploadHandler) UploadVTT(w http.ResponseWriter, r *http.Request) {
+	// Synchronous indexing can take longer than the default timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if !parseSubtitleForm(w, r) {
+		return
+	}
+
+	header, vttContent, doc, format, ok := h.readSubtitleFile(w, r)
+	if !ok {
+		return
+	}
+
+	// Look up the video before storing anything so a bad video or track ID leaves no file behind
+	video, ok := h.getFormVideo(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	trackIndex := 0
+	vttName := strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename)) + ".vtt"
+	if video != nil {
+		trackIndex = uploadTrackIndex(video, strings.TrimSpace(r.FormValue("track")))
+		if trackIndex == trackNotFound {
+			errors.WriteErrorResponse(w, errors.ErrSubtitleTrackNotFound)
+			return
+		}
+		vttName = video.ID + ".vtt"
+	}
+
+	report, err := h.lint(ctx, doc, video, r)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+	if r.FormValue("reject_on_error") == "true" && report.HasErrors() {
+		writeLintErrors(w, report)
+		return
+	}
+
+	duplicates, err := h.indexService.FindSubtitlesByContent(ctx, []byte(vttContent))
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+	duplicateNames := make([]string, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		duplicateNames = append(duplicateNames, duplicate.Name)
+	}
+
+	warnings := doc.Warnings
+	if warnings == nil {
+		warnings = []utils.ParseWarning{}
+	}
+
+	response := map[string]interface{}{
+		"message":       "VTT file uploaded successfully",
+		"original_name": header.Filename,
+		"size":          len(vttContent),
+		"hash":          storage.HashBytes([]byte(vttContent)),
+		"format":        format,
+		"converted":     format != utils.SubtitleFormatVTT,
+		"cue_count":     len(doc.Cues),
+		"warnings":      warnings,
+		"lint":          report,
+		"duplicate_of":  duplicateNames,
+		"uploaded_at":   time.Now().UTC(),
+	}
+
+	// A file that is already stored is reused rather than stored twice. Files bound to a video are
+	// the exception: the subtitle editor rewrites them in place, so each track keeps its own copy.
+	if existing := duplicateFile(duplicates, video, trackIndex); existing != "" {
+		response["message"] = "An identical subtitle file is already stored"
+		response["filename"] = existing
+		response["url"] = services.SubtitleURL(existing)
+		response["duplicate"] = true
+		if video != nil {
+			response["video_id"] = video.ID
+			response["track_id"] = uploadTrackID(video, trackIndex)
+			response["indexing"] = map[string]interface{}{
+				"status": "skipped",
+				"reason": "the track already has this content",
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := utils.WriteJSONResponse(w, response); err != nil {
+			log.Printf("Failed to write JSON response: %v", err)
+		}
+		return
+	}
+
+	// Converted subtitles are always stored as VTT
+	filename := generateUniqueFilename(vttName)
+	url, err := h.indexService.SaveSubtitle(ctx, filename, []byte(vttContent))
+	if err != nil {
+		log.Printf("Failed to store file: %v", err)
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+	response["filename"] = filename
+	response["url"] = url
+	response["duplicate"] = false
+
+	if video != nil {
+		trackID, err := h.bindToVideo(ctx, video, trackIndex, url)
+		if err != nil {
+			log.Printf("Failed to update subtitle of video %s: %v", video.ID, err)
+			h.store.Delete(ctx, filename)
+			errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+			return
+		}
+
+		response["message"] = "Subtitle track replaced successfully"
+		response["video_id"] = video.ID
+		response["track_id"] = trackID
+		response["indexing"] = h.indexUpload(ctx, video, trackID, r.FormValue("async") == "true", getUserIDFromContext(r.Context()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	// Write JSON response
+	if err := utils.WriteJSONResponse(w, response); err != nil {
+		log.Printf("Failed to write JSON response: %v", err)
+	}
+}
+
+// LintVTT handles POST /vtt/lint. It checks an uploaded subtitle file, or the current
+// subtitle track of the video given by video_id, without storing anything.
+func (h *VTTUploadHandler) LintVTT(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
+
+	if !parseSubtitleForm(w, r) {
+		return
+	}
+
+	video, ok := h.getFormVideo(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	var doc *utils.SubtitleDocument
+	format := utils.SubtitleFormatVTT
+	if _, _, err := r.FormFile("vtt_file"); err == nil {
+		if _, _, doc, format, ok = h.readSubtitleFile(w, r); !ok {
+			return
+		}
+	} else if video != nil {
+		track := selectTrack(video, strings.TrimSpace(r.FormValue("track")))
+		if track == nil {
+			errors.WriteErrorResponse(w, errors.ErrSubtitleTrackNotFound)
+			return
+		}
+
+		content, err := h.indexService.ReadSubtitle(ctx, track.URL)
+		if err != nil {
+			errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrSubtitleTrackNotFound))
+			return
+		}
+		if doc, err = utils.ParseWebVTT(content); err != nil {
+			errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
+			return
+		}
+	} else {
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(errors.ErrInvalidRequest.Code, "Nothing to lint", "upload a vtt_file or give a video_id"))
+		return
+	}
+
+	report, err := h.lint(ctx, doc, video, r)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := utils.WriteJSONResponse(w, map[string]interface{}{
+		"format": format,
+		"lint":   report,
+	}); err != nil {
+		log.Printf("Failed to write JSON response: %v", err)
+	}
+}
 
+// parseSubtitleForm parses a multipart subtitle upload form
+func parseSubtitleForm(w http.ResponseWriter, r *http.Request) bool {
 	// Check if request is multipart/form-data
 	if r.Header.Get("Content-Type") == "" || !strings.Contains(r.Header.Get("Content-Type"), "multipart/form-data") {
 		errors.WriteErrorResponse(w, errors.ErrInvalidRequest)
-		return
+		return false
 	}
 
 	// Parse multipart form with max memory of 32MB
-	err := r.ParseMultipartForm(32 << 20) // 32MB
-	if err != nil {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
 		log.Printf("Failed to parse multipart form: %v", err)
 		errors.WriteErrorResponse(w, errors.ErrInvalidRequest)
-		return
+		return false
 	}
+	return true
+}
 
+// readSubtitleFile reads the vtt_file upload and converts it to VTT if needed
+func (h *VTTUploadHandler) readSubtitleFile(w http.ResponseWriter, r *http.Request) (*multipart.FileHeader, string, *utils.SubtitleDocument, string, bool) {
 	// Get the file from the form
 	file, header, err := r.FormFile("vtt_file")
 	if err != nil {
 		log.Printf("Failed to get file from form: %v", err)
 		errors.WriteErrorResponse(w, errors.ErrInvalidRequest)
-		return
+		return nil, "", nil, "", false
 	}
 	defer file.Close()
 
 	// Validate file
 	if err := h.validateVTTFile(header.Filename, header.Size); err != nil {
 		errors.WriteErrorResponse(w, err)
-		return
+		return nil, "", nil, "", false
 	}
 
-	// Generate unique filename
-	filename := h.generateUniqueFilename(header.Filename)
-	filePath := filepath.Join(h.uploadPath, filename)
+	// Read the upload so it can be parsed, and converted if it isn't VTT
+	content, err := io.ReadAll(file)
+	if err != nil {
+		log.Printf("Failed to read uploaded file: %v", err)
+		errors.WriteErrorResponse(w, errors.ErrInvalidRequest)
+		return nil, "", nil, "", false
+	}
 
-	// Create the file on disk
-	dst, err := os.Create(filePath)
+	vttContent, doc, format, err := utils.ConvertToWebVTT(header.Filename, content)
 	if err != nil {
-		log.Printf("Failed to create file: %v", err)
-		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
-		return
+		errors.WriteErrorResponse(w, errors.NewAPIErrorWithDetails(
+			"INVALID_FILE_TYPE",
+			"Subtitle file could not be parsed.",
+			err.Error(),
+		))
+		return nil, "", nil, "", false
 	}
-	defer dst.Close()
 
-	// Copy the uploaded file to the destination
-	_, err = io.Copy(dst, file)
+	return header, vttContent, doc, format, true
+}
+
+// getFormVideo looks up the video named by the video_id form value, if any
+func (h *VTTUploadHandler) getFormVideo(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.Video, bool) {
+	videoID := strings.TrimSpace(r.FormValue("video_id"))
+	if videoID == "" {
+		return nil, true
+	}
+
+	if ve := validation.ValidateVideoID(videoID); ve.HasErrors() {
+		errors.WriteValidationError(w, ve)
+		return nil, false
+	}
+
+	video, err := h.videoRepo.GetByID(ctx, videoID)
 	if err != nil {
-		log.Printf("Failed to copy file: %v", err)
-		// Clean up the created file
-		os.Remove(filePath)
+		if err == mongo.ErrNoDocuments {
+			errors.WriteErrorResponse(w, errors.ErrVideoNotFound)
+			return nil, false
+		}
 		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
-		return
+		return nil, false
 	}
+	return video, true
+}
 
-	// Read VTT content for vocabulary indexing
-	vttContent, err := os.ReadFile(filePath)
+// lint checks a subtitle document against the video's duration and the vocabulary.
+// max_line_length and max_cps form values override the default limits.
+func (h *VTTUploadHandler) lint(ctx context.Context, doc *utils.SubtitleDocument, video *models.Video, r *http.Request) (*utils.LintReport, error) {
+	vocabularies, err := h.vocabRepo.GetAll(ctx)
 	if err != nil {
-		log.Printf("Failed to read VTT file for indexing: %v", err)
-		// Continue without indexing - file upload was successful
-	} else {
-		// Index vocabulary in the VTT content
-		go h.indexVocabularyInVTT(filename, string(vttContent))
+		return nil, err
 	}
 
-	// Return success response with file info
-	response := map[string]interface{}{
-		"message":       "VTT file uploaded successfully",
-		"filename":      filename,
-		"original_name": header.Filename,
-		"size":          header.Size,
-		"uploaded_at":   time.Now().UTC(),
-		"url":           fmt.Sprintf("/api/v1/uploads/vtt/%s", filename),
+	opts := utils.LintOptions{Vocabulary: vocabularies}
+	if video != nil {
+		opts.Duration = video.DurationSeconds()
+	}
+	if n, err := strconv.Atoi(r.FormValue("max_line_length")); err == nil {
+		opts.MaxLineLength = n
+	}
+	if cps, err := strconv.ParseFloat(r.FormValue("max_cps"), 64); err == nil {
+		opts.MaxCharsPerSecond = cps
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	return utils.LintSubtitle(doc, opts), nil
+}
 
-	// Write JSON response
-	if err := utils.WriteJSONResponse(w, response); err != nil {
-		log.Printf("Failed to write JSON response: %v", err)
+// writeLintErrors rejects an upload whose lint report has errors
+func writeLintErrors(w http.ResponseWriter, report *utils.LintReport) {
+	apiErr := errors.NewAPIErrorWithDetails(
+		errors.ErrSubtitleLintFailed.Code,
+		errors.ErrSubtitleLintFailed.Message,
+		fmt.Sprintf("%d errors and %d warnings found, the file was not stored", report.Errors, report.Warnings),
+	)
+
+	response := struct {
+		*errors.APIError
+		Lint *utils.LintReport `json:"lint"`
+	}{
+		APIError: apiErr,
+		Lint:     report,
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(response)
 }
 
-// validateVTTFile validates the uploaded VTT file
+// validateVTTFile validates the uploaded subtitle file
 func (h *VTTUploadHandler) validateVTTFile(filename string, size int64) error {
 	// Check file extension
-	ext := strings.ToLower(filepath.Ext(filename))
-	if ext != ".vtt" {
-		return errors.NewAPIError("INVALID_FILE_TYPE", "Invalid file type. Only .vtt files are allowed.")
+	if utils.DetectSubtitleFormat(filename) == "" {
+		return errors.NewAPIErrorWithDetails("INVALID_FILE_TYPE",
+			"Invalid file type. Only subtitle files are allowed.",
+			"Allowed extensions: "+strings.Join(utils.SupportedSubtitleExtensions(), ", "))
 	}
 
 	// Check file size (max 10MB)
@@ -155,7 +390,7 @@ func (h *VTTUploadHandler) validateVTTFile(filename string, size int64) error {
 }
 
 // generateUniqueFilename generates a unique filename to prevent conflicts
-func (h *VTTUploadHandler) generateUniqueFilename(originalFilename string) string {
+func generateUniqueFilename(originalFilename string) string {
 	// Get file extension
 	ext := filepath.Ext(originalFilename)
 
@@ -175,10 +410,9 @@ func (h *VTTUploadHandler) generateUniqueFilename(originalFilename string) strin
 	return uniqueFilename
 }
 
-// GetVTTFile serves VTT files
+// GetVTTFile handles GET /uploads/vtt/{filename}, serving an uploaded subtitle file from the store
 func (h *VTTUploadHandler) GetVTTFile(w http.ResponseWriter, r *http.Request) {
-	// Get filename from URL path
-	filename := strings.TrimPrefix(r.URL.Path, "/api/v1/uploads/vtt/")
+	filename := mux.Vars(r)["filename"]
 
 	// Validate filename
 	if filename == "" || strings.Contains(filename, "..") {
@@ -186,49 +420,60 @@ func (h *VTTUploadHandler) GetVTTFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Construct file path
-	filePath := filepath.Join(h.uploadPath, filename)
-
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		errors.WriteErrorResponse(w, errors.NewAPIError("VTT_FILE_NOT_FOUND", "VTT file not found"))
+	obj, info, err := h.store.Get(r.Context(), filename)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			errors.WriteErrorResponse(w, errors.NewAPIError("VTT_FILE_NOT_FOUND", "VTT file not found"))
+			return
+		}
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
 		return
 	}
+	defer obj.Close()
 
 	// Set appropriate headers
 	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
 	w.Header().Set("Cache-Control", "public, max-age=3600") // Cache for 1 hour
+	if info.Hash != "" {
+		w.Header().Set("ETag", `"`+info.Hash+`"`)
+	}
 
-	// Serve the file
-	http.ServeFile(w, r, filePath)
+	// ServeContent handles Range and conditional requests
+	http.ServeContent(w, r, filename, info.ModTime, obj)
 }
 
 // ListVTTFiles lists all uploaded VTT files
 func (h *VTTUploadHandler) ListVTTFiles(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
 
-	// Read directory
-	files, err := os.ReadDir(h.uploadPath)
+	objects, err := h.store.List(ctx, "")
 	if err != nil {
-		log.Printf("Failed to read upload directory: %v", err)
-		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		log.Printf("Failed to list subtitle files: %v", err)
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
 		return
 	}
 
-	var vttFiles []map[string]interface{}
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".vtt") {
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
+	vttFiles := []map[string]interface{}{}
+	for _, info := range objects {
+		if !strings.HasSuffix(strings.ToLower(info.Name), ".vtt") {
+			continue
+		}
 
-			vttFiles = append(vttFiles, map[string]interface{}{
-				"filename":    file.Name(),
-				"size":        info.Size(),
-				"modified_at": info.ModTime().UTC(),
-				"url":         fmt.Sprintf("/api/v1/uploads/vtt/%s", file.Name()),
-			})
+		file := map[string]interface{}{
+			"filename":    info.Name,
+			"size":        info.Size,
+			"modified_at": info.ModTime.UTC(),
+			"url":         services.SubtitleURL(info.Name),
+		}
+		if info.Hash != "" {
+			file["hash"] = info.Hash
+		}
+		// A direct download link, for stores that can hand one out
+		if signed, err := h.store.SignedURL(ctx, info.Name, time.Hour); err == nil {
+			file["signed_url"] = signed
 		}
+		vttFiles = append(vttFiles, file)
 	}
 
 	response := map[string]interface{}{
@@ -244,6 +489,8 @@ func (h *VTTUploadHandler) ListVTTFiles(w http.ResponseWriter, r *http.Request)
 
 // DeleteVTTFile deletes a VTT file
 func (h *VTTUploadHandler) DeleteVTTFile(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := utils.ContextWithTimeout()
+	defer cancel()
 
 	// Get filename from query parameter
 	filename := r.URL.Query().Get("filename")
@@ -258,19 +505,14 @@ func (h *VTTUploadHandler) DeleteVTTFile(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Construct file path
-	filePath := filepath.Join(h.uploadPath, filename)
-
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		errors.WriteErrorResponse(w, errors.NewAPIError("VTT_FILE_NOT_FOUND", "VTT file not found"))
-		return
-	}
-
 	// Delete the file
-	if err := os.Remove(filePath); err != nil {
+	if err := h.store.Delete(ctx, filename); err != nil {
+		if storage.IsNotFound(err) {
+			errors.WriteErrorResponse(w, errors.NewAPIError("VTT_FILE_NOT_FOUND", "VTT file not found"))
+			return
+		}
 		log.Printf("Failed to delete file: %v", err)
-		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrDatabase))
+		errors.WriteErrorResponse(w, errors.WrapError(err, errors.ErrInternalServer))
 		return
 	}
 
@@ -285,49 +527,154 @@ func (h *VTTUploadHandler) DeleteVTTFile(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// indexVocabularyInVTT indexes vocabulary words found in VTT content
-func (h *VTTUploadHandler) indexVocabularyInVTT(filename, vttContent string) {
-	ctx, cancel := utils.ContextWithTimeout()
-	defer cancel()
+// Track positions returned by uploadTrackIndex besides an index into Video.Tracks
+const (
+	trackNotFound = -2
+	legacyTrack   = -1
+)
 
-	// Get all vocabulary
-	vocabularies, err := h.vocabRepo.GetAll(ctx)
-	if err != nil {
-		log.Printf("Failed to get vocabulary for indexing: %v", err)
-		return
+// uploadTrackIndex picks the track an upload replaces: the track with the given ID, or the
+// Māori track by default. Videos that only have a legacy subtitle get legacyTrack, and videos
+// with tracks but no Māori track get a new track appended at len(video.Tracks).
+func uploadTrackIndex(video *models.Video, trackID string) int {
+	if len(video.Tracks) == 0 {
+		if trackID == "" || trackID == "default" {
+			return legacyTrack
+		}
+		return trackNotFound
 	}
 
-	if len(vocabularies) == 0 {
-		log.Printf("No vocabulary found for indexing")
-		return
+	for i := range video.Tracks {
+		if trackID != "" && video.Tracks[i].ID == trackID {
+			return i
+		}
+	}
+	if trackID != "" {
+		return trackNotFound
 	}
 
-	// Parse VTT content
-	transcriptLines, err := utils.ParseVTTToLines(vttContent)
-	if err != nil {
-		log.Printf("Failed to parse VTT content for indexing: %v", err)
-		return
+	if maori := video.MaoriTrack(); maori != nil {
+		for i := range video.Tracks {
+			if video.Tracks[i].ID == maori.ID {
+				return i
+			}
+		}
 	}
+	return len(video.Tracks)
+}
 
-	// Create vocabulary indexer
-	indexer := utils.NewVocabularyIndexer(vocabularies)
+// duplicateFile returns the stored file an upload can reuse instead of storing its content again:
+// for a video, only the file its track already uses, otherwise any file with the same content
+func duplicateFile(duplicates []*storage.ObjectInfo, video *models.Video, trackIndex int) string {
+	if len(duplicates) == 0 {
+		return ""
+	}
+	if video == nil {
+		return duplicates[0].Name
+	}
 
-	// Index vocabulary for this VTT file
-	indexes, err := indexer.IndexTranscript(filename, transcriptLines)
-	if err != nil {
-		log.Printf("Failed to index vocabulary: %v", err)
-		return
+	current := ""
+	switch {
+	case trackIndex == legacyTrack:
+		current = video.Subtitle
+	case trackIndex < len(video.Tracks):
+		current = video.Tracks[trackIndex].URL
 	}
+	for _, duplicate := range duplicates {
+		if current != "" && current == services.SubtitleURL(duplicate.Name) {
+			return duplicate.Name
+		}
+	}
+	return ""
+}
 
-	if len(indexes) > 0 {
-		// Save indexes to database
-		err = h.vocabIndexRepo.CreateBatch(ctx, indexes)
-		if err != nil {
-			log.Printf("Failed to save vocabulary indexes: %v", err)
-			return
+// uploadTrackID returns the ID of the existing track an upload targets
+func uploadTrackID(video *models.Video, trackIndex int) string {
+	if trackIndex == legacyTrack {
+		return "default"
+	}
+	return video.Tracks[trackIndex].ID
+}
+
+// bindToVideo points the video's track at the uploaded file, saves the video and removes
+// the file it replaced if nothing else uses it. It returns the ID of the updated track.
+func (h *VTTUploadHandler) bindToVideo(ctx context.Context, video *models.Video, trackIndex int, url string) (string, error) {
+	var previous, trackID string
+
+	switch {
+	case trackIndex == legacyTrack:
+		previous = video.Subtitle
+		video.Subtitle = url
+		trackID = "default"
+	case trackIndex == len(video.Tracks):
+		video.Tracks = append(video.Tracks, models.SubtitleTrack{
+			Language: models.LanguageMaori,
+			Label:    "Te reo Māori",
+			Kind:     models.SubtitleKindSubtitles,
+			URL:      url,
+		})
+	default:
+		previous = video.Tracks[trackIndex].URL
+		video.Tracks[trackIndex].URL = url
+	}
+
+	if trackIndex != legacyTrack {
+		video.NormalizeTracks()
+		trackID = video.Tracks[trackIndex].ID
+	}
+
+	if err := h.videoRepo.Update(ctx, video.ID, video); err != nil {
+		return "", err
+	}
+
+	if previous != "" && previous != url {
+		if err := h.indexService.RemoveUnusedSubtitle(ctx, previous); err != nil {
+			log.Printf("Failed to remove replaced subtitle %s: %v", previous, err)
 		}
-		log.Printf("Indexed %d vocabulary occurrences in VTT file: %s", len(indexes), filename)
-	} else {
-		log.Printf("No vocabulary found in VTT file: %s", filename)
+	}
+
+	return trackID, nil
+}
+
+// indexUpload reindexes the video after its subtitle was replaced, either straight away or as
+// a background job, and describes the outcome for the upload response
+func (h *VTTUploadHandler) indexUpload(ctx context.Context, video *models.Video, trackID string, async bool, userID string) map[string]interface{} {
+	// Only the Māori track feeds the vocabulary index, but every track feeds the translations and transcript index
+	if maori := video.MaoriTrack(); maori == nil || maori.ID != trackID {
+		h.indexService.SyncTranscripts(ctx, video)
+		return map[string]interface{}{
+			"status": "skipped",
+			"reason": "only the Māori track is indexed",
+		}
+	}
+
+	if async {
+		job := services.NewIndexVideosJob([]string{video.ID}, userID)
+		if err := h.jobs.Enqueue(ctx, job); err != nil {
+			log.Printf("Failed to queue indexing of video %s: %v", video.ID, err)
+			return map[string]interface{}{
+				"status": models.JobFailed,
+				"error":  err.Error(),
+			}
+		}
+		return map[string]interface{}{
+			"status": job.Status,
+			"job_id": job.ID,
+		}
+	}
+
+	indexed, err := h.indexService.ReindexVideo(ctx, video)
+	if err != nil {
+		log.Printf("Failed to index vocabulary for video %s: %v", video.ID, err)
+		return map[string]interface{}{
+			"status": models.JobFailed,
+			"error":  err.Error(),
+		}
+	}
+
+	log.Printf("Indexed %d vocabulary occurrences for video %s", indexed, video.ID)
+	return map[string]interface{}{
+		"status":  models.JobCompleted,
+		"indexed": indexed,
 	}
 }
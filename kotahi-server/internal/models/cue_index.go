@@ -0,0 +1,47 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CueTokensVersion is the version of the way IndexedCue tokens are written. Version 1 tokens were
+// only lowercased; since version 2 they are normalised with NormalizeMaori, so rows written before
+// then are not found by searches and must be indexed again.
+const CueTokensVersion = 2
+
+// IndexedCue is one cue of a video's subtitle track in the full-text transcript index.
+// Unlike the vocabulary index it covers every word of every local track.
+type IndexedCue struct {
+	ID        string   `json:"id" bson:"_id,omitempty"`
+	VideoID   string   `json:"video_id" bson:"video_id"`
+	TrackID   string   `json:"track_id" bson:"track_id"`
+	Language  string   `json:"language" bson:"language"`
+	CueIndex  int      `json:"cue_index" bson:"cue_index"`   // 1-based position of the cue in the track
+	StartTime float64  `json:"start_time" bson:"start_time"` // Start time in seconds
+	EndTime   float64  `json:"end_time" bson:"end_time"`     // End time in seconds
+	Text      string   `json:"text" bson:"text"`             // Plain text with lines joined by spaces
+	Tokens    []string `json:"-" bson:"tokens"`              // Distinct normalised words, used to find candidate cues
+
+	TokensVersion int `json:"-" bson:"tokens_version,omitempty"` // CueTokensVersion the tokens were written with
+}
+
+// GenerateID generates a unique ID for the indexed cue
+func (c *IndexedCue) GenerateID() {
+	if c.ID == "" {
+		c.ID = primitive.NewObjectID().Hex()
+	}
+}
+
+// ConcordanceLine is one occurrence of a search term in a transcript, shown with the text around it
+type ConcordanceLine struct {
+	VideoID    string  `json:"video_id"`
+	VideoTitle string  `json:"video_title"`
+	TrackID    string  `json:"track_id"`
+	Language   string  `json:"language"`
+	CueIndex   int     `json:"cue_index"`
+	StartTime  float64 `json:"start_time"` // Start of the cue containing the match, in seconds
+	EndTime    float64 `json:"end_time"`
+	Left       string  `json:"left"`  // Text before the match
+	Match      string  `json:"match"` // The matched text as it appears in the transcript
+	Right      string  `json:"right"` // Text after the match
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Job statuses
+const (
+	JobPending   = "pending"   // Waiting for a worker, or for its next attempt after a failure
+	JobRunning   = "running"   // A worker is running the job
+	JobCompleted = "completed" // The job finished and its result is set
+	JobFailed    = "failed"    // Every attempt failed, the last error is set
+	JobCancelled = "cancelled" // An admin cancelled the job before it finished
+)
+
+// Job is a long-running task such as a reindex, run in the background by a worker. Jobs are
+// stored so their progress can be followed from any server and so they survive a restart.
+type Job struct {
+	ID              string                 `json:"id" bson:"_id,omitempty"`
+	Type            string                 `json:"type" bson:"type"`
+	Status          string                 `json:"status" bson:"status"`
+	Params          map[string]string      `json:"params,omitempty" bson:"params,omitempty"`
+	Progress        JobProgress            `json:"progress" bson:"progress"`
+	Result          map[string]interface{} `json:"result,omitempty" bson:"result,omitempty"`
+	Error           string                 `json:"error,omitempty" bson:"error,omitempty"` // Error of the last failed attempt
+	Attempts        int                    `json:"attempts" bson:"attempts"`
+	MaxAttempts     int                    `json:"max_attempts" bson:"max_attempts"`
+	CancelRequested bool                   `json:"cancel_requested,omitempty" bson:"cancel_requested,omitempty"`
+	CreatedBy       string                 `json:"created_by,omitempty" bson:"created_by,omitempty"` // ID of the admin who started the job
+	CreatedAt       time.Time              `json:"created_at" bson:"created_at"`
+	RunAt           time.Time              `json:"run_at" bson:"run_at"` // When the job may next be started
+	StartedAt       *time.Time             `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	HeartbeatAt     *time.Time             `json:"-" bson:"heartbeat_at,omitempty"` // Last sign of life from the worker running the job
+	FinishedAt      *time.Time             `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// JobProgress is how far a running job has got
+type JobProgress struct {
+	Done    int    `json:"done" bson:"done"`
+	Total   int    `json:"total" bson:"total"`
+	Message string `json:"message,omitempty" bson:"message,omitempty"`
+}
+
+// GenerateID generates a new random ID as string
+func (j *Job) GenerateID() {
+	if j.ID == "" {
+		bytes := make([]byte, 12)
+		rand.Read(bytes)
+		j.ID = hex.EncodeToString(bytes)
+	}
+}
+
+// Finished reports whether the job has stopped for good
+func (j *Job) Finished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed || j.Status == JobCancelled
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Media kinds that can be uploaded
+const (
+	MediaKindVideo     = "video"
+	MediaKindThumbnail = "thumbnail"
+)
+
+// Media represents an uploaded video or thumbnail file hosted by the server
+type Media struct {
+	ID           string    `json:"id" bson:"_id,omitempty"`
+	Kind         string    `json:"kind" bson:"kind"`         // "video" or "thumbnail"
+	Filename     string    `json:"filename" bson:"filename"` // Name of the file in storage
+	OriginalName string    `json:"original_name" bson:"original_name"`
+	ContentType  string    `json:"content_type" bson:"content_type"`
+	Size         int64     `json:"size" bson:"size"`
+	Public       bool      `json:"public" bson:"public"` // Non-public media requires a signed URL
+	UploadedBy   string    `json:"uploaded_by" bson:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+	URL          string    `json:"url" bson:"-"` // Populated for responses
+}
+
+// GenerateID generates a new random ID as string
+func (m *Media) GenerateID() {
+	if m.ID == "" {
+		bytes := make([]byte, 12)
+		rand.Read(bytes)
+		m.ID = hex.EncodeToString(bytes)
+	}
+}
+
+// Path returns the API path used to stream the media file
+func (m *Media) Path() string {
+	return "/api/v1/media/" + m.ID
+}
@@ -15,9 +15,10 @@ type VocabularyOccurrence struct {
 	Vocabulary  string  `json:"vocabulary"`
 	English     string  `json:"english"`
 	Description string  `json:"description"`
-	StartTime   float64 `json:"start_time"` // Time in seconds where vocabulary was found
-	EndTime     float64 `json:"end_time"`   // End time in seconds
-	Transcript  string  `json:"transcript"` // The transcript line containing the vocabulary
+	StartTime   float64 `json:"start_time"`            // Time in seconds where vocabulary was found
+	EndTime     float64 `json:"end_time"`              // End time in seconds
+	Transcript  string  `json:"transcript"`            // The transcript line containing the vocabulary
+	Translation string  `json:"translation,omitempty"` // English sentence aligned with the transcript line
 }
 
 // SearchResponse represents the response for general search
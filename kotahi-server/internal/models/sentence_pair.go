@@ -0,0 +1,38 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SentencePair is a line of a video's Māori transcript aligned with its English translation.
+// A pair can span several cues on either side when the tracks split sentences differently.
+type SentencePair struct {
+	ID             string  `json:"id" bson:"_id,omitempty"`
+	VideoID        string  `json:"video_id" bson:"video_id"`
+	Index          int     `json:"index" bson:"index"`
+	StartTime      float64 `json:"start_time" bson:"start_time"` // Start of the earliest cue in seconds
+	EndTime        float64 `json:"end_time" bson:"end_time"`     // End of the latest cue in seconds
+	Maori          string  `json:"maori" bson:"maori"`
+	English        string  `json:"english" bson:"english"`
+	MaoriCues      []int   `json:"maori_cues" bson:"maori_cues"`     // 1-based indexes of the Māori cues
+	EnglishCues    []int   `json:"english_cues" bson:"english_cues"` // 1-based indexes of the English cues
+	MaoriTrackID   string  `json:"maori_track_id" bson:"maori_track_id"`
+	EnglishTrackID string  `json:"english_track_id" bson:"english_track_id"`
+}
+
+// GenerateID generates a unique ID for the sentence pair
+func (p *SentencePair) GenerateID() {
+	if p.ID == "" {
+		p.ID = primitive.NewObjectID().Hex()
+	}
+}
+
+// FindSentencePair returns the pair whose time range contains the given time, or nil
+func FindSentencePair(pairs []*SentencePair, time float64) *SentencePair {
+	for _, pair := range pairs {
+		if time >= pair.StartTime && time < pair.EndTime {
+			return pair
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Subtitle track kinds, matching the HTML <track> element
+const (
+	SubtitleKindSubtitles = "subtitles"
+	SubtitleKindCaptions  = "captions"
+)
+
+// Language codes used for subtitle tracks
+const (
+	LanguageMaori     = "mi"  // Te reo Māori
+	LanguageEnglish   = "en"  // English
+	LanguageBilingual = "mul" // Bilingual Māori and English
+)
+
+// SubtitleTrack represents one subtitle file attached to a video
+type SubtitleTrack struct {
+	ID       string `json:"id" bson:"id"`
+	Language string `json:"language" bson:"language"` // BCP 47 language code, e.g. "mi", "en" or "mul"
+	Label    string `json:"label" bson:"label"`       // Name shown in the player, e.g. "Te reo Māori"
+	Kind     string `json:"kind" bson:"kind"`         // "subtitles" or "captions"
+	URL      string `json:"url" bson:"url"`           // URL or path of the VTT file
+	Default  bool   `json:"default" bson:"default"`   // Whether the player selects this track by default
+}
+
+// IsMaori reports whether the track is in te reo Māori
+func (t *SubtitleTrack) IsMaori() bool {
+	return t.primaryLanguage() == LanguageMaori
+}
+
+// IsEnglish reports whether the track is in English
+func (t *SubtitleTrack) IsEnglish() bool {
+	return t.primaryLanguage() == LanguageEnglish
+}
+
+// primaryLanguage returns the primary subtag of the track's language code, e.g. "en" for "en-NZ"
+func (t *SubtitleTrack) primaryLanguage() string {
+	return strings.ToLower(strings.SplitN(t.Language, "-", 2)[0])
+}
+
+// GenerateID generates a unique ID for the track
+func (t *SubtitleTrack) GenerateID() {
+	if t.ID == "" {
+		t.ID = primitive.NewObjectID().Hex()
+	}
+}
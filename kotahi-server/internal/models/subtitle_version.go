@@ -0,0 +1,31 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// SubtitleVersion is a stored revision of a video's subtitle track.
+// Version 0 holds the file as it was before the first edit.
+type SubtitleVersion struct {
+	ID           string    `json:"id" bson:"_id,omitempty"`
+	VideoID      string    `json:"video_id" bson:"video_id"`
+	TrackID      string    `json:"track_id" bson:"track_id"`
+	Version      int       `json:"version" bson:"version"`
+	Content      string    `json:"content,omitempty" bson:"content"` // WebVTT content of the track
+	CueCount     int       `json:"cue_count" bson:"cue_count"`
+	Author       string    `json:"author" bson:"author"` // ID of the user who saved the version
+	Message      string    `json:"message,omitempty" bson:"message,omitempty"`
+	RestoredFrom *int      `json:"restored_from,omitempty" bson:"restored_from,omitempty"` // Set when the version is a rollback
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+}
+
+// GenerateID generates a new random ID as string
+func (v *SubtitleVersion) GenerateID() {
+	if v.ID == "" {
+		bytes := make([]byte, 12)
+		rand.Read(bytes)
+		v.ID = hex.EncodeToString(bytes)
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+// VocabularySpan marks a vocabulary match inside a cue's text
+type VocabularySpan struct {
+	Start        int    `json:"start"` // Offset of the first character, counted in Unicode code points
+	End          int    `json:"end"`   // Offset just past the last character
+	Text         string `json:"text"`  // The matched text as it appears in the cue
+	VocabularyID string `json:"vocabulary_id"`
+	Vocabulary   string `json:"vocabulary"` // The Māori word/phrase
+	English      string `json:"english"`    // English gloss
+	MatchType    string `json:"match_type"` // MatchTypeExact or MatchTypeInflected
+}
+
+// TranscriptCue represents one cue of a transcript with its vocabulary matches
+type TranscriptCue struct {
+	ID        string           `json:"id"`
+	Index     int              `json:"index"`
+	StartTime float64          `json:"start_time"` // Start time in seconds
+	EndTime   float64          `json:"end_time"`   // End time in seconds
+	Text      string           `json:"text"`       // Plain text, lines separated by "\n"
+	Speaker   string           `json:"speaker,omitempty"`
+	Spans     []VocabularySpan `json:"spans"`
+
+	// The aligned sentence in the other language, from the video's sentence pairs
+	Translation string `json:"translation,omitempty"`
+	Pair        int    `json:"pair,omitempty"` // Index of the sentence pair the cue belongs to
+}
+
+// Transcript represents a video's subtitle track as structured cues
+type Transcript struct {
+	VideoID string          `json:"video_id"`
+	Track   SubtitleTrack   `json:"track"`
+	Cues    []TranscriptCue `json:"cues"`
+
+	SentencePairs []*SentencePair `json:"sentence_pairs,omitempty"` // Set when the video has Māori and English tracks
+}
+
+// GlossaryEntry is a vocabulary word or phrase found in a video, listed at the end of an exported transcript
+type GlossaryEntry struct {
+	Maori       string `json:"maori"`
+	English     string `json:"english"`
+	Description string `json:"description,omitempty"`
+}
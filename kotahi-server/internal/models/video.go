@@ -3,27 +3,49 @@ package models
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Video represents a video object
 type Video struct {
-	ID          string `json:"id" bson:"_id,omitempty"`
-	Title       string `json:"title" bson:"title"`
-	Description string `json:"description" bson:"description"`
-	Thumbnail   string `json:"thumbnail" bson:"thumbnail"`
-	Video       string `json:"video" bson:"video"`
-	Subtitle    string `json:"subtitle" bson:"subtitle"`
-	Duration    string `json:"duration" bson:"duration"`
+	ID          string          `json:"id" bson:"_id,omitempty"`
+	Title       string          `json:"title" bson:"title"`
+	Description string          `json:"description" bson:"description"`
+	Thumbnail   string          `json:"thumbnail" bson:"thumbnail"`
+	Video       string          `json:"video" bson:"video"`
+	Subtitle    string          `json:"subtitle" bson:"subtitle"` // URL of the primary Māori track, kept for older clients
+	Tracks      []SubtitleTrack `json:"tracks" bson:"tracks,omitempty"`
+	Duration    string          `json:"duration" bson:"duration"`
+	Tags        []string        `json:"tags" bson:"tags,omitempty"`
+	Series      string          `json:"series" bson:"series,omitempty"`                   // Series or season the video belongs to
+	Source      string          `json:"source" bson:"source"`                             // Provider type used to resolve the video URL
+	SourceURL   string          `json:"source_url" bson:"source_url"`                     // Original link as entered, used to re-resolve
+	DeletedAt   *time.Time      `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // Set when the video is in the trash
 }
 
 // VideoRequest represents the request payload for creating/updating videos
 type VideoRequest struct {
-	Title       string `json:"title" validate:"required"`
-	Description string `json:"description"`
-	Thumbnail   string `json:"thumbnail"`
-	Video       string `json:"video" validate:"required"`
-	Subtitle    string `json:"subtitle"`
-	Duration    string `json:"duration"`
+	Title       string          `json:"title" validate:"required"`
+	Description string          `json:"description"`
+	Thumbnail   string          `json:"thumbnail"`
+	Video       string          `json:"video" validate:"required"`
+	Subtitle    string          `json:"subtitle"`
+	Tracks      []SubtitleTrack `json:"tracks"`
+	Duration    string          `json:"duration"`
+	Tags        []string        `json:"tags"`
+	Series      string          `json:"series"`
+	Source      string          `json:"source"` // Optional provider type, detected from the link if empty
+}
+
+// RelatedVideo represents a recommended video and why it was recommended
+type RelatedVideo struct {
+	Video            *Video   `json:"video"`
+	Score            float64  `json:"score"`
+	SharedVocabulary []string `json:"shared_vocabulary"` // Most significant words the videos share
+	SharedTags       []string `json:"shared_tags"`
+	SameSeries       bool     `json:"same_series"`
 }
 
 // ToVideo converts VideoRequest to Video
@@ -34,8 +56,114 @@ func (vr *VideoRequest) ToVideo() *Video {
 		Thumbnail:   vr.Thumbnail,
 		Video:       vr.Video,
 		Subtitle:    vr.Subtitle,
+		Tracks:      vr.Tracks,
 		Duration:    vr.Duration,
+		Tags:        normalizeTags(vr.Tags),
+		Series:      strings.TrimSpace(vr.Series),
+		Source:      vr.Source,
+	}
+}
+
+// SubtitleTracks returns the video's subtitle tracks, treating a legacy subtitle as a single Māori track
+func (v *Video) SubtitleTracks() []SubtitleTrack {
+	if len(v.Tracks) > 0 {
+		return v.Tracks
+	}
+	if v.Subtitle == "" {
+		return []SubtitleTrack{}
+	}
+	return []SubtitleTrack{{
+		ID:       "default",
+		Language: LanguageMaori,
+		Label:    "Te reo Māori",
+		Kind:     SubtitleKindSubtitles,
+		URL:      v.Subtitle,
+		Default:  true,
+	}}
+}
+
+// MaoriTrack returns the track used for vocabulary indexing: the default Māori track,
+// otherwise the first Māori track, or nil if the video has none
+func (v *Video) MaoriTrack() *SubtitleTrack {
+	return v.findTrack((*SubtitleTrack).IsMaori)
+}
+
+// EnglishTrack returns the track used for translations: the default English track,
+// otherwise the first English track, or nil if the video has none
+func (v *Video) EnglishTrack() *SubtitleTrack {
+	return v.findTrack((*SubtitleTrack).IsEnglish)
+}
+
+// findTrack returns the default track matching the language check, otherwise the first match
+func (v *Video) findTrack(matches func(*SubtitleTrack) bool) *SubtitleTrack {
+	tracks := v.SubtitleTracks()
+	var first *SubtitleTrack
+	for i := range tracks {
+		if !matches(&tracks[i]) {
+			continue
+		}
+		if tracks[i].Default {
+			return &tracks[i]
+		}
+		if first == nil {
+			first = &tracks[i]
+		}
 	}
+	return first
+}
+
+// NormalizeTracks assigns track IDs, makes sure exactly one track is the default
+// and keeps the legacy Subtitle field pointing at the Māori track
+func (v *Video) NormalizeTracks() {
+	if len(v.Tracks) == 0 {
+		return
+	}
+
+	hasDefault := false
+	for i := range v.Tracks {
+		v.Tracks[i].GenerateID()
+		if v.Tracks[i].Kind == "" {
+			v.Tracks[i].Kind = SubtitleKindSubtitles
+		}
+		if v.Tracks[i].Default {
+			if hasDefault {
+				v.Tracks[i].Default = false
+			}
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		v.Tracks[0].Default = true
+	}
+
+	if track := v.MaoriTrack(); track != nil {
+		v.Subtitle = track.URL
+	} else {
+		v.Subtitle = ""
+	}
+}
+
+// DurationSeconds parses the MM:SS or HH:MM:SS duration, returning 0 if it is missing or invalid
+func (v *Video) DurationSeconds() float64 {
+	parts := strings.Split(v.Duration, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return 0
+	}
+
+	seconds := 0
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return float64(seconds)
+}
+
+// IsTrashed reports whether the video has been moved to the trash
+func (v *Video) IsTrashed() bool {
+	return v.DeletedAt != nil
 }
 
 // GenerateID generates a new random ID as string
@@ -46,3 +174,18 @@ func (v *Video) GenerateID() {
 		v.ID = hex.EncodeToString(bytes)
 	}
 }
+
+// normalizeTags lowercases and trims tags, dropping empty and duplicate ones
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
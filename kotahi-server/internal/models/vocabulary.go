@@ -1,31 +1,65 @@
 package models
 
 import (
+	"reflect"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Vocabulary represents a Māori vocabulary word/phrase
 type Vocabulary struct {
-	ID          string `json:"id" bson:"_id,omitempty"`
-	Maori       string `json:"maori" bson:"maori"`
-	English     string `json:"english" bson:"english"`
-	Description string `json:"description" bson:"description"`
+	ID           string              `json:"id" bson:"_id,omitempty"`
+	Maori        string              `json:"maori" bson:"maori"`
+	English      string              `json:"english" bson:"english"` // Main English sense
+	Description  string              `json:"description" bson:"description"`
+	PartOfSpeech string              `json:"part_of_speech,omitempty" bson:"part_of_speech,omitempty"`
+	Variants     []string            `json:"variants,omitempty" bson:"variants,omitempty"` // Alternative spellings and dialect forms
+	Forms        []VocabularyForm    `json:"forms,omitempty" bson:"forms,omitempty"`       // Inflected forms such as plurals and passives
+	Senses       []string            `json:"senses,omitempty" bson:"senses,omitempty"`     // Further English senses
+	Examples     []VocabularyExample `json:"examples,omitempty" bson:"examples,omitempty"`
+	Tags         []string            `json:"tags,omitempty" bson:"tags,omitempty"`             // Topic tags
+	Difficulty   int                 `json:"difficulty,omitempty" bson:"difficulty,omitempty"` // 1 (beginner) to 5 (advanced), 0 if not set
+	AudioURL     string              `json:"audio_url,omitempty" bson:"audio_url,omitempty"`   // Pronunciation recording
+}
+
+// VocabularyForm is an inflected form of a headword
+type VocabularyForm struct {
+	Kind string `json:"kind" bson:"kind"` // e.g. "plural" or "passive"
+	Form string `json:"form" bson:"form"`
+}
+
+// VocabularyExample is an example sentence using a headword
+type VocabularyExample struct {
+	Maori   string `json:"maori" bson:"maori"`
+	English string `json:"english,omitempty" bson:"english,omitempty"`
+}
+
+// PartsOfSpeech lists the accepted part of speech values
+var PartsOfSpeech = []string{
+	"noun", "proper_noun", "verb", "stative", "adjective", "adverb", "pronoun", "particle",
+	"preposition", "conjunction", "determiner", "numeral", "interjection", "phrase",
 }
 
 // VocabularyRequest represents the request payload for creating/updating vocabulary
 type VocabularyRequest struct {
-	Maori       string `json:"maori" validate:"required,min=1,max=200"`
-	English     string `json:"english" validate:"required,min=1,max=200"`
-	Description string `json:"description" validate:"required,min=1,max=1000"`
+	Maori        string              `json:"maori" validate:"required,min=1,max=200"`
+	English      string              `json:"english" validate:"required,min=1,max=200"`
+	Description  string              `json:"description" validate:"required,min=1,max=1000"`
+	PartOfSpeech string              `json:"part_of_speech"`
+	Variants     []string            `json:"variants"`
+	Forms        []VocabularyForm    `json:"forms"`
+	Senses       []string            `json:"senses"`
+	Examples     []VocabularyExample `json:"examples"`
+	Tags         []string            `json:"tags"`
+	Difficulty   int                 `json:"difficulty" validate:"min=0,max=5"`
+	AudioURL     string              `json:"audio_url"`
 }
 
 // ToVocabulary converts a VocabularyRequest to a Vocabulary model
 func (vr *VocabularyRequest) ToVocabulary() *Vocabulary {
-	return &Vocabulary{
-		Maori:       vr.Maori,
-		English:     vr.English,
-		Description: vr.Description,
-	}
+	v := &Vocabulary{}
+	v.UpdateFromRequest(vr)
+	return v
 }
 
 // GenerateID generates a unique ID for the vocabulary item
@@ -41,4 +75,69 @@ func (v *Vocabulary) UpdateFromRequest(vr *VocabularyRequest) {
 	v.Maori = vr.Maori
 	v.English = vr.English
 	v.Description = vr.Description
+	v.PartOfSpeech = vr.PartOfSpeech
+	v.Variants = vr.Variants
+	v.Forms = vr.Forms
+	v.Senses = vr.Senses
+	v.Examples = vr.Examples
+	v.Tags = vr.Tags
+	v.Difficulty = vr.Difficulty
+	v.AudioURL = vr.AudioURL
+}
+
+// SameContent reports whether two entries hold the same content, ignoring their IDs.
+// Empty and missing lists are treated alike.
+func (v *Vocabulary) SameContent(other *Vocabulary) bool {
+	a, b := *v, *other
+	a.ID, b.ID = "", ""
+	for _, entry := range []*Vocabulary{&a, &b} {
+		if len(entry.Variants) == 0 {
+			entry.Variants = nil
+		}
+		if len(entry.Forms) == 0 {
+			entry.Forms = nil
+		}
+		if len(entry.Senses) == 0 {
+			entry.Senses = nil
+		}
+		if len(entry.Examples) == 0 {
+			entry.Examples = nil
+		}
+		if len(entry.Tags) == 0 {
+			entry.Tags = nil
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Surfaces returns the headword followed by its variants and inflected forms, the spellings
+// that count as an occurrence of the entry in a transcript
+func (v *Vocabulary) Surfaces() []string {
+	surfaces := []string{v.Maori}
+	surfaces = append(surfaces, v.Variants...)
+	for _, form := range v.Forms {
+		surfaces = append(surfaces, form.Form)
+	}
+	return surfaces
+}
+
+// VocabularyChange is an existing entry and what an import would change it to
+type VocabularyChange struct {
+	Before *Vocabulary `json:"before"`
+	After  *Vocabulary `json:"after"`
+}
+
+// VocabularyDiff describes what a vocabulary import adds, changes and removes, matched by Māori headword
+type VocabularyDiff struct {
+	Mode      string             `json:"mode"`
+	Added     []*Vocabulary      `json:"added"`
+	Changed   []VocabularyChange `json:"changed"`
+	Unchanged []*Vocabulary      `json:"unchanged"`
+	Removed   []*Vocabulary      `json:"removed"`           // Only in replace mode
+	Skipped   []VocabularyChange `json:"skipped,omitempty"` // Existing entries an add-only import leaves as they are
+}
+
+// HasChanges reports whether applying the diff would change the vocabulary
+func (d *VocabularyDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Changed) > 0 || len(d.Removed) > 0
 }
@@ -6,20 +6,53 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Vocabulary index match types
+const (
+	MatchTypeExact     = "exact"     // The headword, a variant or a listed form was found
+	MatchTypeInflected = "inflected" // An inflection of one of them was recognised, e.g. "karangatia" for "karanga"
+)
+
 // VocabularyIndex represents an indexed vocabulary word/phrase in a video transcript
 type VocabularyIndex struct {
-	ID          string    `json:"id" bson:"_id,omitempty"`
-	VideoID     string    `json:"video_id" bson:"video_id"`
-	Video       Video     `json:"video" bson:"video"`
-	Vocabulary  string    `json:"vocabulary" bson:"vocabulary"` // The Māori word/phrase
-	English     string    `json:"english" bson:"english"`       // English translation
-	Description string    `json:"description" bson:"description"`
-	StartTime   float64   `json:"start_time" bson:"start_time"`   // Start time in seconds
-	EndTime     float64   `json:"end_time" bson:"end_time"`       // End time in seconds
-	Transcript  string    `json:"transcript" bson:"transcript"`   // The full transcript line
-	LineNumber  int       `json:"line_number" bson:"line_number"` // Line number in the transcript
-	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+	ID           string    `json:"id" bson:"_id,omitempty"`
+	VideoID      string    `json:"video_id" bson:"video_id"`
+	Video        Video     `json:"video" bson:"video"`
+	VocabularyID string    `json:"vocabulary_id,omitempty" bson:"vocabulary_id,omitempty"`
+	Vocabulary   string    `json:"vocabulary" bson:"vocabulary"`                         // The Māori word/phrase
+	MatchedForm  string    `json:"matched_form,omitempty" bson:"matched_form,omitempty"` // Variant or inflected form found, empty when the headword itself was found
+	MatchType    string    `json:"match_type,omitempty" bson:"match_type,omitempty"`     // MatchTypeExact or MatchTypeInflected
+	English      string    `json:"english" bson:"english"`                               // English translation
+	Description  string    `json:"description" bson:"description"`
+	StartTime    float64   `json:"start_time" bson:"start_time"`   // Start time in seconds
+	EndTime      float64   `json:"end_time" bson:"end_time"`       // End time in seconds
+	Transcript   string    `json:"transcript" bson:"transcript"`   // The full transcript line
+	LineNumber   int       `json:"line_number" bson:"line_number"` // Line number in the transcript
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// VocabularyIndexDrift lists how a video's stored index entries differ from indexing it again.
+// Each occurrence is described by its vocabulary item and line, e.g. "kia ora (line 3)".
+type VocabularyIndexDrift struct {
+	VideoID  string   `json:"video_id"`
+	Title    string   `json:"title"`
+	Missing  []string `json:"missing,omitempty"`  // Occurrences in the subtitles that aren't indexed
+	Extra    []string `json:"extra,omitempty"`    // Indexed occurrences that are no longer found
+	Outdated []string `json:"outdated,omitempty"` // Indexed occurrences whose details have changed
+	Error    string   `json:"error,omitempty"`    // Why the video's subtitles couldn't be checked
+}
+
+// VocabularyIndexCheck is the result of comparing the active index with the videos and vocabulary
+type VocabularyIndexCheck struct {
+	InSync         bool                   `json:"in_sync"`
+	CheckedVideos  int                    `json:"checked_videos"`
+	DriftedVideos  int                    `json:"drifted_videos"`
+	Missing        int                    `json:"missing"`
+	Extra          int                    `json:"extra"`
+	Outdated       int                    `json:"outdated"`
+	OrphanedVideos []string               `json:"orphaned_videos"` // Videos that no longer exist but still have index entries
+	Videos         []VocabularyIndexDrift `json:"videos"`          // Videos whose index differs
+	CheckedAt      time.Time              `json:"checked_at"`
 }
 
 // VocabularyIndexRequest represents the request payload for vocabulary index operations
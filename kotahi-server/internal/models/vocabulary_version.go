@@ -0,0 +1,69 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Vocabulary version statuses
+const (
+	VocabularyVersionBuilding = "building" // Entries are saved and the index is being built
+	VocabularyVersionReady    = "ready"    // The index is complete and the version can be activated
+	VocabularyVersionFailed   = "failed"   // The index could not be built, the version can't be activated
+)
+
+// VocabularyVersion is a snapshot of the whole vocabulary together with its video index.
+// Each version keeps its entries and index in collections of its own, so activating a version
+// only swaps which collections are read.
+type VocabularyVersion struct {
+	ID               string     `json:"id" bson:"_id,omitempty"`
+	Number           int        `json:"number" bson:"number"`
+	Status           string     `json:"status" bson:"status"`
+	Source           string     `json:"source" bson:"source"`                         // How the version was made, e.g. "import" or "initial"
+	Mode             string     `json:"mode,omitempty" bson:"mode,omitempty"`         // Import mode the version was made with
+	Filename         string     `json:"filename,omitempty" bson:"filename,omitempty"` // Name of the imported file
+	Note             string     `json:"note,omitempty" bson:"note,omitempty"`
+	BasedOn          string     `json:"based_on,omitempty" bson:"based_on,omitempty"`                   // ID of the version the import was applied to
+	BasedOnRevision  int        `json:"based_on_revision,omitempty" bson:"based_on_revision,omitempty"` // Last edit of BasedOn the version's entries include
+	EntryCount       int        `json:"entry_count" bson:"entry_count"`
+	Added            int        `json:"added" bson:"added"`
+	Changed          int        `json:"changed" bson:"changed"`
+	Removed          int        `json:"removed" bson:"removed"`
+	IndexedCount     int        `json:"indexed_count" bson:"indexed_count"`
+	IndexedVideos    int        `json:"indexed_videos" bson:"indexed_videos"`
+	Incremental      bool       `json:"incremental" bson:"incremental,omitempty"`                 // The index was built from the previous version's index
+	BuildJobID       string     `json:"build_job_id,omitempty" bson:"build_job_id,omitempty"`     // Job that builds the version's index
+	IndexStale       bool       `json:"index_stale" bson:"index_stale,omitempty"`                 // Subtitles may have changed since the index was built, until RefreshJobID finishes
+	RefreshJobID     string     `json:"refresh_job_id,omitempty" bson:"refresh_job_id,omitempty"` // Job that brings the index up to date after activation
+	PublishedBy      string     `json:"published_by,omitempty" bson:"published_by,omitempty"`     // ID of the admin who published the version
+	PublishedByEmail string     `json:"published_by_email,omitempty" bson:"published_by_email,omitempty"`
+	Error            string     `json:"error,omitempty" bson:"error,omitempty"`
+	Collection       string     `json:"-" bson:"collection"`       // Collection holding the version's entries
+	IndexCollection  string     `json:"-" bson:"index_collection"` // Collection holding the version's video index
+	Active           bool       `json:"active" bson:"-"`           // Set when listing versions
+	CreatedAt        time.Time  `json:"created_at" bson:"created_at"`
+	ReadyAt          *time.Time `json:"ready_at,omitempty" bson:"ready_at,omitempty"`
+	ActivatedAt      *time.Time `json:"activated_at,omitempty" bson:"activated_at,omitempty"` // When the version was last activated
+}
+
+// GenerateID generates a new random ID as string
+func (v *VocabularyVersion) GenerateID() {
+	if v.ID == "" {
+		bytes := make([]byte, 12)
+		rand.Read(bytes)
+		v.ID = hex.EncodeToString(bytes)
+	}
+}
+
+// VocabularyEdit is a single entry created, edited or deleted in a version through the vocabulary
+// API. Edits are replayed into versions made from an earlier snapshot of that version, so they
+// aren't lost when one of those is activated.
+type VocabularyEdit struct {
+	ID           string      `json:"id" bson:"_id,omitempty"`
+	VersionID    string      `json:"version_id" bson:"version_id"`
+	Revision     int         `json:"revision" bson:"revision"` // The version's revision after the edit
+	VocabularyID string      `json:"vocabulary_id" bson:"vocabulary_id"`
+	After        *Vocabulary `json:"after,omitempty" bson:"after,omitempty"` // The entry after the edit, nil if it was deleted
+	CreatedAt    time.Time   `json:"created_at" bson:"created_at"`
+}
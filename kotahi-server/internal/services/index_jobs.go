@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Index job types
+const (
+	JobReindexVocabulary = "reindex_vocabulary" // Rebuild the active vocabulary index for every video, run by VocabularyVersionService
+	JobIndexVideos       = "index_videos"       // Reindex some videos, params: video_ids, comma separated
+	JobIndexTranscripts  = "index_transcripts"  // Rebuild the transcript index of every video
+)
+
+// indexJobTimeout limits each attempt of an index job, which reads the subtitles of many videos
+const indexJobTimeout = 30 * time.Minute
+
+// IndexVideosResult is the result of an index_videos job
+type IndexVideosResult struct {
+	ReindexResult
+	Failed map[string]string `json:"failed,omitempty"` // Error for each video that could not be indexed
+}
+
+// IndexTranscriptsResult is the result of an index_transcripts job
+type IndexTranscriptsResult struct {
+	TotalVideos int               `json:"total_videos"`
+	IndexedCues int               `json:"indexed_cues"`
+	Failed      map[string]string `json:"failed,omitempty"` // Error for each video whose transcripts could not be indexed
+}
+
+// RegisterJobs sets how the runner runs index jobs
+func (s *VocabularyIndexService) RegisterJobs(runner *JobRunner) {
+	runner.Register(JobIndexVideos, JobType{
+		Run:         s.runIndexVideos,
+		MaxAttempts: 3,
+		Timeout:     indexJobTimeout,
+	})
+	runner.Register(JobIndexTranscripts, JobType{
+		Run:         s.runIndexTranscripts,
+		MaxAttempts: 3,
+		Timeout:     indexJobTimeout,
+	})
+}
+
+// QueueTranscriptUpgrade queues an index_transcripts job when the transcript index has cues written
+// before the current CueTokensVersion, which searches no longer find, and no such job is waiting
+// or running already. It is called at startup, so upgrading needs no manual step.
+func (s *VocabularyIndexService) QueueTranscriptUpgrade(ctx context.Context, runner *JobRunner) (*models.Job, error) {
+	outdated, err := s.cueIndexRepo.CountOutdated(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count outdated transcript index entries: %w", err)
+	}
+	if outdated == 0 {
+		return nil, nil
+	}
+
+	for _, status := range []string{models.JobPending, models.JobRunning} {
+		jobs, err := runner.List(ctx, JobIndexTranscripts, status, 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list transcript index jobs: %w", err)
+		}
+		if len(jobs) > 0 {
+			return jobs[0], nil
+		}
+	}
+
+	job := &models.Job{Type: JobIndexTranscripts}
+	if err := runner.Enqueue(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// NewReindexVocabularyJob returns a job that rebuilds the active vocabulary index
+func NewReindexVocabularyJob(createdBy string) *models.Job {
+	return &models.Job{Type: JobReindexVocabulary, CreatedBy: createdBy}
+}
+
+// NewIndexVideosJob returns a job that reindexes the given videos
+func NewIndexVideosJob(videoIDs []string, createdBy string) *models.Job {
+	return &models.Job{
+		Type:      JobIndexVideos,
+		Params:    map[string]string{"video_ids": strings.Join(videoIDs, ",")},
+		CreatedBy: createdBy,
+	}
+}
+
+// runIndexVideos reindexes each of the job's videos. Videos deleted since the job was queued are
+// skipped. A video that can't be indexed doesn't stop the others; the job only fails, and is
+// retried, when none of them could be indexed.
+func (s *VocabularyIndexService) runIndexVideos(ctx context.Context, job *models.Job) (interface{}, error) {
+	ids := strings.Split(job.Params["video_ids"], ",")
+	result := &IndexVideosResult{
+		ReindexResult: ReindexResult{TotalVideos: len(ids)},
+		Failed:        make(map[string]string),
+	}
+	if indexer, err := s.NewIndexer(ctx); err == nil {
+		result.TotalVocabulary = indexer.Len()
+	}
+
+	var lastErr error
+	for i, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ReportProgress(ctx, i, len(ids), "")
+
+		video, err := s.videoRepo.GetByID(ctx, id)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get video %s: %w", id, err)
+		}
+
+		indexed, err := s.ReindexVideo(ctx, video)
+		if err != nil {
+			result.Failed[id] = err.Error()
+			lastErr = err
+			continue
+		}
+
+		result.TotalIndexed += indexed
+		result.ProcessedVideos++
+	}
+	ReportProgress(ctx, len(ids), len(ids), "")
+
+	if result.ProcessedVideos == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return result, nil
+}
+
+// runIndexTranscripts rebuilds the transcript index of every video, leaving the vocabulary index
+// alone. Like runIndexVideos it only fails when no video could be indexed.
+func (s *VocabularyIndexService) runIndexTranscripts(ctx context.Context, job *models.Job) (interface{}, error) {
+	videos, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+	result := &IndexTranscriptsResult{
+		TotalVideos: len(videos),
+		Failed:      make(map[string]string),
+	}
+
+	var lastErr error
+	for i, video := range videos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ReportProgress(ctx, i, len(videos), video.Title)
+
+		indexed, err := s.IndexTranscripts(ctx, video)
+		if err != nil {
+			result.Failed[video.ID] = err.Error()
+			lastErr = err
+			continue
+		}
+		result.IndexedCues += indexed
+	}
+	ReportProgress(ctx, len(videos), len(videos), "")
+
+	if len(result.Failed) == len(videos) && lastErr != nil {
+		return nil, lastErr
+	}
+	return result, nil
+}
@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// DefaultJobWorkers is how many jobs a server runs at once
+	DefaultJobWorkers = 2
+	// defaultJobTimeout limits each attempt of job types that don't set their own timeout
+	defaultJobTimeout = time.Hour
+	// jobPollInterval is how often idle workers look for due jobs, such as retries and jobs
+	// queued by another server
+	jobPollInterval = 5 * time.Second
+	// jobHeartbeatInterval is how often a running job's progress is saved and its cancellation checked
+	jobHeartbeatInterval = 5 * time.Second
+	// jobStaleAfter is how long a running job can go without a heartbeat before it is assumed to
+	// have been lost with its server and is queued again
+	jobStaleAfter = time.Minute
+	// jobSweepInterval is how often lost jobs are requeued and old jobs removed
+	jobSweepInterval = time.Minute
+	// jobRetention is how long finished jobs can still be looked up
+	jobRetention = 7 * 24 * time.Hour
+	// jobRetryDelay is the wait before the second attempt, doubled for each attempt after that
+	jobRetryDelay = 30 * time.Second
+	// jobMaxRetryDelay caps the wait between attempts
+	jobMaxRetryDelay = 15 * time.Minute
+	// jobInterruptGrace is how long interrupted jobs are given to stop when the server shuts down
+	jobInterruptGrace = 5 * time.Second
+)
+
+// Job errors
+var (
+	ErrUnknownJobType = errors.New("unknown job type")
+	ErrJobFinished    = errors.New("job has already finished")
+)
+
+// Reasons a running job's context is cancelled
+var (
+	errJobCancelled   = errors.New("job cancelled")
+	errJobInterrupted = errors.New("job interrupted by server shutdown")
+)
+
+// JobFunc runs one attempt of a job. It should stop when ctx is done and may report how far it
+// has got with ReportProgress. The result must marshal to a JSON object.
+type JobFunc func(ctx context.Context, job *models.Job) (interface{}, error)
+
+// JobType describes how a kind of job is run
+type JobType struct {
+	Run         JobFunc
+	MaxAttempts int           // Attempts before the job fails, at least 1
+	Timeout     time.Duration // Limit on each attempt, an hour if not set
+	// Failed is called once a job has failed for good or been cancelled, to undo or record what
+	// it leaves behind. It may be called more than once for a cancelled job. Optional.
+	Failed func(ctx context.Context, job *models.Job)
+}
+
+// JobRunner runs stored jobs on a bounded pool of workers. Jobs are claimed from the database,
+// so several servers can share the queue. Failed attempts are retried with exponential backoff,
+// and jobs still running at shutdown are put back in the queue for the next start.
+type JobRunner struct {
+	repo    database.JobRepository
+	workers int
+	types   map[string]JobType
+
+	wake     chan struct{} // Nudges idle workers when a job is queued
+	stop     chan struct{} // Closed when the runner starts shutting down
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex // Guards running
+	running map[string]context.CancelCauseFunc
+}
+
+// NewJobRunner creates a job runner with the given number of workers
+func NewJobRunner(repo database.JobRepository, workers int) *JobRunner {
+	if workers < 1 {
+		workers = 1
+	}
+	return &JobRunner{
+		repo:    repo,
+		workers: workers,
+		types:   make(map[string]JobType),
+		wake:    make(chan struct{}, workers),
+		stop:    make(chan struct{}),
+		running: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Register sets how jobs of a type are run. Types must be registered before Start.
+func (r *JobRunner) Register(jobType string, def JobType) {
+	if def.MaxAttempts < 1 {
+		def.MaxAttempts = 1
+	}
+	if def.Timeout <= 0 {
+		def.Timeout = defaultJobTimeout
+	}
+	r.types[jobType] = def
+}
+
+// Start starts the workers and the sweep that requeues lost jobs
+func (r *JobRunner) Start() {
+	r.sweep()
+
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(jobSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.sweep()
+			}
+		}
+	}()
+}
+
+// Shutdown stops taking new work and waits for running jobs to finish. Jobs still running when
+// ctx is done are interrupted and queued again, so they start over on the next start.
+func (r *JobRunner) Shutdown(ctx context.Context) error {
+	r.stopOnce.Do(func() { close(r.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	r.mu.Lock()
+	for id, cancel := range r.running {
+		log.Printf("Interrupting job %s for shutdown", id)
+		cancel(errJobInterrupted)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(jobInterruptGrace):
+		log.Printf("Jobs did not stop in time, they will be queued again once they are stale")
+	}
+	return ctx.Err()
+}
+
+// Enqueue stores a new job and wakes a worker to run it. The job's type and params must be set;
+// its ID may be set beforehand so it can be referred to before it is queued.
+func (r *JobRunner) Enqueue(ctx context.Context, job *models.Job) error {
+	def, ok := r.types[job.Type]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type)
+	}
+
+	now := time.Now()
+	job.Status = models.JobPending
+	job.MaxAttempts = def.MaxAttempts
+	job.CreatedAt = now
+	job.RunAt = now
+	if err := r.repo.Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to queue job: %w", err)
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Get returns a job by ID
+func (r *JobRunner) Get(ctx context.Context, id string) (*models.Job, error) {
+	return r.repo.GetByID(ctx, id)
+}
+
+// List returns the newest jobs, optionally only those of a type or status
+func (r *JobRunner) List(ctx context.Context, jobType, status string, limit int) ([]*models.Job, error) {
+	return r.repo.List(ctx, jobType, status, limit)
+}
+
+// Cancel cancels a pending job, or asks a running one to stop. A job running on this server
+// stops straight away; one running elsewhere stops at its next heartbeat.
+func (r *JobRunner) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	job, err := r.repo.RequestCancel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Finished() && job.Status != models.JobCancelled {
+		return job, ErrJobFinished
+	}
+
+	r.mu.Lock()
+	if cancel, ok := r.running[id]; ok {
+		cancel(errJobCancelled)
+	}
+	r.mu.Unlock()
+
+	// A job cancelled before it started is never finished by a worker
+	if def, ok := r.types[job.Type]; ok && def.Failed != nil && job.Status == models.JobCancelled {
+		def.Failed(ctx, job)
+	}
+
+	return job, nil
+}
+
+// work runs due jobs until there are none, then waits to be woken or for the next poll
+func (r *JobRunner) work() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for r.next() {
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// next claims and runs one due job, reporting whether there was one
+func (r *JobRunner) next() bool {
+	select {
+	case <-r.stop:
+		return false
+	default:
+	}
+
+	types := make([]string, 0, len(r.types))
+	for jobType := range r.types {
+		types = append(types, jobType)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	job, err := r.repo.Claim(ctx, types)
+	cancel()
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Printf("Failed to claim a job: %v", err)
+		}
+		return false
+	}
+
+	r.run(job)
+	return true
+}
+
+// run runs one attempt of a claimed job and records the outcome
+func (r *JobRunner) run(job *models.Job) {
+	def := r.types[job.Type]
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	r.mu.Lock()
+	r.running[job.ID] = cancel
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, job.ID)
+		r.mu.Unlock()
+	}()
+
+	progress := &jobProgress{progress: job.Progress}
+	runCtx, cancelTimeout := context.WithTimeout(context.WithValue(ctx, jobProgressKey{}, progress), def.Timeout)
+	defer cancelTimeout()
+
+	heartbeatDone := make(chan struct{})
+	stopHeartbeat := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		r.heartbeat(job.ID, progress, cancel, stopHeartbeat)
+	}()
+
+	log.Printf("Job %s (%s) started, attempt %d of %d", job.ID, job.Type, job.Attempts, job.MaxAttempts)
+	result, err := def.Run(runCtx, job)
+
+	close(stopHeartbeat)
+	<-heartbeatDone
+
+	job.Progress = progress.get()
+	r.finish(job, def, result, err, context.Cause(ctx))
+}
+
+// heartbeat saves the job's progress until stop is closed, and cancels the job if an admin asked
+// for it to be cancelled from another server
+func (r *JobRunner) heartbeat(id string, progress *jobProgress, cancel context.CancelCauseFunc, stop chan struct{}) {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancelSave := context.WithTimeout(context.Background(), 10*time.Second)
+		cancelRequested, err := r.repo.Heartbeat(ctx, id, progress.get())
+		cancelSave()
+		if err != nil {
+			log.Printf("Failed to save progress of job %s: %v", id, err)
+			continue
+		}
+		if cancelRequested {
+			cancel(errJobCancelled)
+		}
+	}
+}
+
+// finish records the outcome of an attempt: the job completes, is cancelled, goes back in the
+// queue to be retried after a backoff, or fails once it has no attempts left
+func (r *JobRunner) finish(job *models.Job, def JobType, result interface{}, runErr, cause error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	job.HeartbeatAt = nil
+	attempt := job.Attempts
+
+	switch {
+	case runErr == nil:
+		job.Status = models.JobCompleted
+		job.Error = ""
+		job.FinishedAt = &now
+		document, err := resultDocument(result)
+		if err != nil {
+			log.Printf("Failed to record the result of job %s: %v", job.ID, err)
+		}
+		job.Result = document
+		log.Printf("Job %s (%s) completed", job.ID, job.Type)
+
+	case errors.Is(cause, errJobCancelled):
+		job.Status = models.JobCancelled
+		job.Error = errJobCancelled.Error()
+		job.FinishedAt = &now
+		log.Printf("Job %s (%s) cancelled", job.ID, job.Type)
+
+	case errors.Is(cause, errJobInterrupted):
+		// An interrupted attempt doesn't count, the job starts over on the next start
+		job.Status = models.JobPending
+		job.Attempts--
+		job.RunAt = now
+		log.Printf("Job %s (%s) interrupted, it will run again after a restart", job.ID, job.Type)
+
+	case job.Attempts < job.MaxAttempts:
+		job.Status = models.JobPending
+		job.Error = runErr.Error()
+		job.RunAt = now.Add(retryDelay(job.Attempts))
+		log.Printf("Job %s (%s) attempt %d failed, retrying at %s: %v", job.ID, job.Type, job.Attempts, job.RunAt.Format(time.RFC3339), runErr)
+
+	default:
+		job.Status = models.JobFailed
+		job.Error = runErr.Error()
+		job.FinishedAt = &now
+		log.Printf("Job %s (%s) failed after %d attempts: %v", job.ID, job.Type, job.Attempts, runErr)
+	}
+
+	saved, err := r.repo.Finish(ctx, job, attempt)
+	if err == nil && !saved && job.Status == models.JobPending {
+		// An admin asked for the job to be cancelled after its last heartbeat, so it isn't retried
+		job.Status = models.JobCancelled
+		job.Attempts = attempt
+		job.Error = errJobCancelled.Error()
+		job.FinishedAt = &now
+		saved, err = r.repo.Finish(ctx, job, attempt)
+		if saved {
+			log.Printf("Job %s (%s) cancelled", job.ID, job.Type)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to save job %s: %v", job.ID, err)
+	} else if !saved {
+		// The job was requeued while this attempt ran and now belongs to another worker
+		log.Printf("Job %s (%s) attempt %d finished after the job was taken over, its outcome is dropped", job.ID, job.Type, attempt)
+		return
+	}
+
+	if def.Failed != nil && (job.Status == models.JobFailed || job.Status == models.JobCancelled) {
+		def.Failed(ctx, job)
+	}
+}
+
+// sweep requeues jobs whose server stopped sending heartbeats and removes old finished jobs
+func (r *JobRunner) sweep() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if requeued, err := r.repo.RequeueStale(ctx, time.Now().Add(-jobStaleAfter)); err != nil {
+		log.Printf("Failed to requeue stale jobs: %v", err)
+	} else if requeued > 0 {
+		log.Printf("Requeued %d jobs that stopped without finishing", requeued)
+	}
+
+	if _, err := r.repo.DeleteFinishedBefore(ctx, time.Now().Add(-jobRetention)); err != nil {
+		log.Printf("Failed to remove old jobs: %v", err)
+	}
+}
+
+// retryDelay returns how long to wait before the attempt after the given one
+func retryDelay(attempt int) time.Duration {
+	delay := jobRetryDelay
+	for i := 1; i < attempt && delay < jobMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > jobMaxRetryDelay {
+		delay = jobMaxRetryDelay
+	}
+	return delay
+}
+
+// resultDocument converts a job result to the document stored on the job
+func resultDocument(result interface{}) (map[string]interface{}, error) {
+	if result == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// jobProgressKey is the context key of the running job's progress
+type jobProgressKey struct{}
+
+// jobProgress is the progress of a running job, saved by its heartbeat
+type jobProgress struct {
+	mu       sync.Mutex
+	progress models.JobProgress
+}
+
+// get returns a copy of the progress
+func (p *jobProgress) get() models.JobProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.progress
+}
+
+// ReportProgress records how far the job running with ctx has got. It does nothing when ctx
+// doesn't belong to a job, so work shared with request handlers can always report.
+func ReportProgress(ctx context.Context, done, total int, message string) {
+	progress, ok := ctx.Value(jobProgressKey{}).(*jobProgress)
+	if !ok {
+		return
+	}
+	progress.mu.Lock()
+	progress.progress = models.JobProgress{Done: done, Total: total, Message: message}
+	progress.mu.Unlock()
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/models"
+)
+
+const (
+	// relatedTagBonus is added to the score for each tag two videos share
+	relatedTagBonus = 0.1
+	// relatedMaxTagBonus caps the total tag bonus so tags can't outweigh vocabulary
+	relatedMaxTagBonus = 0.3
+	// relatedSeriesBonus is added to the score when two videos are in the same series
+	relatedSeriesBonus = 0.25
+	// relatedSharedVocabularyLimit is how many shared words are listed for each recommendation
+	relatedSharedVocabularyLimit = 5
+)
+
+// RelatedVideoService recommends videos that share vocabulary, tags or a series with a given video
+type RelatedVideoService struct {
+	videoRepo        database.VideoRepository
+	vocabIndexRepo   database.VocabularyIndexRepository
+	watchHistoryRepo database.WatchHistoryRepository
+}
+
+// NewRelatedVideoService creates a new related video service
+func NewRelatedVideoService(
+	videoRepo database.VideoRepository,
+	vocabIndexRepo database.VocabularyIndexRepository,
+	watchHistoryRepo database.WatchHistoryRepository,
+) *RelatedVideoService {
+	return &RelatedVideoService{
+		videoRepo:        videoRepo,
+		vocabIndexRepo:   vocabIndexRepo,
+		watchHistoryRepo: watchHistoryRepo,
+	}
+}
+
+// Related ranks other videos by their similarity to the given video.
+// Vocabulary overlap is scored with TF-IDF cosine similarity so rare shared words count
+// for more than common ones, and shared tags and series add a bonus on top.
+// If userID is set, videos the user has completed are left out.
+func (s *RelatedVideoService) Related(ctx context.Context, video *models.Video, userID string, limit int) ([]*models.RelatedVideo, error) {
+	videos, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+
+	termCounts, err := s.vocabIndexRepo.GetTermCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary counts: %w", err)
+	}
+
+	completed := make(map[string]bool)
+	if userID != "" {
+		histories, err := s.watchHistoryRepo.GetCompletedVideos(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get completed videos: %w", err)
+		}
+		for _, history := range histories {
+			completed[history.VideoID] = true
+		}
+	}
+
+	// Document frequency of each word across active videos; the index may still hold
+	// rows for trashed videos or standalone VTT uploads, which are ignored
+	documentFrequency := make(map[string]int)
+	documents := 0
+	for _, v := range videos {
+		terms := termCounts[v.ID]
+		if len(terms) == 0 {
+			continue
+		}
+		documents++
+		for term := range terms {
+			documentFrequency[term]++
+		}
+	}
+
+	weights := func(terms map[string]int) (map[string]float64, float64) {
+		vector := make(map[string]float64, len(terms))
+		norm := 0.0
+		for term, count := range terms {
+			idf := math.Log(1 + float64(documents)/float64(documentFrequency[term]))
+			weight := (1 + math.Log(float64(count))) * idf
+			vector[term] = weight
+			norm += weight * weight
+		}
+		return vector, math.Sqrt(norm)
+	}
+
+	target, targetNorm := weights(termCounts[video.ID])
+	targetTags := make(map[string]bool, len(video.Tags))
+	for _, tag := range video.Tags {
+		targetTags[tag] = true
+	}
+
+	var related []*models.RelatedVideo
+	for _, candidate := range videos {
+		if candidate.ID == video.ID || completed[candidate.ID] {
+			continue
+		}
+
+		result := &models.RelatedVideo{
+			Video:            candidate,
+			SharedVocabulary: []string{},
+			SharedTags:       []string{},
+		}
+
+		// Cosine similarity of the TF-IDF vectors
+		if targetNorm > 0 {
+			vector, norm := weights(termCounts[candidate.ID])
+			if norm > 0 {
+				type sharedTerm struct {
+					term   string
+					weight float64
+				}
+				var shared []sharedTerm
+				dot := 0.0
+				for term, weight := range vector {
+					if targetWeight, ok := target[term]; ok {
+						dot += weight * targetWeight
+						shared = append(shared, sharedTerm{term, weight * targetWeight})
+					}
+				}
+				result.Score = dot / (norm * targetNorm)
+
+				sort.Slice(shared, func(i, j int) bool {
+					if shared[i].weight != shared[j].weight {
+						return shared[i].weight > shared[j].weight
+					}
+					return shared[i].term < shared[j].term
+				})
+				for i := 0; i < len(shared) && i < relatedSharedVocabularyLimit; i++ {
+					result.SharedVocabulary = append(result.SharedVocabulary, shared[i].term)
+				}
+			}
+		}
+
+		for _, tag := range candidate.Tags {
+			if targetTags[tag] {
+				result.SharedTags = append(result.SharedTags, tag)
+			}
+		}
+		result.Score += math.Min(float64(len(result.SharedTags))*relatedTagBonus, relatedMaxTagBonus)
+
+		if video.Series != "" && candidate.Series == video.Series {
+			result.SameSeries = true
+			result.Score += relatedSeriesBonus
+		}
+
+		if result.Score > 0 {
+			result.Score = math.Round(result.Score*1000) / 1000
+			related = append(related, result)
+		}
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return related[i].Video.Title < related[j].Video.Title
+	})
+
+	if limit > 0 && len(related) > limit {
+		related = related[:limit]
+	}
+
+	return related, nil
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/storage"
+	"video-player-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrVersionConflict is returned when a track was saved by someone else since it was loaded
+	ErrVersionConflict = errors.New("subtitle track has changed since the base version")
+	// ErrTrackNotEditable is returned for tracks hosted outside the server
+	ErrTrackNotEditable = errors.New("only uploaded subtitle tracks can be edited")
+	// ErrSubtitleFileNotFound is returned when a track's file is missing from the upload directory
+	ErrSubtitleFileNotFound = errors.New("subtitle file not found")
+)
+
+// SubtitleEditSession is a subtitle track loaded for editing
+type SubtitleEditSession struct {
+	Video    *models.Video
+	Track    *models.SubtitleTrack
+	Content  string
+	Document *utils.SubtitleDocument
+	Version  int // Latest stored version, 0 if the track has never been edited
+
+	latest   *models.SubtitleVersion
+	recorded bool // Whether Content is already stored as a version
+	cueCount int  // Number of cues in Content
+}
+
+// SubtitleEditorService saves edited subtitle tracks and keeps their version history
+type SubtitleEditorService struct {
+	versionRepo  database.SubtitleVersionRepository
+	videoRepo    database.VideoRepository
+	indexService *VocabularyIndexService
+}
+
+// NewSubtitleEditorService creates a new subtitle editor service
+func NewSubtitleEditorService(versionRepo database.SubtitleVersionRepository, videoRepo database.VideoRepository, indexService *VocabularyIndexService) *SubtitleEditorService {
+	return &SubtitleEditorService{
+		versionRepo:  versionRepo,
+		videoRepo:    videoRepo,
+		indexService: indexService,
+	}
+}
+
+// Open loads a track's current content and version for editing
+func (s *SubtitleEditorService) Open(ctx context.Context, video *models.Video, track *models.SubtitleTrack) (*SubtitleEditSession, error) {
+	if strings.HasPrefix(track.URL, "http://") || strings.HasPrefix(track.URL, "https://") {
+		return nil, ErrTrackNotEditable
+	}
+
+	content, err := s.indexService.ReadSubtitle(ctx, track.URL)
+	if storage.IsNotFound(err) {
+		return nil, ErrSubtitleFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subtitle track: %w", err)
+	}
+
+	doc, err := utils.ParseWebVTT(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subtitle track: %w", err)
+	}
+
+	session := &SubtitleEditSession{
+		Video:    video,
+		Track:    track,
+		Content:  content,
+		Document: doc,
+		cueCount: len(doc.Cues),
+	}
+
+	latest, err := s.versionRepo.GetLatest(ctx, video.ID, track.ID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to get latest version: %w", err)
+	}
+	if latest != nil {
+		session.latest = latest
+		session.Version = latest.Version
+		session.recorded = latest.Content == content
+	}
+
+	return session, nil
+}
+
+// Save writes the edited document to the track's file, stores it as a new version and
+// reindexes the video if the track is the one used for vocabulary. If baseVersion is set
+// and the track has been saved since that version, ErrVersionConflict is returned.
+func (s *SubtitleEditorService) Save(ctx context.Context, session *SubtitleEditSession, doc *utils.SubtitleDocument, author, message string, baseVersion *int) (*models.SubtitleVersion, error) {
+	return s.save(ctx, session, doc, author, message, baseVersion, nil)
+}
+
+// Rollback restores a stored version of the track as a new version
+func (s *SubtitleEditorService) Rollback(ctx context.Context, session *SubtitleEditSession, version int, author, message string, baseVersion *int) (*models.SubtitleVersion, error) {
+	stored, err := s.versionRepo.GetByVersion(ctx, session.Video.ID, session.Track.ID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := utils.ParseWebVTT(stored.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored version: %w", err)
+	}
+
+	if message == "" {
+		message = fmt.Sprintf("Rolled back to version %d", version)
+	}
+	return s.save(ctx, session, doc, author, message, baseVersion, &version)
+}
+
+// Versions lists a track's stored versions, newest first
+func (s *SubtitleEditorService) Versions(ctx context.Context, videoID, trackID string) ([]*models.SubtitleVersion, error) {
+	return s.versionRepo.GetByTrack(ctx, videoID, trackID)
+}
+
+// Version retrieves a stored version of a track including its content
+func (s *SubtitleEditorService) Version(ctx context.Context, videoID, trackID string, version int) (*models.SubtitleVersion, error) {
+	return s.versionRepo.GetByVersion(ctx, videoID, trackID, version)
+}
+
+// save stores the document as the next version of the session's track
+func (s *SubtitleEditorService) save(ctx context.Context, session *SubtitleEditSession, doc *utils.SubtitleDocument, author, message string, baseVersion, restoredFrom *int) (*models.SubtitleVersion, error) {
+	if baseVersion != nil && *baseVersion != session.Version {
+		return nil, ErrVersionConflict
+	}
+
+	// Make sure nobody saved the track between Open and Save
+	latest, err := s.versionRepo.GetLatest(ctx, session.Video.ID, session.Track.ID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to get latest version: %w", err)
+	}
+	if (latest == nil) != (session.latest == nil) || (latest != nil && latest.Version != session.latest.Version) {
+		return nil, ErrVersionConflict
+	}
+
+	next := 0
+	if latest != nil {
+		next = latest.Version + 1
+	}
+
+	// Keep the content the edit started from, whether it is the original upload or
+	// a file that was replaced outside the editor
+	if !session.recorded {
+		baseline := &models.SubtitleVersion{
+			VideoID:   session.Video.ID,
+			TrackID:   session.Track.ID,
+			Version:   next,
+			Content:   session.Content,
+			CueCount:  session.cueCount,
+			Message:   "Original upload",
+			CreatedAt: time.Now(),
+		}
+		if latest != nil {
+			baseline.Message = "Replaced outside the editor"
+		}
+		if err := s.createVersion(ctx, baseline); err != nil {
+			return nil, err
+		}
+		next++
+	}
+
+	// The version is stored before the file is written. Its number is unique per track, so of
+	// two saves racing from the same version only the first gets to write the file.
+	content := utils.WriteWebVTT(doc)
+	version := &models.SubtitleVersion{
+		VideoID:      session.Video.ID,
+		TrackID:      session.Track.ID,
+		Version:      next,
+		Content:      content,
+		CueCount:     len(doc.Cues),
+		Author:       author,
+		Message:      message,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.createVersion(ctx, version); err != nil {
+		return nil, err
+	}
+
+	if err := s.writeTrack(ctx, session, []byte(content)); err != nil {
+		// The track still holds the previous content, so the version must not stay in the history
+		if delErr := s.versionRepo.Delete(ctx, version.ID); delErr != nil {
+			log.Printf("Failed to remove version %d of track %s of video %s after a failed save: %v", version.Version, session.Track.ID, session.Video.ID, delErr)
+		}
+		return nil, fmt.Errorf("failed to write subtitle track: %w", err)
+	}
+
+	// Only the Māori track feeds the vocabulary index, but every track feeds the translations and transcript index
+	if maori := session.Video.MaoriTrack(); maori != nil && maori.ID == session.Track.ID {
+		if _, err := s.indexService.ReindexVideo(ctx, session.Video); err != nil {
+			// The edit is saved either way; a full reindex will pick it up
+			log.Printf("Failed to reindex video %s after subtitle edit: %v", session.Video.ID, err)
+		}
+	} else {
+		s.indexService.SyncTranscripts(ctx, session.Video)
+	}
+
+	session.Content = content
+	session.Document = doc
+	session.Version = version.Version
+	session.latest = version
+	session.recorded = true
+	session.cueCount = len(doc.Cues)
+
+	return version, nil
+}
+
+// createVersion stores a version, reporting ErrVersionConflict when another save took its number first
+func (s *SubtitleEditorService) createVersion(ctx context.Context, version *models.SubtitleVersion) error {
+	err := s.versionRepo.Create(ctx, version)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store version %d: %w", version.Version, err)
+	}
+	return nil
+}
+
+// writeTrack writes the content to the session's track. A file that another track also uses is
+// left as it is: the content is stored as a new file and the track is pointed at it, so an edit
+// never changes the subtitles of other videos.
+func (s *SubtitleEditorService) writeTrack(ctx context.Context, session *SubtitleEditSession, content []byte) error {
+	shared, err := s.indexService.SubtitleShared(ctx, session.Track.URL, session.Video.ID, session.Track.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check whether the file is shared: %w", err)
+	}
+	if !shared {
+		return s.indexService.ReplaceSubtitle(ctx, session.Track.URL, content)
+	}
+
+	filename := storedSubtitleFilename(session.Track.URL)
+	filename = fmt.Sprintf("%s_%s_%s.vtt", strings.TrimSuffix(filename, path.Ext(filename)), session.Video.ID, session.Track.ID)
+	url, err := s.indexService.SaveSubtitle(ctx, filename, content)
+	if err != nil {
+		return err
+	}
+
+	// Read the video again so changes made since the session was opened are kept
+	video, err := s.videoRepo.GetByID(ctx, session.Video.ID)
+	if err == nil {
+		err = pointTrackAt(video, session.Track.ID, url)
+	}
+	if err == nil {
+		err = s.videoRepo.Update(ctx, video.ID, video)
+	}
+	if err != nil {
+		if removeErr := s.indexService.RemoveUnusedSubtitle(ctx, url); removeErr != nil {
+			log.Printf("Failed to remove subtitle copy %s: %v", url, removeErr)
+		}
+		return fmt.Errorf("failed to point the track at its own copy: %w", err)
+	}
+
+	session.Video = video
+	for _, track := range video.SubtitleTracks() {
+		if track.ID == session.Track.ID {
+			session.Track = &track
+			break
+		}
+	}
+	return nil
+}
+
+// pointTrackAt sets the URL of one of the video's tracks, or of its legacy subtitle
+func pointTrackAt(video *models.Video, trackID, url string) error {
+	if len(video.Tracks) == 0 {
+		if video.Subtitle == "" || trackID != "default" {
+			return fmt.Errorf("track %s no longer exists", trackID)
+		}
+		video.Subtitle = url
+		return nil
+	}
+	for i := range video.Tracks {
+		if video.Tracks[i].ID == trackID {
+			video.Tracks[i].URL = url
+			video.NormalizeTracks()
+			return nil
+		}
+	}
+	return fmt.Errorf("track %s no longer exists", trackID)
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/storage"
+)
+
+// DefaultTrashRetention is how long deleted videos stay in the trash before they are purged
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// VideoTrashService permanently removes trashed videos together with everything that references them
+type VideoTrashService struct {
+	db               *database.MongoDB
+	videoRepo        database.VideoRepository
+	vocabIndexRepo   database.VocabularyIndexRepository
+	playlistRepo     database.PlaylistRepository
+	watchHistoryRepo database.WatchHistoryRepository
+	versionRepo      database.SubtitleVersionRepository
+	pairRepo         database.SentencePairRepository
+	cueIndexRepo     database.CueIndexRepository
+	subtitles        storage.BlobStore
+	retention        time.Duration
+}
+
+// NewVideoTrashService creates a new video trash service
+func NewVideoTrashService(
+	db *database.MongoDB,
+	videoRepo database.VideoRepository,
+	vocabIndexRepo database.VocabularyIndexRepository,
+	playlistRepo database.PlaylistRepository,
+	watchHistoryRepo database.WatchHistoryRepository,
+	versionRepo database.SubtitleVersionRepository,
+	pairRepo database.SentencePairRepository,
+	cueIndexRepo database.CueIndexRepository,
+	subtitles storage.BlobStore,
+	retention time.Duration,
+) *VideoTrashService {
+	return &VideoTrashService{
+		db:               db,
+		videoRepo:        videoRepo,
+		vocabIndexRepo:   vocabIndexRepo,
+		playlistRepo:     playlistRepo,
+		watchHistoryRepo: watchHistoryRepo,
+		versionRepo:      versionRepo,
+		pairRepo:         pairRepo,
+		cueIndexRepo:     cueIndexRepo,
+		subtitles:        subtitles,
+		retention:        retention,
+	}
+}
+
+// Retention returns how long videos stay in the trash
+func (s *VideoTrashService) Retention() time.Duration {
+	return s.retention
+}
+
+// Purge permanently deletes a video and cleans up its index rows, playlist entries,
+// watch history, learning list references, subtitle version history, sentence pairs, transcript index and subtitle files
+func (s *VideoTrashService) Purge(ctx context.Context, video *models.Video) error {
+	if err := s.vocabIndexRepo.DeleteByVideoID(ctx, video.ID); err != nil {
+		return fmt.Errorf("failed to delete vocabulary index: %w", err)
+	}
+
+	if err := s.playlistRepo.RemoveVideoFromAll(ctx, video.ID); err != nil {
+		return fmt.Errorf("failed to remove video from playlists: %w", err)
+	}
+
+	if err := s.watchHistoryRepo.DeleteByVideoID(ctx, video.ID); err != nil {
+		return fmt.Errorf("failed to delete watch history: %w", err)
+	}
+
+	if err := s.db.ClearLearningListVideo(ctx, video.ID); err != nil {
+		return fmt.Errorf("failed to clear learning list references: %w", err)
+	}
+
+	if err := s.versionRepo.DeleteByVideoID(ctx, video.ID); err != nil {
+		return fmt.Errorf("failed to delete subtitle versions: %w", err)
+	}
+
+	if err := s.pairRepo.DeleteByVideoID(ctx, video.ID); err != nil {
+		return fmt.Errorf("failed to delete sentence pairs: %w", err)
+	}
+
+	if err := s.cueIndexRepo.DeleteByVideoID(ctx, video.ID); err != nil {
+		return fmt.Errorf("failed to delete transcript index: %w", err)
+	}
+
+	if err := s.deleteSubtitleFiles(ctx, video); err != nil {
+		// The files can be cleaned up manually, so don't keep the video around because of them
+		log.Printf("Failed to delete subtitle files for video %s: %v", video.ID, err)
+	}
+
+	if err := s.videoRepo.Delete(ctx, video.ID); err != nil {
+		return fmt.Errorf("failed to delete video: %w", err)
+	}
+
+	log.Printf("Purged video %s (%s)", video.ID, video.Title)
+	return nil
+}
+
+// PurgeExpired purges every video that has been in the trash longer than the retention period
+func (s *VideoTrashService) PurgeExpired(ctx context.Context) (int, error) {
+	videos, err := s.videoRepo.GetTrashedBefore(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, video := range videos {
+		if err := s.Purge(ctx, video); err != nil {
+			log.Printf("Failed to purge video %s: %v", video.ID, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// Run purges expired videos on the given interval until the context is cancelled
+func (s *VideoTrashService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		purged, err := s.PurgeExpired(purgeCtx)
+		cancel()
+		if err != nil {
+			log.Printf("Trash purge failed: %v", err)
+		} else if purged > 0 {
+			log.Printf("Trash purge removed %d videos", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteSubtitleFiles removes the video's uploaded VTT files unless another video still uses them
+func (s *VideoTrashService) deleteSubtitleFiles(ctx context.Context, video *models.Video) error {
+	filenames := make(map[string]bool)
+	for _, track := range video.SubtitleTracks() {
+		if filename := storedSubtitleFilename(track.URL); filename != "" {
+			filenames[filename] = true
+		}
+	}
+	if len(filenames) == 0 {
+		return nil
+	}
+
+	// Check both active and trashed videos for other references to the same files
+	active, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	trashed, err := s.videoRepo.GetTrashed(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range append(active, trashed...) {
+		if other.ID == video.ID {
+			continue
+		}
+		for _, track := range other.SubtitleTracks() {
+			delete(filenames, storedSubtitleFilename(track.URL))
+		}
+	}
+
+	for filename := range filenames {
+		err := s.subtitles.Delete(ctx, filename)
+		if err != nil && !storage.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/utils"
+)
+
+// Vocabulary import modes
+const (
+	VocabularyImportReplace = "replace"  // The file becomes the whole dictionary, entries missing from it are removed
+	VocabularyImportMerge   = "merge"    // New headwords are added and existing ones updated, nothing is removed
+	VocabularyImportAddOnly = "add_only" // Only new headwords are added, existing entries are left as they are
+)
+
+// ValidVocabularyImportMode reports whether mode is a known import mode
+func ValidVocabularyImportMode(mode string) bool {
+	return mode == VocabularyImportReplace || mode == VocabularyImportMerge || mode == VocabularyImportAddOnly
+}
+
+// VocabularyImportService imports vocabulary files as new vocabulary versions. Entries that
+// keep their headword keep their ID, so references to them stay valid.
+type VocabularyImportService struct {
+	vocabRepo      database.VocabularyRepository
+	versionService *VocabularyVersionService
+}
+
+// NewVocabularyImportService creates a new vocabulary import service
+func NewVocabularyImportService(vocabRepo database.VocabularyRepository, versionService *VocabularyVersionService) *VocabularyImportService {
+	return &VocabularyImportService{
+		vocabRepo:      vocabRepo,
+		versionService: versionService,
+	}
+}
+
+// Plan compares imported entries with the active vocabulary and returns what importing them would do
+func (s *VocabularyImportService) Plan(ctx context.Context, incoming []*models.Vocabulary, mode string) (*models.VocabularyDiff, error) {
+	if !ValidVocabularyImportMode(mode) {
+		return nil, fmt.Errorf("unknown import mode %q", mode)
+	}
+
+	existing, err := s.vocabRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary: %w", err)
+	}
+	return DiffVocabulary(existing, incoming, mode), nil
+}
+
+// Import applies a planned import to the active vocabulary and publishes the result as a new
+// version. No version is made when the import changes nothing.
+func (s *VocabularyImportService) Import(ctx context.Context, diff *models.VocabularyDiff, opts PublishOptions) (*models.VocabularyVersion, error) {
+	if !diff.HasChanges() {
+		return nil, nil
+	}
+
+	// Edits made after the entries are read are replayed into the new version when it is activated
+	basedOn, revision, err := s.versionService.snapshotRevision(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary revision: %w", err)
+	}
+	opts.basedOn, opts.basedOnRevision = basedOn, revision
+
+	existing, err := s.vocabRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary: %w", err)
+	}
+
+	opts.Mode = diff.Mode
+	return s.versionService.Publish(ctx, ApplyVocabularyDiff(existing, diff), diff, opts)
+}
+
+// ApplyVocabularyDiff returns the entries that result from applying a diff to existing entries.
+// Entries are matched by their normalised headword, as DiffVocabulary matches them.
+func ApplyVocabularyDiff(existing []*models.Vocabulary, diff *models.VocabularyDiff) []*models.Vocabulary {
+	removed := make(map[string]bool, len(diff.Removed))
+	for _, vocab := range diff.Removed {
+		removed[utils.NormalizeMaori(vocab.Maori)] = true
+	}
+	changed := make(map[string]*models.Vocabulary, len(diff.Changed))
+	for _, change := range diff.Changed {
+		changed[utils.NormalizeMaori(change.Before.Maori)] = change.After
+	}
+
+	entries := make([]*models.Vocabulary, 0, len(existing)+len(diff.Added))
+	for _, vocab := range existing {
+		key := utils.NormalizeMaori(vocab.Maori)
+		if removed[key] {
+			continue
+		}
+		if after, ok := changed[key]; ok {
+			vocab = after
+		}
+		entries = append(entries, vocab)
+	}
+	return append(entries, diff.Added...)
+}
+
+// DiffVocabulary matches imported entries to existing ones by Māori headword and sorts them into
+// added, changed, unchanged and removed entries for the given mode. Headwords are compared as
+// search compares them, so "maori" updates the entry "Māori" rather than adding a second one.
+func DiffVocabulary(existing, incoming []*models.Vocabulary, mode string) *models.VocabularyDiff {
+	diff := &models.VocabularyDiff{
+		Mode:      mode,
+		Added:     []*models.Vocabulary{},
+		Changed:   []models.VocabularyChange{},
+		Unchanged: []*models.Vocabulary{},
+		Removed:   []*models.Vocabulary{},
+	}
+
+	byHeadword := make(map[string]*models.Vocabulary, len(existing))
+	for _, vocab := range existing {
+		byHeadword[utils.NormalizeMaori(vocab.Maori)] = vocab
+	}
+
+	seen := make(map[string]bool, len(incoming))
+	for _, vocab := range incoming {
+		key := utils.NormalizeMaori(vocab.Maori)
+		// ParseVocabularyCSV refuses files that repeat a headword; elsewhere the first entry wins
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		current, ok := byHeadword[key]
+		if !ok {
+			diff.Added = append(diff.Added, vocab)
+			continue
+		}
+		if current.SameContent(vocab) {
+			diff.Unchanged = append(diff.Unchanged, current)
+			continue
+		}
+
+		// The updated entry keeps its ID so references to it stay valid
+		after := *vocab
+		after.ID = current.ID
+		change := models.VocabularyChange{Before: current, After: &after}
+		if mode == VocabularyImportAddOnly {
+			diff.Skipped = append(diff.Skipped, change)
+		} else {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	if mode == VocabularyImportReplace {
+		for _, vocab := range existing {
+			if !seen[utils.NormalizeMaori(vocab.Maori)] {
+				diff.Removed = append(diff.Removed, vocab)
+			}
+		}
+		sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Maori < diff.Removed[j].Maori })
+	}
+
+	return diff
+}
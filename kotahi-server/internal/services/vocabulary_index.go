@@ -0,0 +1,798 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/models"
+	"video-player-backend/internal/storage"
+	"video-player-backend/internal/utils"
+)
+
+// ReindexResult summarises a full vocabulary reindex
+type ReindexResult struct {
+	ProcessedVideos int `json:"processed_videos"`
+	TotalIndexed    int `json:"total_indexed"`
+	TotalVideos     int `json:"total_videos"`
+	TotalVocabulary int `json:"total_vocabulary"`
+}
+
+// VocabularyIndexService indexes vocabulary occurrences in the Māori subtitle tracks of videos,
+// aligns them with the English tracks and keeps the full-text transcript index
+type VocabularyIndexService struct {
+	vocabRepo      database.VocabularyRepository
+	vocabIndexRepo database.VocabularyIndexRepository
+	videoRepo      database.VideoRepository
+	pairRepo       database.SentencePairRepository
+	cueIndexRepo   database.CueIndexRepository
+	subtitles      storage.BlobStore
+	active         *database.ActiveVocabulary
+
+	indexerMu      sync.Mutex // Guards indexer and indexerVersion
+	indexer        *utils.VocabularyIndexer
+	indexerVersion string // Vocabulary version the cached indexer was built from
+}
+
+// NewVocabularyIndexService creates a new vocabulary index service
+func NewVocabularyIndexService(
+	vocabRepo database.VocabularyRepository,
+	vocabIndexRepo database.VocabularyIndexRepository,
+	videoRepo database.VideoRepository,
+	pairRepo database.SentencePairRepository,
+	cueIndexRepo database.CueIndexRepository,
+	subtitles storage.BlobStore,
+	active *database.ActiveVocabulary,
+) *VocabularyIndexService {
+	return &VocabularyIndexService{
+		vocabRepo:      vocabRepo,
+		vocabIndexRepo: vocabIndexRepo,
+		videoRepo:      videoRepo,
+		pairRepo:       pairRepo,
+		cueIndexRepo:   cueIndexRepo,
+		subtitles:      subtitles,
+		active:         active,
+	}
+}
+
+// SubtitleURLPrefix is the URL path uploaded subtitle files are served under
+const SubtitleURLPrefix = "/api/v1/uploads/vtt/"
+
+// SubtitleURL returns the URL of an uploaded subtitle file
+func SubtitleURL(filename string) string {
+	return SubtitleURLPrefix + filename
+}
+
+// subtitleFilename extracts the uploaded VTT filename from a subtitle URL or path
+func subtitleFilename(subtitle string) string {
+	if subtitle == "" {
+		return ""
+	}
+	if strings.HasPrefix(subtitle, SubtitleURLPrefix) {
+		return strings.TrimPrefix(subtitle, SubtitleURLPrefix)
+	}
+	return path.Base(subtitle)
+}
+
+// storedSubtitleFilename returns the filename of a subtitle kept in the subtitle store, or an
+// empty string for subtitles hosted elsewhere, which were never uploaded here and must never be
+// mistaken for a stored file with the same name
+func storedSubtitleFilename(subtitle string) string {
+	if strings.HasPrefix(subtitle, "http://") || strings.HasPrefix(subtitle, "https://") {
+		return ""
+	}
+	return subtitleFilename(subtitle)
+}
+
+// Subtitles returns the store uploaded subtitle files are kept in
+func (s *VocabularyIndexService) Subtitles() storage.BlobStore {
+	return s.subtitles
+}
+
+// ReadSubtitle reads an uploaded subtitle file referenced by a URL, path or filename.
+// A missing file, or a subtitle hosted elsewhere, is reported as storage.ErrNotFound.
+func (s *VocabularyIndexService) ReadSubtitle(ctx context.Context, subtitle string) (string, error) {
+	filename, err := uploadedSubtitle(subtitle)
+	if err != nil {
+		return "", err
+	}
+
+	obj, _, err := s.subtitles.Get(ctx, filename)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	content, err := io.ReadAll(obj)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// SaveSubtitle stores a subtitle file and returns its URL
+func (s *VocabularyIndexService) SaveSubtitle(ctx context.Context, filename string, content []byte) (string, error) {
+	if _, err := s.subtitles.Put(ctx, filename, bytes.NewReader(content)); err != nil {
+		return "", err
+	}
+	return SubtitleURL(filename), nil
+}
+
+// ReplaceSubtitle overwrites an uploaded subtitle file referenced by a URL, path or filename.
+// The store replaces the file atomically, so readers never see a partially written track.
+// Subtitles hosted elsewhere are reported as storage.ErrNotFound.
+func (s *VocabularyIndexService) ReplaceSubtitle(ctx context.Context, subtitle string, content []byte) error {
+	filename, err := uploadedSubtitle(subtitle)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.subtitles.Put(ctx, filename, bytes.NewReader(content))
+	return err
+}
+
+// uploadedSubtitle returns the stored filename of a subtitle reference, or storage.ErrNotFound
+// for a subtitle hosted elsewhere
+func uploadedSubtitle(subtitle string) (string, error) {
+	if subtitle == "" {
+		return "", fmt.Errorf("empty subtitle reference")
+	}
+	filename := storedSubtitleFilename(subtitle)
+	if filename == "" {
+		return "", fmt.Errorf("%w: %s is not an uploaded subtitle", storage.ErrNotFound, subtitle)
+	}
+	return filename, nil
+}
+
+// FindSubtitlesByContent returns the stored subtitle files with exactly the given content
+func (s *VocabularyIndexService) FindSubtitlesByContent(ctx context.Context, content []byte) ([]*storage.ObjectInfo, error) {
+	return storage.FindByHash(ctx, s.subtitles, "", storage.HashBytes(content))
+}
+
+// RemoveUnusedSubtitle deletes an uploaded subtitle file unless a video, active or trashed, still uses it
+func (s *VocabularyIndexService) RemoveUnusedSubtitle(ctx context.Context, subtitle string) error {
+	filename := storedSubtitleFilename(subtitle)
+	if filename == "" {
+		return nil
+	}
+
+	used, err := s.subtitleUsed(ctx, filename, "", "")
+	if err != nil || used {
+		return err
+	}
+
+	err = s.subtitles.Delete(ctx, filename)
+	if err != nil && !storage.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// SubtitleShared reports whether an uploaded subtitle file is used by any track, of an active or
+// trashed video, other than the given one
+func (s *VocabularyIndexService) SubtitleShared(ctx context.Context, subtitle, videoID, trackID string) (bool, error) {
+	filename := storedSubtitleFilename(subtitle)
+	if filename == "" {
+		return false, nil
+	}
+	return s.subtitleUsed(ctx, filename, videoID, trackID)
+}
+
+// subtitleUsed reports whether a track of an active or trashed video uses the stored file, not
+// counting the track of the given video
+func (s *VocabularyIndexService) subtitleUsed(ctx context.Context, filename, videoID, trackID string) (bool, error) {
+	active, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return false, err
+	}
+	trashed, err := s.videoRepo.GetTrashed(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, video := range append(active, trashed...) {
+		for _, track := range video.SubtitleTracks() {
+			if video.ID == videoID && track.ID == trackID {
+				continue
+			}
+			if storedSubtitleFilename(track.URL) == filename {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// NewIndexer returns a vocabulary indexer for the active vocabulary. The indexer is built once per
+// vocabulary version and shared until another version is activated or InvalidateIndexer is called.
+func (s *VocabularyIndexService) NewIndexer(ctx context.Context) (*utils.VocabularyIndexer, error) {
+	version := s.active.VersionID(ctx)
+
+	s.indexerMu.Lock()
+	defer s.indexerMu.Unlock()
+	if s.indexer != nil && s.indexerVersion == version {
+		return s.indexer, nil
+	}
+
+	vocabularies, err := s.vocabRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary: %w", err)
+	}
+	s.indexer = utils.NewVocabularyIndexer(vocabularies)
+	s.indexerVersion = version
+	return s.indexer, nil
+}
+
+// InvalidateIndexer drops the cached indexer after the active vocabulary is edited in place
+func (s *VocabularyIndexService) InvalidateIndexer() {
+	s.indexerMu.Lock()
+	s.indexer = nil
+	s.indexerMu.Unlock()
+}
+
+// IndexVideo indexes the video's Māori track and saves the resulting index entries.
+// Videos without a Māori track are skipped. Existing entries are not removed.
+func (s *VocabularyIndexService) IndexVideo(ctx context.Context, indexer *utils.VocabularyIndexer, video *models.Video) (int, error) {
+	return s.indexVideoInto(ctx, indexer, video, s.vocabIndexRepo)
+}
+
+// indexVideoInto indexes the video's Māori track and saves the entries to the given index
+func (s *VocabularyIndexService) indexVideoInto(ctx context.Context, indexer *utils.VocabularyIndexer, video *models.Video, target database.VocabularyIndexRepository) (int, error) {
+	indexes, err := s.videoIndex(ctx, indexer, video)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(indexes) > 0 {
+		if err := target.CreateBatch(ctx, indexes); err != nil {
+			return 0, fmt.Errorf("failed to save indexes: %w", err)
+		}
+	}
+
+	return len(indexes), nil
+}
+
+// videoIndex returns the index entries the indexer finds in the video's Māori track, without saving them
+func (s *VocabularyIndexService) videoIndex(ctx context.Context, indexer *utils.VocabularyIndexer, video *models.Video) ([]*models.VocabularyIndex, error) {
+	transcriptLines, err := s.maoriTranscript(ctx, video)
+	if err != nil {
+		return nil, err
+	}
+	return transcriptIndex(indexer, video, transcriptLines)
+}
+
+// maoriTranscript reads the lines of the video's Māori track, or none when it has no Māori track
+func (s *VocabularyIndexService) maoriTranscript(ctx context.Context, video *models.Video) ([]utils.TranscriptLine, error) {
+	track := video.MaoriTrack()
+	if track == nil {
+		return nil, nil
+	}
+
+	content, err := s.ReadSubtitle(ctx, track.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subtitle track %s: %w", track.URL, err)
+	}
+
+	transcriptLines, err := utils.ParseVTTToLines(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subtitle track %s: %w", track.URL, err)
+	}
+	return transcriptLines, nil
+}
+
+// transcriptIndex returns the index entries the indexer finds in the lines of a video's Māori track
+func transcriptIndex(indexer *utils.VocabularyIndexer, video *models.Video, transcriptLines []utils.TranscriptLine) ([]*models.VocabularyIndex, error) {
+	indexes, err := indexer.IndexTranscript(video.ID, transcriptLines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index vocabulary: %w", err)
+	}
+	return indexes, nil
+}
+
+// ReindexVideo replaces the index entries, sentence pairs and transcript index of a single video
+func (s *VocabularyIndexService) ReindexVideo(ctx context.Context, video *models.Video) (int, error) {
+	if err := s.vocabIndexRepo.DeleteByVideoID(ctx, video.ID); err != nil {
+		return 0, fmt.Errorf("failed to clear existing indexes: %w", err)
+	}
+
+	s.SyncTranscripts(ctx, video)
+
+	indexer, err := s.NewIndexer(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.IndexVideo(ctx, indexer, video)
+}
+
+// ReindexAll indexes every video with the vocabulary into an empty index and refreshes every
+// video's sentence pairs and transcript index. The active index is rebuilt this way in a new
+// collection, so search keeps using the old one until the new one replaces it.
+// When run as a job it reports how many videos it has been through.
+func (s *VocabularyIndexService) ReindexAll(ctx context.Context, vocabularies []*models.Vocabulary, target database.VocabularyIndexRepository) (*ReindexResult, error) {
+	videos, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+	indexer := utils.NewVocabularyIndexer(vocabularies)
+
+	result := &ReindexResult{
+		TotalVideos:     len(videos),
+		TotalVocabulary: indexer.Len(),
+	}
+
+	for i, video := range videos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ReportProgress(ctx, i, len(videos), video.Title)
+
+		// Every video gets a transcript index, not just those with a Māori track
+		s.SyncTranscripts(ctx, video)
+
+		if video.MaoriTrack() == nil {
+			continue // Skip videos without a Māori subtitle track
+		}
+
+		indexed, err := s.indexVideoInto(ctx, indexer, video, target)
+		if err != nil {
+			// Skip videos with missing or invalid subtitles so the rest still get indexed
+			log.Printf("Skipping video %s during reindex: %v", video.ID, err)
+			continue
+		}
+
+		result.TotalIndexed += indexed
+		result.ProcessedVideos++
+	}
+	ReportProgress(ctx, len(videos), len(videos), "")
+
+	return result, nil
+}
+
+// BuildIndex indexes every video with the given vocabulary into an empty index, such as the index
+// of a vocabulary version that isn't active yet. Transcripts are left alone.
+func (s *VocabularyIndexService) BuildIndex(ctx context.Context, vocabularies []*models.Vocabulary, target database.VocabularyIndexRepository) (*ReindexResult, error) {
+	return s.indexInto(ctx, utils.NewVocabularyIndexer(vocabularies), target)
+}
+
+// BuildIndexFrom builds the index of a vocabulary version made by applying diff to the active
+// vocabulary. The active index is copied into the empty target collection without the entries of
+// changed and removed items, then each video that mentions an added, changed or removed item is
+// indexed again with the new vocabulary, as in IndexVocabulary.
+func (s *VocabularyIndexService) BuildIndexFrom(ctx context.Context, vocabularies []*models.Vocabulary, diff *models.VocabularyDiff, target database.VocabularyIndexRepository, collection string) (*ReindexResult, error) {
+	var stale []string
+	changed := append([]*models.Vocabulary{}, diff.Added...)
+	for _, change := range diff.Changed {
+		stale = append(stale, change.Before.ID)
+		changed = append(changed, change.Before, change.After)
+	}
+	for _, vocab := range diff.Removed {
+		stale = append(stale, vocab.ID)
+		changed = append(changed, vocab)
+	}
+
+	if err := s.vocabIndexRepo.CopyTo(ctx, collection, stale); err != nil {
+		return nil, fmt.Errorf("failed to copy the active index: %w", err)
+	}
+
+	result, err := s.reindexMentioning(ctx, utils.NewVocabularyIndexer(vocabularies), utils.WordsOf(changed...), target)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.TotalIndexed, err = target.Count(ctx); err != nil {
+		return nil, fmt.Errorf("failed to count index entries: %w", err)
+	}
+	result.TotalVocabulary = len(vocabularies)
+	return result, nil
+}
+
+// IndexVocabulary updates the active index after vocabulary items are added, edited or deleted.
+// changed holds each item as it was before the change and as it is after, so an added item is
+// given once, an edited one twice and a deleted one once. A change can also alter how other words
+// are matched, such as a new headword that was an inflection of another, so rather than looking
+// for the items alone, each video whose Māori track mentions one of their words is indexed again
+// in full. The entries of other videos can't have changed and are left alone.
+func (s *VocabularyIndexService) IndexVocabulary(ctx context.Context, changed ...*models.Vocabulary) (*ReindexResult, error) {
+	ids := make([]string, len(changed))
+	for i, vocab := range changed {
+		ids[i] = vocab.ID
+	}
+	if err := s.vocabIndexRepo.DeleteByVocabularyIDs(ctx, ids); err != nil {
+		return nil, fmt.Errorf("failed to clear existing indexes: %w", err)
+	}
+
+	indexer, err := s.NewIndexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.reindexMentioning(ctx, indexer, utils.WordsOf(changed...), s.vocabIndexRepo)
+}
+
+// reindexMentioning replaces the entries in the target index of every video whose Māori track
+// mentions one of the words with those the indexer finds in it
+func (s *VocabularyIndexService) reindexMentioning(ctx context.Context, indexer *utils.VocabularyIndexer, words utils.VocabularyWords, target database.VocabularyIndexRepository) (*ReindexResult, error) {
+	videos, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+
+	result := &ReindexResult{
+		TotalVideos:     len(videos),
+		TotalVocabulary: indexer.Len(),
+	}
+
+	for i, video := range videos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ReportProgress(ctx, i, len(videos), video.Title)
+
+		transcriptLines, err := s.maoriTranscript(ctx, video)
+		if err != nil {
+			log.Printf("Skipping video %s while updating vocabulary index: %v", video.ID, err)
+			continue
+		}
+		if !words.Mentioned(transcriptLines) {
+			continue
+		}
+
+		indexes, err := transcriptIndex(indexer, video, transcriptLines)
+		if err != nil {
+			log.Printf("Skipping video %s while updating vocabulary index: %v", video.ID, err)
+			continue
+		}
+		if err := target.DeleteByVideoID(ctx, video.ID); err != nil {
+			return nil, fmt.Errorf("failed to clear indexes of video %s: %w", video.ID, err)
+		}
+		if len(indexes) > 0 {
+			if err := target.CreateBatch(ctx, indexes); err != nil {
+				return nil, fmt.Errorf("failed to save indexes of video %s: %w", video.ID, err)
+			}
+		}
+
+		result.TotalIndexed += len(indexes)
+		result.ProcessedVideos++
+	}
+	ReportProgress(ctx, len(videos), len(videos), "")
+
+	return result, nil
+}
+
+// indexInto indexes every video with the indexer and adds the entries to the target index
+func (s *VocabularyIndexService) indexInto(ctx context.Context, indexer *utils.VocabularyIndexer, target database.VocabularyIndexRepository) (*ReindexResult, error) {
+	videos, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+
+	result := &ReindexResult{
+		TotalVideos:     len(videos),
+		TotalVocabulary: indexer.Len(),
+	}
+
+	for i, video := range videos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ReportProgress(ctx, i, len(videos), video.Title)
+		if video.MaoriTrack() == nil {
+			continue
+		}
+
+		indexed, err := s.indexVideoInto(ctx, indexer, video, target)
+		if err != nil {
+			log.Printf("Skipping video %s while building vocabulary index: %v", video.ID, err)
+			continue
+		}
+
+		result.TotalIndexed += indexed
+		result.ProcessedVideos++
+	}
+	ReportProgress(ctx, len(videos), len(videos), "")
+
+	return result, nil
+}
+
+// RefreshIndex brings an index up to date with the videos' current subtitles, such as the index of a
+// vocabulary version that is activated again after subtitles changed. Every video is indexed again,
+// and its entries are replaced only where they differ from those stored. Entries of videos that no
+// longer exist are removed; trashed videos keep theirs until they are purged.
+func (s *VocabularyIndexService) RefreshIndex(ctx context.Context, indexer *utils.VocabularyIndexer, target database.VocabularyIndexRepository) (*ReindexResult, error) {
+	videos, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+	trashed, err := s.videoRepo.GetTrashed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed videos: %w", err)
+	}
+
+	result := &ReindexResult{
+		TotalVideos:     len(videos),
+		TotalVocabulary: indexer.Len(),
+	}
+	known := make(map[string]bool, len(videos)+len(trashed))
+	for _, video := range trashed {
+		known[video.ID] = true
+	}
+
+	for i, video := range videos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ReportProgress(ctx, i, len(videos), video.Title)
+		known[video.ID] = true
+
+		expected, err := s.videoIndex(ctx, indexer, video)
+		if err != nil {
+			log.Printf("Skipping video %s while refreshing vocabulary index: %v", video.ID, err)
+			continue
+		}
+		stored, err := target.GetByVideoID(ctx, video.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get indexes of video %s: %w", video.ID, err)
+		}
+
+		drift := models.VocabularyIndexDrift{}
+		compareVideoIndex(&drift, expected, stored)
+		if len(drift.Missing)+len(drift.Extra)+len(drift.Outdated) == 0 {
+			continue
+		}
+
+		if err := target.DeleteByVideoID(ctx, video.ID); err != nil {
+			return nil, fmt.Errorf("failed to clear indexes of video %s: %w", video.ID, err)
+		}
+		if len(expected) > 0 {
+			if err := target.CreateBatch(ctx, expected); err != nil {
+				return nil, fmt.Errorf("failed to save indexes of video %s: %w", video.ID, err)
+			}
+		}
+		result.TotalIndexed += len(expected)
+		result.ProcessedVideos++
+	}
+	ReportProgress(ctx, len(videos), len(videos), "")
+
+	indexedVideos, err := target.GetVideoIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexed videos: %w", err)
+	}
+	for _, id := range indexedVideos {
+		if known[id] {
+			continue
+		}
+		if err := target.DeleteByVideoID(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to clear indexes of video %s: %w", id, err)
+		}
+		result.ProcessedVideos++
+	}
+
+	return result, nil
+}
+
+// CheckIndex compares the active index with what indexing every video again would produce and
+// reports where they differ. Trashed videos keep their entries until they are purged, so they
+// are neither checked nor reported.
+func (s *VocabularyIndexService) CheckIndex(ctx context.Context) (*models.VocabularyIndexCheck, error) {
+	indexer, err := s.NewIndexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	videos, err := s.videoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+	trashed, err := s.videoRepo.GetTrashed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed videos: %w", err)
+	}
+
+	check := &models.VocabularyIndexCheck{
+		OrphanedVideos: []string{},
+		Videos:         []models.VocabularyIndexDrift{},
+		CheckedAt:      time.Now(),
+	}
+	known := make(map[string]bool, len(videos)+len(trashed))
+	for _, video := range trashed {
+		known[video.ID] = true
+	}
+
+	for _, video := range videos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		known[video.ID] = true
+		check.CheckedVideos++
+
+		drift := models.VocabularyIndexDrift{VideoID: video.ID, Title: video.Title}
+		stored, err := s.vocabIndexRepo.GetByVideoID(ctx, video.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get indexes of video %s: %w", video.ID, err)
+		}
+		expected, err := s.videoIndex(ctx, indexer, video)
+		if err != nil {
+			drift.Error = err.Error()
+		} else {
+			compareVideoIndex(&drift, expected, stored)
+		}
+
+		if drift.Error == "" && len(drift.Missing)+len(drift.Extra)+len(drift.Outdated) == 0 {
+			continue
+		}
+		check.DriftedVideos++
+		check.Missing += len(drift.Missing)
+		check.Extra += len(drift.Extra)
+		check.Outdated += len(drift.Outdated)
+		check.Videos = append(check.Videos, drift)
+	}
+
+	indexedVideos, err := s.vocabIndexRepo.GetVideoIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexed videos: %w", err)
+	}
+	for _, id := range indexedVideos {
+		if !known[id] {
+			check.OrphanedVideos = append(check.OrphanedVideos, id)
+		}
+	}
+
+	check.InSync = check.DriftedVideos == 0 && len(check.OrphanedVideos) == 0
+	return check, nil
+}
+
+// compareVideoIndex records which of a video's stored index entries are missing, extra or out of
+// date compared with the expected entries. Entries are matched by vocabulary item and line.
+func compareVideoIndex(drift *models.VocabularyIndexDrift, expected, stored []*models.VocabularyIndex) {
+	key := func(index *models.VocabularyIndex) string {
+		return fmt.Sprintf("%s:%d", index.VocabularyID, index.LineNumber)
+	}
+	describe := func(index *models.VocabularyIndex) string {
+		return fmt.Sprintf("%s (line %d)", index.Vocabulary, index.LineNumber)
+	}
+
+	want := make(map[string]*models.VocabularyIndex, len(expected))
+	for _, index := range expected {
+		want[key(index)] = index
+	}
+
+	seen := make(map[string]bool, len(stored))
+	for _, index := range stored {
+		k := key(index)
+		expectedIndex, ok := want[k]
+		if !ok || seen[k] {
+			drift.Extra = append(drift.Extra, describe(index))
+			continue
+		}
+		seen[k] = true
+		if !sameIndexEntry(expectedIndex, index) {
+			drift.Outdated = append(drift.Outdated, describe(index))
+		}
+	}
+
+	for _, index := range expected {
+		if !seen[key(index)] {
+			drift.Missing = append(drift.Missing, describe(index))
+		}
+	}
+}
+
+// sameIndexEntry reports whether two index entries for the same item and line have the same details
+func sameIndexEntry(a, b *models.VocabularyIndex) bool {
+	return a.Vocabulary == b.Vocabulary && a.MatchedForm == b.MatchedForm && a.MatchType == b.MatchType &&
+		a.English == b.English && a.Description == b.Description && a.Transcript == b.Transcript &&
+		a.StartTime == b.StartTime && a.EndTime == b.EndTime
+}
+
+// SyncTranscripts refreshes the sentence pairs and the transcript index of a video after its
+// tracks change. Neither is needed for the vocabulary index, so failures are only logged.
+func (s *VocabularyIndexService) SyncTranscripts(ctx context.Context, video *models.Video) {
+	if _, err := s.AlignVideo(ctx, video); err != nil {
+		log.Printf("Failed to align transcripts of video %s: %v", video.ID, err)
+	}
+	if _, err := s.IndexTranscripts(ctx, video); err != nil {
+		log.Printf("Failed to index transcripts of video %s: %v", video.ID, err)
+	}
+}
+
+// IndexTranscripts replaces the video's entries in the full-text transcript index with every cue
+// of its uploaded subtitle tracks, in all languages. Tracks hosted elsewhere are skipped.
+func (s *VocabularyIndexService) IndexTranscripts(ctx context.Context, video *models.Video) (int, error) {
+	var cues []*models.IndexedCue
+	for _, track := range video.SubtitleTracks() {
+		if strings.HasPrefix(track.URL, "http://") || strings.HasPrefix(track.URL, "https://") {
+			continue
+		}
+
+		doc, err := s.readDocument(ctx, &track)
+		if err != nil {
+			// Keep the video's other tracks searchable
+			log.Printf("Skipping track %s of video %s in transcript index: %v", track.ID, video.ID, err)
+			continue
+		}
+
+		for _, cue := range doc.Cues {
+			text := cue.PlainText()
+			if text == "" {
+				continue
+			}
+			cues = append(cues, &models.IndexedCue{
+				VideoID:   video.ID,
+				TrackID:   track.ID,
+				Language:  track.Language,
+				CueIndex:  cue.Index,
+				StartTime: cue.StartTime,
+				EndTime:   cue.EndTime,
+				Text:      text,
+				Tokens:    utils.TokenizeWords(text),
+
+				TokensVersion: models.CueTokensVersion,
+			})
+		}
+	}
+
+	if err := s.cueIndexRepo.ReplaceForVideo(ctx, video.ID, cues); err != nil {
+		return 0, fmt.Errorf("failed to save transcript index: %w", err)
+	}
+	return len(cues), nil
+}
+
+// AlignVideo pairs the cues of the video's Māori and English tracks by time and stores the
+// result as sentence pairs, replacing any earlier alignment. Videos without both tracks have
+// their pairs removed.
+func (s *VocabularyIndexService) AlignVideo(ctx context.Context, video *models.Video) (int, error) {
+	maori, english := video.MaoriTrack(), video.EnglishTrack()
+	if maori == nil || english == nil {
+		return 0, s.pairRepo.DeleteByVideoID(ctx, video.ID)
+	}
+
+	maoriDoc, err := s.readDocument(ctx, maori)
+	if err != nil {
+		return 0, err
+	}
+	englishDoc, err := s.readDocument(ctx, english)
+	if err != nil {
+		return 0, err
+	}
+
+	var pairs []*models.SentencePair
+	for _, alignment := range utils.AlignCues(maoriDoc.Cues, englishDoc.Cues) {
+		pairs = append(pairs, &models.SentencePair{
+			VideoID:        video.ID,
+			Index:          len(pairs) + 1,
+			StartTime:      alignment.StartTime,
+			EndTime:        alignment.EndTime,
+			Maori:          utils.JoinCueText(maoriDoc.Cues, alignment.Source),
+			English:        utils.JoinCueText(englishDoc.Cues, alignment.Target),
+			MaoriCues:      alignment.Source,
+			EnglishCues:    alignment.Target,
+			MaoriTrackID:   maori.ID,
+			EnglishTrackID: english.ID,
+		})
+	}
+
+	if err := s.pairRepo.ReplaceForVideo(ctx, video.ID, pairs); err != nil {
+		return 0, fmt.Errorf("failed to save sentence pairs: %w", err)
+	}
+	return len(pairs), nil
+}
+
+// readDocument reads and parses a track's subtitle file
+func (s *VocabularyIndexService) readDocument(ctx context.Context, track *models.SubtitleTrack) (*utils.SubtitleDocument, error) {
+	content, err := s.ReadSubtitle(ctx, track.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subtitle track %s: %w", track.URL, err)
+	}
+
+	doc, err := utils.ParseWebVTT(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subtitle track %s: %w", track.URL, err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,634 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"video-player-backend/internal/database"
+	"video-player-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Vocabulary version job types
+const (
+	JobBuildVocabularyVersion = "build_vocabulary_version" // Build the index of a new version, params: version_id and activate
+	JobRefreshVocabularyIndex = "refresh_vocabulary_index" // Bring an activated version's index up to date, params: version_id
+)
+
+// vocabularyBuildTimeout limits how long building the index of a new vocabulary version may take
+const vocabularyBuildTimeout = 30 * time.Minute
+
+// vocabularyVersionsKept is how many of the newest versions are kept for rollback.
+// The active version and the one before it are always kept.
+const vocabularyVersionsKept = 10
+
+// Vocabulary version errors
+var (
+	ErrVocabularyVersionNotReady = errors.New("vocabulary version is not ready to be activated")
+	ErrNoPreviousVocabulary      = errors.New("there is no previous vocabulary version to roll back to")
+)
+
+// PublishOptions describes a new vocabulary version
+type PublishOptions struct {
+	Source           string
+	Mode             string
+	Filename         string
+	Note             string
+	PublishedBy      string
+	PublishedByEmail string
+	Activate         bool // Activate the version as soon as its index is built
+
+	basedOn         string // Version the entries were read from, the active one if empty
+	basedOnRevision int    // Last edit of basedOn the entries include
+}
+
+// VocabularyVersionService keeps the vocabulary as versioned snapshots. Each version has its
+// own entries and index, built by a background job, and activating a version swaps both at once.
+type VocabularyVersionService struct {
+	db           *database.MongoDB
+	versionRepo  database.VocabularyVersionRepository
+	vocabRepo    database.VocabularyRepository
+	indexService *VocabularyIndexService
+	jobs         *JobRunner
+}
+
+// NewVocabularyVersionService creates a new vocabulary version service
+func NewVocabularyVersionService(db *database.MongoDB, versionRepo database.VocabularyVersionRepository, vocabRepo database.VocabularyRepository, indexService *VocabularyIndexService, jobs *JobRunner) *VocabularyVersionService {
+	return &VocabularyVersionService{
+		db:           db,
+		versionRepo:  versionRepo,
+		vocabRepo:    vocabRepo,
+		indexService: indexService,
+		jobs:         jobs,
+	}
+}
+
+// RegisterJobs sets how the runner runs vocabulary version builds
+func (s *VocabularyVersionService) RegisterJobs(runner *JobRunner) {
+	runner.Register(JobBuildVocabularyVersion, JobType{
+		Run:         s.runBuild,
+		MaxAttempts: 3,
+		Timeout:     vocabularyBuildTimeout,
+		Failed:      s.buildFailed,
+	})
+	runner.Register(JobRefreshVocabularyIndex, JobType{
+		Run:         s.runRefresh,
+		MaxAttempts: 3,
+		Timeout:     indexJobTimeout,
+	})
+	runner.Register(JobReindexVocabulary, JobType{
+		Run:         s.runReindex,
+		MaxAttempts: 3,
+		Timeout:     indexJobTimeout,
+	})
+}
+
+// Init records the vocabulary that existed before versioning as version 1, and fails builds
+// whose job is gone so they aren't left building forever. Builds whose job is still queued
+// carry on once the job runner starts.
+func (s *VocabularyVersionService) Init(ctx context.Context) error {
+	versions, err := s.versionRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) == 0 {
+		entries, err := s.vocabRepo.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		version := &models.VocabularyVersion{
+			Number:          1,
+			Status:          models.VocabularyVersionReady,
+			Source:          "initial",
+			EntryCount:      len(entries),
+			Collection:      database.DefaultVocabularyCollection,
+			IndexCollection: database.DefaultVocabularyIndexCollection,
+			CreatedAt:       now,
+			ReadyAt:         &now,
+		}
+		if err := s.versionRepo.Create(ctx, version); err != nil {
+			return err
+		}
+		_, err = s.Activate(ctx, version.ID)
+		return err
+	}
+
+	for _, version := range versions {
+		if version.Status != models.VocabularyVersionBuilding {
+			continue
+		}
+		if version.BuildJobID == "" {
+			s.fail(ctx, version, fmt.Errorf("interrupted by a server restart"))
+			continue
+		}
+		if job, err := s.jobs.Get(ctx, version.BuildJobID); err != nil || job.Finished() {
+			s.fail(ctx, version, fmt.Errorf("the build job stopped without finishing the version"))
+		}
+	}
+	return nil
+}
+
+// List returns every version, newest first, with the active one marked
+func (s *VocabularyVersionService) List(ctx context.Context) ([]*models.VocabularyVersion, error) {
+	versions, err := s.versionRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	activeID := s.db.ActiveVocabulary.VersionID(ctx)
+	for _, version := range versions {
+		version.Active = version.ID == activeID
+	}
+	return versions, nil
+}
+
+// Get returns a version with its active flag set
+func (s *VocabularyVersionService) Get(ctx context.Context, id string) (*models.VocabularyVersion, error) {
+	version, err := s.versionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	version.Active = version.ID == s.db.ActiveVocabulary.VersionID(ctx)
+	return version, nil
+}
+
+// Entries returns a version's vocabulary entries
+func (s *VocabularyVersionService) Entries(ctx context.Context, version *models.VocabularyVersion) ([]*models.Vocabulary, error) {
+	return database.NewVocabularyRepositoryForVersion(s.db, version).GetAll(ctx)
+}
+
+// Publish saves entries as a new version and queues a job to build its index. Entries without
+// an ID are given one; existing IDs are kept so references stay valid.
+func (s *VocabularyVersionService) Publish(ctx context.Context, entries []*models.Vocabulary, diff *models.VocabularyDiff, opts PublishOptions) (*models.VocabularyVersion, error) {
+	number, err := s.versionRepo.NextNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to number vocabulary version: %w", err)
+	}
+
+	version := &models.VocabularyVersion{
+		Number:           number,
+		Status:           models.VocabularyVersionBuilding,
+		Source:           opts.Source,
+		Mode:             opts.Mode,
+		Filename:         opts.Filename,
+		Note:             opts.Note,
+		BasedOn:          opts.basedOn,
+		BasedOnRevision:  opts.basedOnRevision,
+		EntryCount:       len(entries),
+		PublishedBy:      opts.PublishedBy,
+		PublishedByEmail: opts.PublishedByEmail,
+		CreatedAt:        time.Now(),
+	}
+	if version.BasedOn == "" {
+		version.BasedOn = s.db.ActiveVocabulary.VersionID(ctx)
+	}
+	// The ID goes in the collection names too, so no two versions can ever write to the same collection
+	version.GenerateID()
+	version.Collection = fmt.Sprintf("vocabulary_v%d_%s", number, version.ID)
+	version.IndexCollection = fmt.Sprintf("vocabulary_index_v%d_%s", number, version.ID)
+	if diff != nil {
+		version.Added = len(diff.Added)
+		version.Changed = len(diff.Changed)
+		version.Removed = len(diff.Removed)
+	}
+
+	// The version and its build job refer to each other, so both IDs are set before either is saved
+	job := &models.Job{
+		Type: JobBuildVocabularyVersion,
+		Params: map[string]string{
+			"version_id": version.ID,
+			"activate":   strconv.FormatBool(opts.Activate),
+		},
+		CreatedBy: opts.PublishedBy,
+	}
+	job.GenerateID()
+	version.BuildJobID = job.ID
+
+	if err := s.versionRepo.Create(ctx, version); err != nil {
+		return nil, fmt.Errorf("failed to create vocabulary version: %w", err)
+	}
+
+	if err := database.NewVocabularyRepositoryForVersion(s.db, version).CreateBatch(ctx, entries); err != nil {
+		s.fail(ctx, version, err)
+		return nil, fmt.Errorf("failed to save vocabulary version: %w", err)
+	}
+
+	if err := s.jobs.Enqueue(ctx, job); err != nil {
+		s.fail(ctx, version, err)
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// Activate makes a ready version the live vocabulary. Its index was built from the subtitles as
+// they were then, and subtitles edited since only reindexed the version that was active at the
+// time, so the index is marked stale and a job is queued to bring it up to date.
+func (s *VocabularyVersionService) Activate(ctx context.Context, id string) (*models.VocabularyVersion, error) {
+	version, err := s.versionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status != models.VocabularyVersionReady {
+		return nil, ErrVocabularyVersionNotReady
+	}
+
+	// Entries edited in the version this one was made from after its entries were read would be lost
+	replayed, err := s.replayEdits(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay vocabulary edits: %w", err)
+	}
+	if err := s.db.ActiveVocabulary.Activate(ctx, version); err != nil {
+		return nil, fmt.Errorf("failed to activate vocabulary version: %w", err)
+	}
+	if replayed > 0 {
+		s.indexService.InvalidateIndexer()
+	}
+
+	now := time.Now()
+	version.ActivatedAt = &now
+	version.IndexStale = true
+	job := &models.Job{Type: JobRefreshVocabularyIndex, Params: map[string]string{"version_id": version.ID}}
+	if err := s.jobs.Enqueue(ctx, job); err != nil {
+		// The index stays marked stale until a reindex replaces it
+		log.Printf("Failed to queue refresh of vocabulary version %d: %v", version.Number, err)
+	} else {
+		version.RefreshJobID = job.ID
+	}
+	if err := s.versionRepo.Update(ctx, version); err != nil {
+		// The version is live, only its record is out of date
+		log.Printf("Failed to record activation of vocabulary version %d: %v", version.Number, err)
+	}
+
+	version.Active = true
+	return version, nil
+}
+
+// CreateEntry adds an entry to the active version. Like UpdateEntry and DeleteEntry, it records
+// the edit so that a version being built from an earlier snapshot of the active one gets it too.
+func (s *VocabularyVersionService) CreateEntry(ctx context.Context, vocabulary *models.Vocabulary) error {
+	versionID := s.db.ActiveVocabulary.VersionID(ctx)
+	if err := s.vocabRepo.Create(ctx, vocabulary); err != nil {
+		return err
+	}
+	s.recordEdit(ctx, versionID, vocabulary.ID, vocabulary)
+	return nil
+}
+
+// UpdateEntry saves an edited entry of the active version
+func (s *VocabularyVersionService) UpdateEntry(ctx context.Context, id string, vocabulary *models.Vocabulary) error {
+	versionID := s.db.ActiveVocabulary.VersionID(ctx)
+	if err := s.vocabRepo.Update(ctx, id, vocabulary); err != nil {
+		return err
+	}
+	s.recordEdit(ctx, versionID, id, vocabulary)
+	return nil
+}
+
+// DeleteEntry deletes an entry of the active version
+func (s *VocabularyVersionService) DeleteEntry(ctx context.Context, id string) error {
+	versionID := s.db.ActiveVocabulary.VersionID(ctx)
+	if err := s.vocabRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.recordEdit(ctx, versionID, id, nil)
+	return nil
+}
+
+// recordEdit records a saved edit of a version's entry. The edit is saved either way, so a
+// failure is only logged; a version built from the snapshot will miss it.
+func (s *VocabularyVersionService) recordEdit(ctx context.Context, versionID, vocabularyID string, after *models.Vocabulary) {
+	if versionID == "" {
+		return
+	}
+	if err := s.versionRepo.RecordEdit(ctx, versionID, vocabularyID, after); err != nil {
+		log.Printf("Failed to record edit of vocabulary entry %s: %v", vocabularyID, err)
+	}
+}
+
+// snapshotRevision returns the active version and how many edits it has had. Read it before
+// reading the entries, so any edit not included in them is recorded after that revision.
+func (s *VocabularyVersionService) snapshotRevision(ctx context.Context) (string, int, error) {
+	versionID := s.db.ActiveVocabulary.VersionID(ctx)
+	revision, err := s.versionRepo.Revision(ctx, versionID)
+	return versionID, revision, err
+}
+
+// replayEdits applies the edits made to the version's BasedOn version since its entries were read,
+// and returns how many there were. Each edit sets the entry as it was after the edit, so replaying
+// one twice does no harm. The caller saves the version.
+func (s *VocabularyVersionService) replayEdits(ctx context.Context, version *models.VocabularyVersion) (int, error) {
+	if version.BasedOn == "" {
+		return 0, nil
+	}
+	edits, err := s.versionRepo.GetEditsSince(ctx, version.BasedOn, version.BasedOnRevision)
+	if err != nil || len(edits) == 0 {
+		return 0, err
+	}
+
+	repo := database.NewVocabularyRepositoryForVersion(s.db, version)
+	for _, edit := range edits {
+		if edit.After == nil {
+			err = repo.Delete(ctx, edit.VocabularyID)
+		} else if err = repo.Update(ctx, edit.VocabularyID, edit.After); err == mongo.ErrNoDocuments {
+			err = repo.Create(ctx, edit.After)
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			return 0, fmt.Errorf("failed to replay edit of entry %s: %w", edit.VocabularyID, err)
+		}
+		version.BasedOnRevision = edit.Revision
+	}
+
+	entries, err := repo.GetAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count vocabulary version entries: %w", err)
+	}
+	version.EntryCount = len(entries)
+	log.Printf("Replayed %d vocabulary edits into version %d", len(edits), version.Number)
+	return len(edits), nil
+}
+
+// runRefresh brings the index of the job's version up to date with the current subtitles, unless
+// another version has been activated since, which will have been given a refresh of its own
+func (s *VocabularyVersionService) runRefresh(ctx context.Context, job *models.Job) (interface{}, error) {
+	version, err := s.versionRepo.GetByID(ctx, job.Params["version_id"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary version: %w", err)
+	}
+	if version.ID != s.db.ActiveVocabulary.VersionID(ctx) {
+		return map[string]interface{}{"version_id": version.ID, "skipped": "version is no longer active"}, nil
+	}
+
+	// Other servers keep editing the version this one was made from until their cached active
+	// version expires, so those edits are replayed once that has happened
+	if version.ActivatedAt != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Until(version.ActivatedAt.Add(database.ActiveVocabularyRefresh))):
+		}
+	}
+	replayed, err := s.replayEdits(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay vocabulary edits: %w", err)
+	}
+	if replayed > 0 {
+		if err := s.versionRepo.Update(ctx, version); err != nil {
+			return nil, fmt.Errorf("failed to save vocabulary version %d: %w", version.Number, err)
+		}
+		s.indexService.InvalidateIndexer()
+	}
+
+	indexer, err := s.indexService.NewIndexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.indexService.RefreshIndex(ctx, indexer, database.NewVocabularyIndexRepositoryForVersion(s.db, version))
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the version again so the flag is only cleared if no later activation set it
+	if version, err = s.versionRepo.GetByID(ctx, version.ID); err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary version: %w", err)
+	}
+	if version.RefreshJobID == job.ID {
+		version.IndexStale = false
+		if err := s.versionRepo.Update(ctx, version); err != nil {
+			return nil, fmt.Errorf("failed to save vocabulary version %d: %w", version.Number, err)
+		}
+	}
+	return result, nil
+}
+
+// runReindex rebuilds the active version's index from every video in a new collection and switches
+// the version to it once it is complete. Search keeps using the old index in the meantime, and the
+// old collection is dropped once every server has stopped reading it.
+func (s *VocabularyVersionService) runReindex(ctx context.Context, job *models.Job) (interface{}, error) {
+	version, err := s.versionRepo.GetByID(ctx, s.db.ActiveVocabulary.VersionID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the active vocabulary version: %w", err)
+	}
+	entries, err := s.Entries(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary version entries: %w", err)
+	}
+
+	// Each attempt builds into a collection of its own, so a failed one leaves nothing in use behind
+	rebuilt := *version
+	rebuilt.IndexCollection = fmt.Sprintf("vocabulary_index_v%d_%s_%d", version.Number, version.ID, time.Now().Unix())
+	indexRepo := database.NewVocabularyIndexRepositoryForVersion(s.db, &rebuilt)
+	result, err := s.indexService.ReindexAll(ctx, entries, indexRepo)
+	if err != nil {
+		s.dropCollection(context.Background(), rebuilt.IndexCollection)
+		return nil, err
+	}
+
+	// The version may have been changed or deactivated while its index was rebuilt
+	version, err = s.versionRepo.GetByID(ctx, version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary version: %w", err)
+	}
+	previous := version.IndexCollection
+	version.IndexCollection = rebuilt.IndexCollection
+	version.IndexedCount = result.TotalIndexed
+	version.IndexedVideos = result.ProcessedVideos
+	if err := s.versionRepo.Update(ctx, version); err != nil {
+		s.dropCollection(context.Background(), rebuilt.IndexCollection)
+		return nil, fmt.Errorf("failed to save vocabulary version %d: %w", version.Number, err)
+	}
+
+	if version.ID == s.db.ActiveVocabulary.VersionID(ctx) {
+		// Activating again switches to the new index and catches up on edits made during the rebuild
+		if _, err := s.Activate(ctx, version.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Other servers read the old collection until their cached active version expires
+	select {
+	case <-ctx.Done():
+		log.Printf("Vocabulary index collection %s was replaced but not dropped", previous)
+	case <-time.After(2 * database.ActiveVocabularyRefresh):
+		s.dropCollection(ctx, previous)
+	}
+	return result, nil
+}
+
+// dropCollection drops a vocabulary collection that is no longer used
+func (s *VocabularyVersionService) dropCollection(ctx context.Context, name string) {
+	if err := s.db.Database.Collection(name).Drop(ctx); err != nil {
+		log.Printf("Failed to drop vocabulary collection %s: %v", name, err)
+	}
+}
+
+// Rollback activates the version that was active before the current one
+func (s *VocabularyVersionService) Rollback(ctx context.Context) (*models.VocabularyVersion, error) {
+	previous := s.db.ActiveVocabulary.PreviousVersionID(ctx)
+	if previous == "" {
+		return nil, ErrNoPreviousVocabulary
+	}
+	return s.Activate(ctx, previous)
+}
+
+// runBuild builds the index of the job's version unless it has already been built. An import
+// records which version it was applied to, and the difference from that version's entries is
+// worked out again so the index can be built from the active one.
+func (s *VocabularyVersionService) runBuild(ctx context.Context, job *models.Job) (interface{}, error) {
+	version, err := s.versionRepo.GetByID(ctx, job.Params["version_id"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary version: %w", err)
+	}
+	if version.Status == models.VocabularyVersionBuilding {
+		entries, err := s.Entries(ctx, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vocabulary version entries: %w", err)
+		}
+
+		var diff *models.VocabularyDiff
+		if version.Source == "import" && version.BasedOn != "" {
+			if base, err := s.versionRepo.GetByID(ctx, version.BasedOn); err == nil {
+				if existing, err := s.Entries(ctx, base); err == nil {
+					diff = DiffVocabulary(existing, entries, VocabularyImportReplace)
+				}
+			}
+		}
+
+		if err := s.build(ctx, version, entries, diff, job.Params["activate"] == "true"); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{
+		"version_id":     version.ID,
+		"number":         version.Number,
+		"status":         version.Status,
+		"indexed_count":  version.IndexedCount,
+		"indexed_videos": version.IndexedVideos,
+		"incremental":    version.Incremental,
+	}, nil
+}
+
+// buildFailed marks the version of a build job that failed or was cancelled as failed
+func (s *VocabularyVersionService) buildFailed(ctx context.Context, job *models.Job) {
+	version, err := s.versionRepo.GetByID(ctx, job.Params["version_id"])
+	if err != nil {
+		log.Printf("Failed to get vocabulary version of job %s: %v", job.ID, err)
+		return
+	}
+	if version.Status == models.VocabularyVersionBuilding {
+		s.fail(ctx, version, errors.New(job.Error))
+	}
+}
+
+// build indexes a new version and activates it if asked to. The live vocabulary is untouched
+// until the index is complete. A version made from the active one by an import starts from the
+// active index, so only the entries the import added or changed are looked for in the videos.
+func (s *VocabularyVersionService) build(ctx context.Context, version *models.VocabularyVersion, entries []*models.Vocabulary, diff *models.VocabularyDiff, activate bool) error {
+	// An earlier attempt may have left part of the index behind
+	indexRepo := database.NewVocabularyIndexRepositoryForVersion(s.db, version)
+	if err := indexRepo.DeleteAll(ctx); err != nil {
+		return fmt.Errorf("failed to clear the version's index: %w", err)
+	}
+
+	var result *ReindexResult
+	var err error
+	if diff != nil && version.BasedOn == s.db.ActiveVocabulary.VersionID(ctx) {
+		result, err = s.indexService.BuildIndexFrom(ctx, entries, diff, indexRepo, version.IndexCollection)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Start again from an empty index rather than fail the version
+			log.Printf("Failed to build vocabulary version %d from the active index, indexing every entry: %v", version.Number, err)
+			if err := indexRepo.DeleteAll(ctx); err != nil {
+				return fmt.Errorf("failed to clear the version's index: %w", err)
+			}
+		}
+		version.Incremental = err == nil
+	}
+	if result == nil {
+		result, err = s.indexService.BuildIndex(ctx, entries, indexRepo)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	version.Status = models.VocabularyVersionReady
+	version.IndexedCount = result.TotalIndexed
+	version.IndexedVideos = result.ProcessedVideos
+	version.ReadyAt = &now
+	if err := s.versionRepo.Update(ctx, version); err != nil {
+		return fmt.Errorf("failed to save vocabulary version %d: %w", version.Number, err)
+	}
+
+	if activate {
+		if _, err := s.Activate(ctx, version.ID); err != nil {
+			log.Printf("Failed to activate vocabulary version %d: %v", version.Number, err)
+		}
+	}
+
+	s.prune(ctx)
+	return nil
+}
+
+// fail records why a version could not be built
+func (s *VocabularyVersionService) fail(ctx context.Context, version *models.VocabularyVersion, cause error) {
+	log.Printf("Vocabulary version %d failed: %v", version.Number, cause)
+
+	version.Status = models.VocabularyVersionFailed
+	version.Error = cause.Error()
+	if err := s.versionRepo.Update(ctx, version); err != nil {
+		log.Printf("Failed to save vocabulary version %d: %v", version.Number, err)
+	}
+}
+
+// prune drops the collections and records of old versions beyond vocabularyVersionsKept
+func (s *VocabularyVersionService) prune(ctx context.Context) {
+	versions, err := s.versionRepo.GetAll(ctx)
+	if err != nil {
+		log.Printf("Failed to list vocabulary versions: %v", err)
+		return
+	}
+
+	activeID := s.db.ActiveVocabulary.VersionID(ctx)
+	previousID := s.db.ActiveVocabulary.PreviousVersionID(ctx)
+	basedOn := make(map[string]bool)
+	for i, version := range versions {
+		if i < vocabularyVersionsKept || version.ID == activeID || version.ID == previousID ||
+			version.Status == models.VocabularyVersionBuilding {
+			basedOn[version.BasedOn] = true
+		}
+	}
+	for i, version := range versions {
+		if i < vocabularyVersionsKept || version.ID == activeID || version.ID == previousID ||
+			version.Status == models.VocabularyVersionBuilding {
+			continue
+		}
+
+		// A kept version made from this one still needs its edits when it is activated
+		if !basedOn[version.ID] {
+			if err := s.versionRepo.DeleteEdits(ctx, version.ID); err != nil {
+				log.Printf("Failed to delete edits of vocabulary version %d: %v", version.Number, err)
+			}
+		}
+
+		for _, name := range []string{version.Collection, version.IndexCollection} {
+			if err := s.db.Database.Collection(name).Drop(ctx); err != nil {
+				log.Printf("Failed to drop collection %s of vocabulary version %d: %v", name, version.Number, err)
+			}
+		}
+		if err := s.versionRepo.Delete(ctx, version.ID); err != nil {
+			log.Printf("Failed to delete vocabulary version %d: %v", version.Number, err)
+		}
+	}
+}
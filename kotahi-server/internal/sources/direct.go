@@ -0,0 +1,35 @@
+package sources
+
+// DirectSource resolves plain http(s) links to video files
+type DirectSource struct{}
+
+// Name returns the provider type
+func (s *DirectSource) Name() string {
+	return ProviderDirect
+}
+
+// Matches reports whether the link is an absolute http(s) URL
+func (s *DirectSource) Matches(link string) bool {
+	_, err := parseHTTPURL(link)
+	return err == nil
+}
+
+// Validate checks that the link is an absolute http(s) URL
+func (s *DirectSource) Validate(link string) error {
+	_, err := parseHTTPURL(link)
+	return err
+}
+
+// Normalize returns the link unchanged as it is already playable
+func (s *DirectSource) Normalize(link string) (string, error) {
+	u, err := parseHTTPURL(link)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Thumbnail returns "" as no thumbnail can be derived from an arbitrary URL
+func (s *DirectSource) Thumbnail(link string) string {
+	return ""
+}
@@ -0,0 +1,62 @@
+package sources
+
+import (
+	"fmt"
+)
+
+// DropboxSource resolves Dropbox share links
+type DropboxSource struct{}
+
+// Name returns the provider type
+func (s *DropboxSource) Name() string {
+	return ProviderDropbox
+}
+
+// Matches reports whether the link is a Dropbox link
+func (s *DropboxSource) Matches(link string) bool {
+	u, err := parseHTTPURL(link)
+	if err != nil {
+		return false
+	}
+	return hostMatches(u, "dropbox.com", "dropboxusercontent.com")
+}
+
+// Validate checks that the link is a Dropbox file link
+func (s *DropboxSource) Validate(link string) error {
+	u, err := parseHTTPURL(link)
+	if err != nil {
+		return err
+	}
+	if !hostMatches(u, "dropbox.com", "dropboxusercontent.com") {
+		return fmt.Errorf("not a Dropbox link")
+	}
+	if u.Path == "" || u.Path == "/" {
+		return fmt.Errorf("Dropbox link must point to a file")
+	}
+	return nil
+}
+
+// Normalize replaces the dl parameter with raw=1 so Dropbox serves the file itself
+func (s *DropboxSource) Normalize(link string) (string, error) {
+	u, err := parseHTTPURL(link)
+	if err != nil {
+		return "", err
+	}
+
+	// Direct content links are already playable
+	if hostMatches(u, "dropboxusercontent.com") {
+		return u.String(), nil
+	}
+
+	query := u.Query()
+	query.Del("dl")
+	query.Set("raw", "1")
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Thumbnail returns "" as Dropbox does not expose public thumbnails
+func (s *DropboxSource) Thumbnail(link string) string {
+	return ""
+}
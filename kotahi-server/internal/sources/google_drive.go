@@ -0,0 +1,76 @@
+package sources
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// driveFilePathPattern extracts the file ID from /file/d/{id}/... links
+var driveFilePathPattern = regexp.MustCompile(`/file/d/([A-Za-z0-9_-]+)`)
+
+// GoogleDriveSource resolves Google Drive share links
+type GoogleDriveSource struct{}
+
+// Name returns the provider type
+func (s *GoogleDriveSource) Name() string {
+	return ProviderGoogleDrive
+}
+
+// Matches reports whether the link is a Google Drive link
+func (s *GoogleDriveSource) Matches(link string) bool {
+	u, err := parseHTTPURL(link)
+	if err != nil {
+		return false
+	}
+	return hostMatches(u, "drive.google.com", "docs.google.com")
+}
+
+// Validate checks that a file ID can be extracted from the link
+func (s *GoogleDriveSource) Validate(link string) error {
+	if _, err := s.fileID(link); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Normalize converts the share link into a direct download link
+func (s *GoogleDriveSource) Normalize(link string) (string, error) {
+	id, err := s.fileID(link)
+	if err != nil {
+		return "", err
+	}
+	return "https://drive.google.com/uc?export=download&id=" + url.QueryEscape(id), nil
+}
+
+// Thumbnail returns the Drive thumbnail URL for the file
+func (s *GoogleDriveSource) Thumbnail(link string) string {
+	id, err := s.fileID(link)
+	if err != nil {
+		return ""
+	}
+	return "https://drive.google.com/thumbnail?sz=w640&id=" + url.QueryEscape(id)
+}
+
+// fileID extracts the Drive file ID from the supported link formats
+func (s *GoogleDriveSource) fileID(link string) (string, error) {
+	u, err := parseHTTPURL(link)
+	if err != nil {
+		return "", err
+	}
+	if !hostMatches(u, "drive.google.com", "docs.google.com") {
+		return "", fmt.Errorf("not a Google Drive link")
+	}
+
+	// https://drive.google.com/file/d/{id}/view
+	if matches := driveFilePathPattern.FindStringSubmatch(u.Path); len(matches) == 2 {
+		return matches[1], nil
+	}
+
+	// https://drive.google.com/open?id={id} and https://drive.google.com/uc?id={id}
+	if id := u.Query().Get("id"); id != "" {
+		return id, nil
+	}
+
+	return "", fmt.Errorf("Google Drive link does not contain a file ID")
+}
@@ -0,0 +1,60 @@
+package sources
+
+import (
+	"fmt"
+	"strings"
+)
+
+// localMediaPrefix is the API path that serves media uploaded to this server
+const localMediaPrefix = "/api/v1/media/"
+
+// LocalSource resolves media files uploaded to this server
+type LocalSource struct{}
+
+// Name returns the provider type
+func (s *LocalSource) Name() string {
+	return ProviderLocal
+}
+
+// Matches reports whether the link points at uploaded media
+func (s *LocalSource) Matches(link string) bool {
+	return strings.HasPrefix(link, localMediaPrefix)
+}
+
+// Validate checks that the link contains a media ID
+func (s *LocalSource) Validate(link string) error {
+	if _, err := s.mediaID(link); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Normalize returns the canonical streaming path for the media, without any signature
+func (s *LocalSource) Normalize(link string) (string, error) {
+	id, err := s.mediaID(link)
+	if err != nil {
+		return "", err
+	}
+	return localMediaPrefix + id, nil
+}
+
+// Thumbnail returns "" as thumbnails are uploaded separately
+func (s *LocalSource) Thumbnail(link string) string {
+	return ""
+}
+
+// mediaID extracts the media ID from an uploaded media path
+func (s *LocalSource) mediaID(link string) (string, error) {
+	if !strings.HasPrefix(link, localMediaPrefix) {
+		return "", fmt.Errorf("not an uploaded media link")
+	}
+
+	id := strings.TrimPrefix(link, localMediaPrefix)
+	if i := strings.IndexAny(id, "?#"); i >= 0 {
+		id = id[:i]
+	}
+	if id == "" || strings.Contains(id, "/") {
+		return "", fmt.Errorf("uploaded media link must contain a media ID")
+	}
+	return id, nil
+}
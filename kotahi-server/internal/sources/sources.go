@@ -0,0 +1,152 @@
+package sources
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Provider names stored on videos
+const (
+	ProviderDropbox     = "dropbox"
+	ProviderGoogleDrive = "google_drive"
+	ProviderDirect      = "direct"
+	ProviderLocal       = "local"
+)
+
+// VideoSource turns links from a hosting provider into playable video URLs
+type VideoSource interface {
+	// Name returns the provider type stored on the video
+	Name() string
+	// Matches reports whether the link belongs to this provider
+	Matches(link string) bool
+	// Validate checks that the link can be resolved by this provider
+	Validate(link string) error
+	// Normalize converts a share link into a URL that can be played directly
+	Normalize(link string) (string, error)
+	// Thumbnail derives a thumbnail URL for the link, or returns "" if the provider has none
+	Thumbnail(link string) string
+}
+
+// Resolved is the result of resolving a link through a provider
+type Resolved struct {
+	Provider  string
+	SourceURL string
+	VideoURL  string
+	Thumbnail string
+}
+
+// Registry holds the available video source providers in detection order
+type Registry struct {
+	sources []VideoSource
+}
+
+// NewRegistry creates a registry with the given providers, checked in order
+func NewRegistry(sources ...VideoSource) *Registry {
+	return &Registry{sources: sources}
+}
+
+// NewDefaultRegistry creates a registry with all built-in providers
+func NewDefaultRegistry() *Registry {
+	return NewRegistry(
+		&LocalSource{},
+		&DropboxSource{},
+		&GoogleDriveSource{},
+		&DirectSource{},
+	)
+}
+
+// Register adds a provider, giving it priority over the existing ones
+func (r *Registry) Register(source VideoSource) {
+	r.sources = append([]VideoSource{source}, r.sources...)
+}
+
+// Get returns the provider with the given name
+func (r *Registry) Get(name string) (VideoSource, bool) {
+	for _, source := range r.sources {
+		if source.Name() == name {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// Detect returns the first provider that matches the link
+func (r *Registry) Detect(link string) (VideoSource, bool) {
+	for _, source := range r.sources {
+		if source.Matches(link) {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the names of all registered providers
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.sources))
+	for _, source := range r.sources {
+		names = append(names, source.Name())
+	}
+	return names
+}
+
+// Resolve validates and normalises a link using the named provider, or detects one if name is empty
+func (r *Registry) Resolve(provider, link string) (*Resolved, error) {
+	link = strings.TrimSpace(link)
+
+	var source VideoSource
+	var ok bool
+	if provider != "" {
+		source, ok = r.Get(provider)
+		if !ok {
+			return nil, fmt.Errorf("unknown video source %q", provider)
+		}
+	} else {
+		source, ok = r.Detect(link)
+		if !ok {
+			return nil, fmt.Errorf("no video source supports this link")
+		}
+	}
+
+	if err := source.Validate(link); err != nil {
+		return nil, err
+	}
+
+	videoURL, err := source.Normalize(link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolved{
+		Provider:  source.Name(),
+		SourceURL: link,
+		VideoURL:  videoURL,
+		Thumbnail: source.Thumbnail(link),
+	}, nil
+}
+
+// parseHTTPURL parses a link and checks that it is an absolute http(s) URL
+func parseHTTPURL(link string) (*url.URL, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("URL must use http or https")
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("URL must include a host")
+	}
+	return u, nil
+}
+
+// hostMatches checks whether the URL host equals or is a subdomain of one of the domains
+func hostMatches(u *url.URL, domains ...string) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryObject is a file held by MemoryStorage
+type memoryObject struct {
+	content []byte
+	info    ObjectInfo
+}
+
+// MemoryStorage keeps files in memory. It is meant for development and for exercising
+// code that uses a BlobStore without touching the disk.
+type MemoryStorage struct {
+	baseURL string
+	signer  *URLSigner
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+}
+
+// NewMemoryStorage creates a new empty in-memory storage. baseURL and signer are only needed for SignedURL.
+func NewMemoryStorage(baseURL string, signer *URLSigner) *MemoryStorage {
+	return &MemoryStorage{
+		baseURL: baseURL,
+		signer:  signer,
+		objects: make(map[string]*memoryObject),
+	}
+}
+
+// Put stores the reader's content under the name, replacing any existing file
+func (s *MemoryStorage) Put(ctx context.Context, name string, r io.Reader) (*ObjectInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &memoryObject{
+		content: content,
+		info: ObjectInfo{
+			Name:    name,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+			Hash:    HashBytes(content),
+		},
+	}
+
+	s.mu.Lock()
+	s.objects[name] = obj
+	s.mu.Unlock()
+
+	info := obj.info
+	return &info, nil
+}
+
+// Get opens the named file for reading
+func (s *MemoryStorage) Get(ctx context.Context, name string) (Object, *ObjectInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.RLock()
+	obj, ok := s.objects[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	// Stored content is never modified in place, so readers can share it
+	info := obj.info
+	return nopCloser{bytes.NewReader(obj.content)}, &info, nil
+}
+
+// Stat describes the named file
+func (s *MemoryStorage) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	info := obj.info
+	return &info, nil
+}
+
+// Delete removes the named file
+func (s *MemoryStorage) Delete(ctx context.Context, name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.objects[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(s.objects, name)
+	return nil
+}
+
+// List describes the files whose names start with prefix
+func (s *MemoryStorage) List(ctx context.Context, prefix string) ([]*ObjectInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var objects []*ObjectInfo
+	for name, obj := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			info := obj.info
+			objects = append(objects, &info)
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+// SignedURL returns a signed URL under the store's base URL
+func (s *MemoryStorage) SignedURL(ctx context.Context, name string, ttl time.Duration) (string, error) {
+	if _, err := s.Stat(ctx, name); err != nil {
+		return "", err
+	}
+	return signedPath(s.baseURL, s.signer, name, ttl)
+}
+
+// nopCloser adds a no-op Close to a bytes.Reader
+type nopCloser struct {
+	*bytes.Reader
+}
+
+// Close does nothing
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a stored file does not exist
+var ErrNotFound = errors.New("object not found")
+
+// IsNotFound reports whether an error means a stored file does not exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Object is an open stored file that can be streamed with range support
+type Object interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// ObjectInfo describes a stored file
+type ObjectInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modified_at"`
+	Hash    string    `json:"hash,omitempty"` // Hex SHA-256 of the content, empty if the store doesn't know it
+}
+
+// BlobStore abstracts where uploaded files are kept. Names are slash-separated paths
+// relative to the store, such as "videos/abc.mp4" or "abc.vtt".
+type BlobStore interface {
+	// Put writes the reader to the named file, replacing any existing file. Readers never see a partial file.
+	Put(ctx context.Context, name string, r io.Reader) (*ObjectInfo, error)
+	// Get opens the named file for reading
+	Get(ctx context.Context, name string) (Object, *ObjectInfo, error)
+	// Stat describes the named file without opening it
+	Stat(ctx context.Context, name string) (*ObjectInfo, error)
+	// Delete removes the named file
+	Delete(ctx context.Context, name string) error
+	// List describes the files whose names start with prefix, in name order
+	List(ctx context.Context, prefix string) ([]*ObjectInfo, error)
+	// SignedURL returns a URL that allows reading the named file until ttl has passed
+	SignedURL(ctx context.Context, name string, ttl time.Duration) (string, error)
+}
+
+// HashBytes returns the hex SHA-256 of content, as stored in ObjectInfo.Hash
+func HashBytes(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFinder is implemented by stores that keep an index of content hashes, so files can be
+// found by content without describing every file
+type HashFinder interface {
+	FindByHash(ctx context.Context, prefix, hash string) ([]*ObjectInfo, error)
+}
+
+// FindByHash returns the files under prefix whose content has the given hash
+func FindByHash(ctx context.Context, store BlobStore, prefix, hash string) ([]*ObjectInfo, error) {
+	if finder, ok := store.(HashFinder); ok {
+		return finder.FindByHash(ctx, prefix, hash)
+	}
+
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*ObjectInfo
+	for _, info := range objects {
+		// Some stores can only report the hash when asked about a single file
+		if info.Hash == "" {
+			if info, err = store.Stat(ctx, info.Name); err != nil {
+				if IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+		}
+		if info.Hash == hash {
+			matches = append(matches, info)
+		}
+	}
+	return matches, nil
+}
+
+// validateName rejects names that could escape the store
+func validateName(name string) error {
+	if name == "" || strings.Contains(name, "..") || strings.Contains(name, "\\") || strings.HasPrefix(name, "/") {
+		return fmt.Errorf("invalid storage name: %q", name)
+	}
+	return nil
+}
+
+// signedPath builds a signed URL for stores whose files are served by this API
+func signedPath(baseURL string, signer *URLSigner, name string, ttl time.Duration) (string, error) {
+	if baseURL == "" || signer == nil {
+		return "", fmt.Errorf("signed URLs are not configured for this store")
+	}
+	return signer.SignURL(strings.TrimSuffix(baseURL, "/")+"/"+name, name, ttl), nil
+}
+
+// LocalStorage stores files on the local filesystem under a root directory
+type LocalStorage struct {
+	root    string
+	baseURL string     // URL path the files are served under, used for signed URLs
+	signer  *URLSigner // Signs URLs to files, nil if signed URLs aren't needed
+	mu      sync.Mutex // Guards hashes
+	hashes  map[string]localHash
+}
+
+// localHash caches the content hash of a file until it changes
+type localHash struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// NewLocalStorage creates a new local filesystem storage rooted at the given directory.
+// baseURL and signer are only needed for SignedURL.
+func NewLocalStorage(root, baseURL string, signer *URLSigner) *LocalStorage {
+	// Ensure storage directory exists
+	if err := os.MkdirAll(root, 0755); err != nil {
+		log.Printf("Failed to create storage directory: %v", err)
+	}
+	return &LocalStorage{
+		root:    root,
+		baseURL: baseURL,
+		signer:  signer,
+		hashes:  make(map[string]localHash),
+	}
+}
+
+// Put writes the reader to the named file, replacing any existing file
+func (s *LocalStorage) Put(ctx context.Context, name string, r io.Reader) (*ObjectInfo, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), r); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	s.mu.Lock()
+	s.hashes[name] = localHash{size: fi.Size(), modTime: fi.ModTime(), hash: hash}
+	s.mu.Unlock()
+
+	return &ObjectInfo{Name: name, Size: fi.Size(), ModTime: fi.ModTime(), Hash: hash}, nil
+}
+
+// Get opens the named file for reading
+func (s *LocalStorage) Get(ctx context.Context, name string) (Object, *ObjectInfo, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, notFound(err, name)
+	}
+
+	info, err := s.stat(name, path)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return file, info, nil
+}
+
+// Stat describes the named file
+func (s *LocalStorage) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.stat(name, path)
+}
+
+// Delete removes the named file
+func (s *LocalStorage) Delete(ctx context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return notFound(err, name)
+	}
+
+	s.mu.Lock()
+	delete(s.hashes, name)
+	s.mu.Unlock()
+	return nil
+}
+
+// List describes the files whose names start with prefix. Temporary upload files are skipped.
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]*ObjectInfo, error) {
+	var objects []*ObjectInfo
+	err := filepath.WalkDir(s.root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") || strings.HasSuffix(d.Name(), ".tmp") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		info, err := s.stat(name, p)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+// SignedURL returns a signed URL under the store's base URL
+func (s *LocalStorage) SignedURL(ctx context.Context, name string, ttl time.Duration) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+	return signedPath(s.baseURL, s.signer, name, ttl)
+}
+
+// path resolves a storage name to a path inside the root directory
+func (s *LocalStorage) path(name string) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(name)), nil
+}
+
+// stat returns information about a stored file, hashing it if it changed since it was last hashed
+func (s *LocalStorage) stat(name, path string) (*ObjectInfo, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, notFound(err, name)
+	}
+
+	s.mu.Lock()
+	cached, ok := s.hashes[name]
+	s.mu.Unlock()
+
+	hash := cached.hash
+	if !ok || cached.size != fi.Size() || !cached.modTime.Equal(fi.ModTime()) {
+		if hash, err = hashFile(path); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.hashes[name] = localHash{size: fi.Size(), modTime: fi.ModTime(), hash: hash}
+		s.mu.Unlock()
+	}
+
+	return &ObjectInfo{
+		Name:    name,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		Hash:    hash,
+	}, nil
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// notFound converts a missing-file error into ErrNotFound
+func notFound(err error, name string) error {
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return err
+}
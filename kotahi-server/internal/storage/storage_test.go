@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// testSecret signs the URLs of the stores under test
+const testSecret = "conformance-secret"
+
+// urlCheck verifies that a signed URL returned by a store gives access to the named file
+type urlCheck func(t *testing.T, name, signed string, content []byte)
+
+// signerCheck verifies URLs of stores whose files are served by this API under baseURL
+func signerCheck(baseURL string, signer *URLSigner) urlCheck {
+	return func(t *testing.T, name, signed string, content []byte) {
+		u, err := url.Parse(signed)
+		if err != nil {
+			t.Fatalf("SignedURL returned an invalid URL %q: %v", signed, err)
+		}
+		if u.Path != baseURL+"/"+name {
+			t.Errorf("SignedURL path = %q, want %q", u.Path, baseURL+"/"+name)
+		}
+		if err := signer.Verify(name, u.Query().Get("expires"), u.Query().Get("signature")); err != nil {
+			t.Errorf("SignedURL signature doesn't verify: %v", err)
+		}
+		if err := signer.Verify("other.vtt", u.Query().Get("expires"), u.Query().Get("signature")); err == nil {
+			t.Error("SignedURL signature verifies for another file")
+		}
+	}
+}
+
+// fetchCheck verifies URLs that can be fetched directly, such as presigned S3 URLs
+func fetchCheck(t *testing.T, name, signed string, content []byte) {
+	resp, err := http.Get(signed)
+	if err != nil {
+		t.Fatalf("GET %s: %v", signed, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, content) {
+		t.Errorf("GET signed URL = %s %q, want 200 %q", resp.Status, body, content)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	signer := NewURLSigner(testSecret)
+	testBlobStore(t, NewLocalStorage(t.TempDir(), "/uploads", signer), signerCheck("/uploads", signer))
+}
+
+func TestMemoryStorage(t *testing.T) {
+	signer := NewURLSigner(testSecret)
+	testBlobStore(t, NewMemoryStorage("/uploads", signer), signerCheck("/uploads", signer))
+}
+
+// TestS3Storage runs the conformance tests against an S3-compatible service, such as the MinIO
+// started by `docker compose --profile minio up`. It is skipped unless S3_TEST_ENDPOINT and
+// S3_TEST_BUCKET are set; the bucket must exist. The keys default to MinIO's.
+func TestS3Storage(t *testing.T) {
+	endpoint, bucket := os.Getenv("S3_TEST_ENDPOINT"), os.Getenv("S3_TEST_BUCKET")
+	if endpoint == "" || bucket == "" {
+		t.Skip("S3_TEST_ENDPOINT and S3_TEST_BUCKET are not set")
+	}
+	env := func(name, fallback string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	store, err := NewS3Storage(S3Config{
+		Endpoint:        endpoint,
+		Region:          env("S3_TEST_REGION", "us-east-1"),
+		Bucket:          bucket,
+		AccessKeyID:     env("S3_TEST_ACCESS_KEY_ID", "minioadmin"),
+		SecretAccessKey: env("S3_TEST_SECRET_ACCESS_KEY", "minioadmin"),
+		Prefix:          fmt.Sprintf("conformance-%d/", time.Now().UnixNano()),
+		PathStyle:       env("S3_TEST_PATH_STYLE", "true") == "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		objects, _ := store.List(ctx, "")
+		for _, object := range objects {
+			store.Delete(ctx, object.Name)
+		}
+	})
+
+	testBlobStore(t, store, fetchCheck)
+}
+
+// testBlobStore checks that a store behaves as the BlobStore interface describes. The store
+// must start out empty.
+func testBlobStore(t *testing.T, store BlobStore, checkURL urlCheck) {
+	ctx := context.Background()
+	first := []byte("WEBVTT\n\n00:00.000 --> 00:01.000\nKia ora\n")
+	second := []byte("WEBVTT\n\n00:00.000 --> 00:02.000\nTēnā koe\n")
+	nested := []byte("WEBVTT\n")
+
+	t.Run("put", func(t *testing.T) {
+		info, err := store.Put(ctx, "a.vtt", bytes.NewReader(first))
+		if err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if info.Name != "a.vtt" || info.Size != int64(len(first)) || info.Hash != HashBytes(first) {
+			t.Errorf("Put = %+v, want a.vtt of %d bytes with hash %s", info, len(first), HashBytes(first))
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		obj, info, err := store.Get(ctx, "a.vtt")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		defer obj.Close()
+
+		content, err := io.ReadAll(obj)
+		if err != nil || !bytes.Equal(content, first) {
+			t.Errorf("Get content = %q, %v; want %q", content, err, first)
+		}
+		if info.Size != int64(len(first)) || info.Hash != HashBytes(first) {
+			t.Errorf("Get info = %+v", info)
+		}
+
+		if _, err := obj.Seek(8, io.SeekStart); err != nil {
+			t.Fatalf("Seek: %v", err)
+		}
+		rest, err := io.ReadAll(obj)
+		if err != nil || !bytes.Equal(rest, first[8:]) {
+			t.Errorf("read after Seek = %q, %v; want %q", rest, err, first[8:])
+		}
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		info, err := store.Stat(ctx, "a.vtt")
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if info.Name != "a.vtt" || info.Size != int64(len(first)) || info.Hash != HashBytes(first) || info.ModTime.IsZero() {
+			t.Errorf("Stat = %+v", info)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		if _, err := store.Put(ctx, "a.vtt", bytes.NewReader(second)); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		obj, info, err := store.Get(ctx, "a.vtt")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		defer obj.Close()
+
+		content, _ := io.ReadAll(obj)
+		if !bytes.Equal(content, second) || info.Hash != HashBytes(second) {
+			t.Errorf("Get after replacing = %q with hash %s, want %q", content, info.Hash, second)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		if _, err := store.Put(ctx, "dir/b.vtt", bytes.NewReader(nested)); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+
+		tests := []struct {
+			prefix string
+			want   []string
+		}{
+			{"", []string{"a.vtt", "dir/b.vtt"}},
+			{"dir/", []string{"dir/b.vtt"}},
+			{"none/", nil},
+		}
+		for _, tt := range tests {
+			objects, err := store.List(ctx, tt.prefix)
+			if err != nil {
+				t.Fatalf("List(%q): %v", tt.prefix, err)
+			}
+			var names []string
+			for _, object := range objects {
+				names = append(names, object.Name)
+				// Stores that can't list hashes leave them empty
+				if object.Hash != "" && object.Hash != map[string]string{"a.vtt": HashBytes(second), "dir/b.vtt": HashBytes(nested)}[object.Name] {
+					t.Errorf("List(%q) hash of %s = %s", tt.prefix, object.Name, object.Hash)
+				}
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Errorf("List(%q) = %v, want %v", tt.prefix, names, tt.want)
+			}
+		}
+	})
+
+	t.Run("find by hash", func(t *testing.T) {
+		matches, err := FindByHash(ctx, store, "", HashBytes(nested))
+		if err != nil {
+			t.Fatalf("FindByHash: %v", err)
+		}
+		if len(matches) != 1 || matches[0].Name != "dir/b.vtt" {
+			t.Errorf("FindByHash = %v, want dir/b.vtt", matches)
+		}
+		if matches, _ := FindByHash(ctx, store, "", HashBytes(first)); len(matches) != 0 {
+			t.Errorf("FindByHash found replaced content in %v", matches)
+		}
+	})
+
+	t.Run("signed URL", func(t *testing.T) {
+		signed, err := store.SignedURL(ctx, "dir/b.vtt", time.Minute)
+		if err != nil {
+			t.Fatalf("SignedURL: %v", err)
+		}
+		checkURL(t, "dir/b.vtt", signed, nested)
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		for _, name := range []string{"", "../escape.vtt", "dir/../../escape.vtt", "/absolute.vtt", `dir\file.vtt`} {
+			if _, err := store.Put(ctx, name, bytes.NewReader(first)); err == nil {
+				t.Errorf("Put(%q) succeeded", name)
+			}
+			if _, _, err := store.Get(ctx, name); err == nil || IsNotFound(err) {
+				t.Errorf("Get(%q) = %v, want an invalid name error", name, err)
+			}
+			if _, err := store.Stat(ctx, name); err == nil || IsNotFound(err) {
+				t.Errorf("Stat(%q) = %v, want an invalid name error", name, err)
+			}
+			if err := store.Delete(ctx, name); err == nil || IsNotFound(err) {
+				t.Errorf("Delete(%q) = %v, want an invalid name error", name, err)
+			}
+			if _, err := store.SignedURL(ctx, name, time.Minute); err == nil {
+				t.Errorf("SignedURL(%q) succeeded", name)
+			}
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, _, err := store.Get(ctx, "missing.vtt"); !IsNotFound(err) {
+			t.Errorf("Get of a missing file = %v, want ErrNotFound", err)
+		}
+		if _, err := store.Stat(ctx, "missing.vtt"); !IsNotFound(err) {
+			t.Errorf("Stat of a missing file = %v, want ErrNotFound", err)
+		}
+		if err := store.Delete(ctx, "missing.vtt"); !IsNotFound(err) {
+			t.Errorf("Delete of a missing file = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := store.Delete(ctx, "a.vtt"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := store.Stat(ctx, "a.vtt"); !IsNotFound(err) {
+			t.Errorf("Stat after Delete = %v, want ErrNotFound", err)
+		}
+		objects, err := store.List(ctx, "")
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(objects) != 1 || objects[0].Name != "dir/b.vtt" {
+			t.Errorf("List after Delete = %v, want only dir/b.vtt", objects)
+		}
+	})
+}
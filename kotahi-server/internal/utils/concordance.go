@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TextSpan is a range of runes in a piece of text
+type TextSpan struct {
+	Start int // Offset of the first rune
+	End   int // Offset just past the last rune
+}
+
+// TokenizeWords splits text into distinct words normalised with NormalizeMaori, in order of first use
+func TokenizeWords(text string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, token := range tokenizeText(text) {
+		if !seen[token.key] {
+			seen[token.key] = true
+			words = append(words, token.key)
+		}
+	}
+	return words
+}
+
+// PhraseWords splits a search query into the normalised words of the phrase it describes.
+// Quotes around the phrase are ignored.
+func PhraseWords(query string) []string {
+	return vocabularyWords(query)
+}
+
+// FindPhrase finds every occurrence of a phrase, given as words from PhraseWords, in text.
+// Words are compared the same way as in the vocabulary index.
+func FindPhrase(text string, words []string) []TextSpan {
+	if len(words) == 0 {
+		return nil
+	}
+
+	tokens := tokenizeText(text)
+	var spans []TextSpan
+	for i := 0; i+len(words) <= len(tokens); i++ {
+		matched := true
+		for j, word := range words {
+			if tokens[i+j].key != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			spans = append(spans, TextSpan{Start: tokens[i].start, End: tokens[i+len(words)-1].end})
+			i += len(words) - 1 // Occurrences don't overlap
+		}
+	}
+	return spans
+}
+
+// ContextBefore returns up to width runes from the end of text, starting at a word boundary
+func ContextBefore(text string, width int) string {
+	runes := []rune(strings.TrimRightFunc(text, unicode.IsSpace))
+	if len(runes) <= width {
+		return string(runes)
+	}
+
+	cut := len(runes) - width
+	if unicode.IsSpace(runes[cut-1]) {
+		return string(runes[cut:])
+	}
+	// Drop the partial word at the cut unless it is the only word in range
+	for i := cut; i < len(runes); i++ {
+		if unicode.IsSpace(runes[i]) {
+			cut = i + 1
+			break
+		}
+	}
+	return strings.TrimLeftFunc(string(runes[cut:]), unicode.IsSpace)
+}
+
+// ContextAfter returns up to width runes from the start of text, ending at a word boundary
+func ContextAfter(text string, width int) string {
+	runes := []rune(strings.TrimLeftFunc(text, unicode.IsSpace))
+	if len(runes) <= width {
+		return string(runes)
+	}
+
+	cut := width
+	for i := cut; i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"video-player-backend/internal/models"
+	"video-player-backend/internal/validation"
 )
 
 // CSVVocabularyRow represents a single row in the CSV file
@@ -16,8 +18,33 @@ type CSVVocabularyRow struct {
 	Description string
 }
 
+// vocabularyCSVColumns lists the vocabulary CSV columns in the order used by files without a header row
+var vocabularyCSVColumns = []string{
+	"maori", "english", "description", "part_of_speech", "variants", "forms",
+	"senses", "examples", "tags", "difficulty", "audio_url",
+}
+
+// vocabularyCSVAliases maps other accepted header names to column names
+var vocabularyCSVAliases = map[string]string{
+	"māori":        "maori",
+	"pos":          "part_of_speech",
+	"partofspeech": "part_of_speech",
+	"variant":      "variants",
+	"spellings":    "variants",
+	"form":         "forms",
+	"sense":        "senses",
+	"example":      "examples",
+	"tag":          "tags",
+	"topics":       "tags",
+	"level":        "difficulty",
+	"audio":        "audio_url",
+}
+
 // ParseVocabularyCSV parses a CSV file containing vocabulary data
-// Expected CSV format: maori,english,description
+// Expected CSV format: maori,english,description followed by any of part_of_speech, variants,
+// forms, senses, examples, tags, difficulty and audio_url. With a header row the columns can be
+// in any order. List columns separate items with semicolons; forms are written as kind:form
+// (e.g. "passive:karangatia") and examples as "Māori sentence | English translation".
 func ParseVocabularyCSV(reader io.Reader) ([]*models.Vocabulary, error) {
 	csvReader := csv.NewReader(reader)
 
@@ -33,13 +60,17 @@ func ParseVocabularyCSV(reader io.Reader) ([]*models.Vocabulary, error) {
 
 	// Skip header row if it exists (check if first row looks like headers)
 	var startRow int
+	columns := vocabularyCSVColumns
 	if len(records) > 0 && isHeaderRow(records[0]) {
 		startRow = 1
+		if columns, err = vocabularyHeaderColumns(records[0]); err != nil {
+			return nil, err
+		}
 	}
 
 	var vocabularies []*models.Vocabulary
 	var validationErrors []string
-	maoriSet := make(map[string]int) // Track Māori words and their row numbers
+	maoriSet := make(map[string]int) // Track normalised Māori words and their row numbers
 
 	for i, record := range records[startRow:] {
 		rowNum := startRow + i + 1
@@ -52,9 +83,15 @@ func ParseVocabularyCSV(reader io.Reader) ([]*models.Vocabulary, error) {
 		}
 
 		// Parse and validate each field
-		maori := strings.TrimSpace(record[0])
-		english := strings.TrimSpace(record[1])
-		description := strings.TrimSpace(record[2])
+		fields := make(map[string]string, len(columns))
+		for col, name := range columns {
+			if col < len(record) && name != "" {
+				fields[name] = strings.TrimSpace(record[col])
+			}
+		}
+		maori := fields["maori"]
+		english := fields["english"]
+		description := fields["description"]
 
 		// Validate required fields
 		if maori == "" {
@@ -73,13 +110,13 @@ func ParseVocabularyCSV(reader io.Reader) ([]*models.Vocabulary, error) {
 			continue
 		}
 
-		// Check for duplicates within CSV
-		if existingRow, exists := maoriSet[maori]; exists {
+		// Check for duplicates within CSV, counting spellings that search treats as equal
+		if existingRow, exists := maoriSet[NormalizeMaori(maori)]; exists {
 			validationErrors = append(validationErrors,
 				fmt.Sprintf("Row %d: Duplicate Māori word '%s' (first seen in row %d)", rowNum, maori, existingRow))
 			continue
 		}
-		maoriSet[maori] = rowNum
+		maoriSet[NormalizeMaori(maori)] = rowNum
 
 		// Validate field lengths
 		if len(maori) > 200 {
@@ -100,9 +137,25 @@ func ParseVocabularyCSV(reader io.Reader) ([]*models.Vocabulary, error) {
 
 		// Create vocabulary request and convert to model
 		vocabReq := &models.VocabularyRequest{
-			Maori:       maori,
-			English:     english,
-			Description: description,
+			Maori:        maori,
+			English:      english,
+			Description:  description,
+			PartOfSpeech: strings.ToLower(fields["part_of_speech"]),
+			Variants:     splitCSVList(fields["variants"]),
+			Senses:       splitCSVList(fields["senses"]),
+			Tags:         splitCSVList(fields["tags"]),
+			AudioURL:     fields["audio_url"],
+		}
+
+		rowErrors := parseVocabularyDetails(vocabReq, fields)
+		for _, e := range validation.ValidateVocabularyDetails(vocabReq).Errors {
+			rowErrors = append(rowErrors, fmt.Sprintf("%s (%s)", e.Message, e.Field))
+		}
+		if len(rowErrors) > 0 {
+			for _, message := range rowErrors {
+				validationErrors = append(validationErrors, fmt.Sprintf("Row %d: %s", rowNum, message))
+			}
+			continue
 		}
 
 		vocabulary := vocabReq.ToVocabulary()
@@ -117,6 +170,81 @@ func ParseVocabularyCSV(reader io.Reader) ([]*models.Vocabulary, error) {
 	return vocabularies, nil
 }
 
+// vocabularyHeaderColumns maps each header cell to a column name. Unknown columns are ignored.
+func vocabularyHeaderColumns(header []string) ([]string, error) {
+	known := make(map[string]bool, len(vocabularyCSVColumns))
+	for _, name := range vocabularyCSVColumns {
+		known[name] = true
+	}
+
+	columns := make([]string, len(header))
+	found := make(map[string]bool)
+	for i, cell := range header {
+		name := strings.ToLower(strings.TrimSpace(cell))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		if alias, ok := vocabularyCSVAliases[name]; ok {
+			name = alias
+		}
+		if known[name] {
+			columns[i] = name
+			found[name] = true
+		}
+	}
+
+	for _, required := range []string{"maori", "english", "description"} {
+		if !found[required] {
+			return nil, fmt.Errorf("CSV header is missing the %s column", required)
+		}
+	}
+	return columns, nil
+}
+
+// parseVocabularyDetails fills the structured optional fields of a request from a CSV row
+func parseVocabularyDetails(req *models.VocabularyRequest, fields map[string]string) []string {
+	var rowErrors []string
+
+	for _, item := range splitCSVList(fields["forms"]) {
+		kind, form, ok := strings.Cut(item, ":")
+		if !ok {
+			rowErrors = append(rowErrors, fmt.Sprintf("form '%s' must be written as kind:form, e.g. passive:karangatia", item))
+			continue
+		}
+		req.Forms = append(req.Forms, models.VocabularyForm{
+			Kind: strings.ToLower(strings.TrimSpace(kind)),
+			Form: strings.TrimSpace(form),
+		})
+	}
+
+	for _, item := range splitCSVList(fields["examples"]) {
+		maori, english, _ := strings.Cut(item, "|")
+		req.Examples = append(req.Examples, models.VocabularyExample{
+			Maori:   strings.TrimSpace(maori),
+			English: strings.TrimSpace(english),
+		})
+	}
+
+	if difficulty := fields["difficulty"]; difficulty != "" {
+		level, err := strconv.Atoi(difficulty)
+		if err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("difficulty '%s' must be a number from 1 to 5", difficulty))
+		}
+		req.Difficulty = level
+	}
+
+	return rowErrors
+}
+
+// splitCSVList splits a semicolon-separated CSV cell into trimmed, non-empty items
+func splitCSVList(cell string) []string {
+	var items []string
+	for _, item := range strings.Split(cell, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // isHeaderRow checks if a row looks like a header row
 func isHeaderRow(row []string) bool {
 	if len(row) < 3 {
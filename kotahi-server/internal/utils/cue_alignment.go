@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+// CueAlignment groups cues from two tracks that cover the same stretch of speech
+type CueAlignment struct {
+	Source    []int // 1-based indexes of the source cues
+	Target    []int // 1-based indexes of the target cues, empty if nothing overlaps
+	StartTime float64
+	EndTime   float64
+}
+
+// AlignCues pairs the cues of two tracks by time overlap. Each cue is linked to the cue on
+// the other side that it overlaps most, and linked cues are grouped together, so one source
+// cue can pair with several target cues and several source cues with one target cue.
+// Target cues that overlap no source cue are left out.
+func AlignCues(source, target []Cue) []CueAlignment {
+	// Union-find over source cues 0..n-1 and target cues n..n+m-1
+	n := len(source)
+	parent := make([]int, n+len(target))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		parent[find(a)] = find(b)
+	}
+
+	linked := make([]bool, len(target))
+	for i := range source {
+		if j := bestOverlap(source[i], target); j >= 0 {
+			union(i, n+j)
+			linked[j] = true
+		}
+	}
+	for j := range target {
+		if i := bestOverlap(target[j], source); i >= 0 {
+			union(i, n+j)
+			linked[j] = true
+		}
+	}
+
+	groups := make(map[int]*CueAlignment)
+	var order []int
+	group := func(root int) *CueAlignment {
+		if g, ok := groups[root]; ok {
+			return g
+		}
+		g := &CueAlignment{StartTime: -1}
+		groups[root] = g
+		order = append(order, root)
+		return g
+	}
+	extend := func(g *CueAlignment, cue Cue) {
+		if g.StartTime < 0 || cue.StartTime < g.StartTime {
+			g.StartTime = cue.StartTime
+		}
+		if cue.EndTime > g.EndTime {
+			g.EndTime = cue.EndTime
+		}
+	}
+
+	for i, cue := range source {
+		g := group(find(i))
+		g.Source = append(g.Source, i+1)
+		extend(g, cue)
+	}
+	for j, cue := range target {
+		if !linked[j] {
+			continue
+		}
+		g := group(find(n + j))
+		g.Target = append(g.Target, j+1)
+		extend(g, cue)
+	}
+
+	alignments := make([]CueAlignment, 0, len(order))
+	for _, root := range order {
+		alignments = append(alignments, *groups[root])
+	}
+	sort.SliceStable(alignments, func(i, j int) bool {
+		return alignments[i].StartTime < alignments[j].StartTime
+	})
+
+	return alignments
+}
+
+// JoinCueText joins the text of the cues at the given 1-based indexes into one line
+func JoinCueText(cues []Cue, indexes []int) string {
+	var parts []string
+	for _, index := range indexes {
+		if index < 1 || index > len(cues) {
+			continue
+		}
+		if text := strings.Join(strings.Fields(cues[index-1].Text), " "); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// bestOverlap returns the index of the cue that overlaps the given cue the most, or -1
+func bestOverlap(cue Cue, others []Cue) int {
+	best, most := -1, 0.0
+	for i, other := range others {
+		overlap := minFloat(cue.EndTime, other.EndTime) - maxFloat(cue.StartTime, other.StartTime)
+		if overlap > most {
+			best, most = i, overlap
+		}
+	}
+	return best
+}
+
+// minFloat returns the smaller of two floats
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxFloat returns the larger of two floats
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,382 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrCueNotFound is returned when a cue index is outside the document
+var ErrCueNotFound = errors.New("cue not found")
+
+// CueEdit holds the fields to change on a cue; nil fields are left unchanged
+type CueEdit struct {
+	ID        *string  `json:"id"`
+	StartTime *float64 `json:"start_time"`
+	EndTime   *float64 `json:"end_time"`
+	Text      *string  `json:"text"` // Cue payload, may contain WebVTT markup such as <i> or <v Speaker>
+}
+
+// EditCue changes the text or timing of the cue at the given 1-based index
+func EditCue(doc *SubtitleDocument, index int, edit CueEdit) error {
+	cue, err := cueAt(doc, index)
+	if err != nil {
+		return err
+	}
+
+	updated := *cue
+	if edit.ID != nil {
+		updated.ID = strings.TrimSpace(*edit.ID)
+	}
+	if edit.StartTime != nil {
+		updated.StartTime = *edit.StartTime
+	}
+	if edit.EndTime != nil {
+		updated.EndTime = *edit.EndTime
+	}
+	if edit.Text != nil {
+		if err := setCueText(&updated, *edit.Text); err != nil {
+			return err
+		}
+	}
+	if err := checkCueTiming(updated.StartTime, updated.EndTime); err != nil {
+		return err
+	}
+
+	*cue = updated
+	renumberCues(doc)
+	return nil
+}
+
+// InsertCue adds a new cue, keeping the cues ordered by start time
+func InsertCue(doc *SubtitleDocument, id string, start, end float64, text string) (*Cue, error) {
+	if err := checkCueTiming(start, end); err != nil {
+		return nil, err
+	}
+
+	cue := Cue{ID: strings.TrimSpace(id), StartTime: start, EndTime: end}
+	if err := setCueText(&cue, text); err != nil {
+		return nil, err
+	}
+
+	doc.Cues = append(doc.Cues, cue)
+	renumberCues(doc)
+
+	for i := range doc.Cues {
+		if doc.Cues[i].StartTime == start && doc.Cues[i].EndTime == end && doc.Cues[i].RawText == cue.RawText {
+			return &doc.Cues[i], nil
+		}
+	}
+	return &doc.Cues[len(doc.Cues)-1], nil
+}
+
+// SplitCue splits the cue at the given index into two cues at the given time.
+// The text is split at position, a rune offset into the cue payload; if position is
+// negative the text is split at the word boundary closest to the time.
+func SplitCue(doc *SubtitleDocument, index int, at float64, position int) error {
+	cue, err := cueAt(doc, index)
+	if err != nil {
+		return err
+	}
+
+	if at <= cue.StartTime || at >= cue.EndTime {
+		return fmt.Errorf("split time must be between the cue's start and end times")
+	}
+
+	runes := []rune(cue.RawText)
+	if position < 0 {
+		position = wordBoundaryNear(runes, int(float64(len(runes))*(at-cue.StartTime)/(cue.EndTime-cue.StartTime)))
+	}
+	if position <= 0 || position >= len(runes) {
+		return fmt.Errorf("split position must be inside the cue text")
+	}
+
+	first := *cue
+	second := *cue
+	second.ID = ""
+	first.EndTime = at
+	second.StartTime = at
+	if err := setCueText(&first, string(runes[:position])); err != nil {
+		return err
+	}
+	if err := setCueText(&second, string(runes[position:])); err != nil {
+		return err
+	}
+	if first.Text == "" || second.Text == "" {
+		return fmt.Errorf("split would leave an empty cue")
+	}
+
+	i := index - 1
+	doc.Cues = append(doc.Cues[:i], append([]Cue{first, second}, doc.Cues[i+1:]...)...)
+	renumberCues(doc)
+	return nil
+}
+
+// MergeCues merges the cue at the given index with the cue that follows it
+func MergeCues(doc *SubtitleDocument, index int) error {
+	first, err := cueAt(doc, index)
+	if err != nil {
+		return err
+	}
+	if index >= len(doc.Cues) {
+		return fmt.Errorf("cue %d is the last cue and has nothing to merge with", index)
+	}
+	second := doc.Cues[index]
+
+	merged := *first
+	if second.EndTime > merged.EndTime {
+		merged.EndTime = second.EndTime
+	}
+	if err := setCueText(&merged, first.RawText+"\n"+second.RawText); err != nil {
+		return err
+	}
+
+	i := index - 1
+	doc.Cues = append(doc.Cues[:i], append([]Cue{merged}, doc.Cues[i+2:]...)...)
+	renumberCues(doc)
+	return nil
+}
+
+// DeleteCue removes the cue at the given index
+func DeleteCue(doc *SubtitleDocument, index int) error {
+	if _, err := cueAt(doc, index); err != nil {
+		return err
+	}
+
+	doc.Cues = append(doc.Cues[:index-1], doc.Cues[index:]...)
+	renumberCues(doc)
+	return nil
+}
+
+// cueAt returns the cue at a 1-based index
+func cueAt(doc *SubtitleDocument, index int) (*Cue, error) {
+	if index < 1 || index > len(doc.Cues) {
+		return nil, ErrCueNotFound
+	}
+	return &doc.Cues[index-1], nil
+}
+
+// setCueText sets a cue's payload and derived plain text and speaker
+func setCueText(cue *Cue, text string) error {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	if strings.Contains(text, "-->") {
+		return fmt.Errorf("cue text cannot contain '-->'")
+	}
+
+	// Blank lines would end the cue, so drop them
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	cue.RawText = strings.Join(lines, "\n")
+	cue.Text, cue.Speaker = stripCueMarkup(cue.RawText)
+	return nil
+}
+
+// checkCueTiming validates a cue's start and end times
+func checkCueTiming(start, end float64) error {
+	if start < 0 {
+		return fmt.Errorf("start time cannot be negative")
+	}
+	if end <= start {
+		return fmt.Errorf("end time must be after start time")
+	}
+	return nil
+}
+
+// renumberCues sorts cues by start time and updates their indexes
+func renumberCues(doc *SubtitleDocument) {
+	sort.SliceStable(doc.Cues, func(i, j int) bool {
+		return doc.Cues[i].StartTime < doc.Cues[j].StartTime
+	})
+	for i := range doc.Cues {
+		doc.Cues[i].Index = i + 1
+	}
+}
+
+// wordBoundaryNear returns the whitespace position closest to target, or target if there is none
+func wordBoundaryNear(runes []rune, target int) int {
+	best := -1
+	for i, r := range runes {
+		if !unicode.IsSpace(r) {
+			continue
+		}
+		if best < 0 || abs(i-target) < abs(best-target) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return target
+	}
+	return best
+}
+
+// abs returns the absolute value of an int
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Cue change operations reported by DiffCues
+const (
+	CueAdded    = "added"
+	CueRemoved  = "removed"
+	CueModified = "modified"
+)
+
+// CueChange describes a difference between two versions of a track
+type CueChange struct {
+	Op  string `json:"op"`
+	Old *Cue   `json:"old,omitempty"`
+	New *Cue   `json:"new,omitempty"`
+}
+
+// DiffCues compares two cue lists and returns the cues that were added, removed or modified.
+// Unchanged cues are matched with a longest common subsequence; removed and added cues
+// between the same unchanged neighbours are paired up as modifications.
+func DiffCues(from, to []Cue) []CueChange {
+	same := func(a, b Cue) bool {
+		return a.StartTime == b.StartTime && a.EndTime == b.EndTime && a.RawText == b.RawText && a.ID == b.ID
+	}
+
+	// lcs[i][j] is the length of the common subsequence of from[i:] and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if same(from[i], to[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var changes []CueChange
+	var removed, added []Cue
+	flush := func() {
+		n := len(removed)
+		if len(added) < n {
+			n = len(added)
+		}
+		for k := 0; k < n; k++ {
+			changes = append(changes, CueChange{Op: CueModified, Old: &removed[k], New: &added[k]})
+		}
+		for k := n; k < len(removed); k++ {
+			changes = append(changes, CueChange{Op: CueRemoved, Old: &removed[k]})
+		}
+		for k := n; k < len(added); k++ {
+			changes = append(changes, CueChange{Op: CueAdded, New: &added[k]})
+		}
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		switch {
+		case i < len(from) && j < len(to) && same(from[i], to[j]):
+			flush()
+			i++
+			j++
+		case j >= len(to) || (i < len(from) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, from[i])
+			i++
+		default:
+			added = append(added, to[j])
+			j++
+		}
+	}
+	flush()
+
+	return changes
+}
+
+// TimingAnchor maps a time in the current subtitles to the time it should be at
+type TimingAnchor struct {
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+}
+
+// TimingAdjustment shifts and rescales cue timings. If two anchors are given, times are
+// first mapped linearly so that each anchor's From lands on its To; the offset is then added.
+type TimingAdjustment struct {
+	Offset  float64        `json:"offset"`
+	Anchors []TimingAnchor `json:"anchors"`
+}
+
+// CueTimingChange describes how a cue's timing was changed
+type CueTimingChange struct {
+	Index        int     `json:"index"`
+	Text         string  `json:"text"`
+	OldStartTime float64 `json:"old_start_time"`
+	OldEndTime   float64 `json:"old_end_time"`
+	StartTime    float64 `json:"start_time"`
+	EndTime      float64 `json:"end_time"`
+}
+
+// RetimeCues applies a timing adjustment to every cue and returns the cues that changed.
+// Start times that would become negative are clamped to zero.
+func RetimeCues(doc *SubtitleDocument, adjustment TimingAdjustment) ([]CueTimingChange, error) {
+	scale, base, target := 1.0, 0.0, 0.0
+	switch len(adjustment.Anchors) {
+	case 0:
+	case 2:
+		a, b := adjustment.Anchors[0], adjustment.Anchors[1]
+		if a.From == b.From {
+			return nil, fmt.Errorf("anchors must be at different times")
+		}
+		scale = (b.To - a.To) / (b.From - a.From)
+		if scale <= 0 {
+			return nil, fmt.Errorf("anchors must keep the cues in the same order")
+		}
+		base, target = a.From, a.To
+	default:
+		return nil, fmt.Errorf("exactly two anchors are needed to rescale timings")
+	}
+
+	retime := func(t float64) float64 {
+		t = target + (t-base)*scale + adjustment.Offset
+		// Round to whole milliseconds, the precision of a VTT timestamp
+		return math.Round(t*1000) / 1000
+	}
+
+	var changes []CueTimingChange
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+		start, end := retime(cue.StartTime), retime(cue.EndTime)
+		if end <= 0 {
+			return nil, fmt.Errorf("cue %d would end before the start of the video", cue.Index)
+		}
+		if start < 0 {
+			start = 0
+		}
+		if start == cue.StartTime && end == cue.EndTime {
+			continue
+		}
+
+		changes = append(changes, CueTimingChange{
+			Index:        cue.Index,
+			Text:         cue.Text,
+			OldStartTime: cue.StartTime,
+			OldEndTime:   cue.EndTime,
+			StartTime:    start,
+			EndTime:      end,
+		})
+		cue.StartTime, cue.EndTime = start, end
+	}
+
+	renumberCues(doc)
+	return changes, nil
+}
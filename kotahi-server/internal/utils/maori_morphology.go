@@ -0,0 +1,126 @@
+package utils
+
+import "strings"
+
+// minMaoriStemLength is the shortest stem, in letters, an inflected word is reduced to. Shorter stems
+// would turn common particles such as "kia" into inflections of "ki".
+const minMaoriStemLength = 3
+
+// maoriSuffixes are the passive and nominalising endings removed when looking for a word's stem,
+// longest first so "karangatanga" is reduced to "karanga" rather than "karangata"
+var maoriSuffixes = []string{
+	// Nominalisations
+	"tanga", "hanga", "ranga", "kanga", "anga", "nga",
+	// Passives
+	"whia", "ngia", "tia", "hia", "kia", "mia", "ria", "ina", "na", "ia", "a",
+}
+
+// maoriPrefixes are the prefixes removed when looking for a word's stem: whaka- makes causatives
+// and kai- makes agent nouns, as in "whakaako" and "kaiako" from "ako"
+var maoriPrefixes = []string{"whaka", "kai"}
+
+// maoriFunctionWords are particles, articles, pronouns and other function words long enough to be
+// stems, written as NormalizeMaori writes them. They take no affixes, so they are never a stem:
+// "maia" is not a passive of "mai", nor "kainga" a kai- derivation of "nga".
+var maoriFunctionWords = map[string]bool{
+	"ake": true, "ana": true, "ano": true, "ara": true, "atu": true, "aua": true, "ehara": true,
+	"ena": true, "engari": true, "era": true, "hei": true, "hoki": true, "iho": true, "ina": true,
+	"kahore": true, "kaore": true, "kau": true, "kei": true, "kia": true, "koa": true, "kore": true,
+	"kua": true, "mai": true, "mehemea": true, "nei": true, "nga": true, "noa": true, "pea": true,
+	"rawa": true, "tena": true, "tenei": true, "tera": true, "tonu": true,
+	// Pronouns and possessives
+	"ahau": true, "koe": true, "ia": true, "korua": true, "koutou": true, "maua": true, "matou": true,
+	"raua": true, "ratou": true, "taua": true, "tatou": true, "taku": true, "toku": true,
+	"tana": true, "tona": true, "aku": true, "oku": true, "ona": true,
+}
+
+// MaoriStem is a word a te reo Māori word may be an inflection of
+type MaoriStem struct {
+	Word     string // Stem, normalised like the word
+	Start    int    // Rune offset of the stem in the word
+	Suffixed bool   // A passive or nominalising suffix was removed
+	Prefixed bool   // The whaka- or kai- prefix was removed
+}
+
+// MaoriStems returns the words a te reo Māori word may be an inflection of, most likely first.
+// The word must already be normalised with NormalizeMaori. Passive and nominalising suffixes are
+// removed first, then the whaka- and kai- prefixes, then both. A stem must be at least 3 letters
+// with two vowels, end in a vowel as every Māori word does, and not be a function word. The stems
+// are only guesses: it is up to the caller to decide whether a word with that stem takes the affixes.
+func MaoriStems(word string) []MaoriStem {
+	var stems []MaoriStem
+	seen := map[string]bool{word: true}
+	add := func(stem MaoriStem) {
+		if isMaoriStem(stem.Word) && !seen[stem.Word] {
+			seen[stem.Word] = true
+			stems = append(stems, stem)
+		}
+	}
+
+	unprefixed := stripMaoriPrefixes(word)
+	for _, stem := range stripMaoriSuffixes(word) {
+		add(stem)
+	}
+	for _, stem := range unprefixed {
+		add(stem)
+	}
+	for _, base := range unprefixed {
+		for _, stem := range stripMaoriSuffixes(base.Word) {
+			stem.Start = base.Start
+			stem.Prefixed = true
+			add(stem)
+		}
+	}
+	return stems
+}
+
+// isMaoriStem reports whether stem could be a word that takes affixes
+func isMaoriStem(stem string) bool {
+	if len(stem) < minMaoriStemLength || !isMaoriVowel(stem[len(stem)-1]) || maoriFunctionWords[stem] {
+		return false
+	}
+
+	vowels := 0
+	for i := 0; i < len(stem); i++ {
+		if isMaoriVowel(stem[i]) {
+			vowels++
+		}
+	}
+	return vowels >= 2
+}
+
+// stripMaoriSuffixes returns word without each suffix it ends in
+func stripMaoriSuffixes(word string) []MaoriStem {
+	var stems []MaoriStem
+	for _, suffix := range maoriSuffixes {
+		if stem, ok := strings.CutSuffix(word, suffix); ok && stem != "" {
+			stems = append(stems, MaoriStem{Word: stem, Suffixed: true})
+			if isMaoriVowel(suffix[0]) {
+				stems = append(stems, MaoriStem{Word: stem + suffix[:1], Suffixed: true})
+			}
+		}
+	}
+	return stems
+}
+
+// stripMaoriPrefixes returns word without each prefix it starts with
+func stripMaoriPrefixes(word string) []MaoriStem {
+	var stems []MaoriStem
+	for _, prefix := range maoriPrefixes {
+		if stem, ok := strings.CutPrefix(word, prefix); ok && stem != "" {
+			stems = append(stems, MaoriStem{Word: stem, Start: len(prefix), Prefixed: true})
+			if last := prefix[len(prefix)-1:]; isMaoriVowel(last[0]) {
+				stems = append(stems, MaoriStem{Word: last + stem, Start: len(prefix) - 1, Prefixed: true})
+			}
+		}
+	}
+	return stems
+}
+
+// isMaoriVowel reports whether c is a plain vowel. NormalizeMaori writes a doubled vowel once, so
+// where an affix starting or ending in a vowel meets a stem with the same vowel, the stem is also
+// tried with the vowel put back: "whakaako" is normalised to "whakako", and without whaka- that is "ako".
+func isMaoriVowel(c byte) bool {
+	_, ok := maoriVowels[rune(c)]
+	return ok
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"video-player-backend/internal/models"
+)
+
+func TestMaoriStems(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string // Each stem written as word@start, with -s and p- for a suffix or prefix removed
+	}{
+		{"karangatia", []string{"karanga@0-s", "karangati@0-s"}},
+		{"karangatanga", []string{"karanga@0-s", "karangata@0-s"}},
+		{"whakako", []string{"ako@4p-"}}, // "whakaako"
+		{"whakakona", []string{"whakako@0-s", "kona@5p-", "akona@4p-", "ako@4p-s"}},
+		{"kaiako", []string{"ako@3p-", "iako@2p-"}},
+		{"kainga", []string{"kai@0-s", "inga@2p-"}},
+		{"patua", []string{"patu@0-s"}},
+		{"whakapapa", []string{"papa@5p-", "apapa@4p-"}},
+		{"maia", nil},
+		{"kina", nil},
+		{"ako", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, stem := range MaoriStems(tt.word) {
+			summary := stem.Word + "@" + string(rune('0'+stem.Start))
+			if stem.Prefixed {
+				summary += "p"
+			}
+			summary += "-"
+			if stem.Suffixed {
+				summary += "s"
+			}
+			got = append(got, summary)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("MaoriStems(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestMaoriStemsRejectsShortStemsAndFunctionWords(t *testing.T) {
+	tests := []struct {
+		word string
+		stem string
+	}{
+		{"maia", "mai"},     // mai is a particle
+		{"kiatia", "kia"},   // So is kia
+		{"kainga", "nga"},   // nga is the plural article
+		{"whakanga", "nga"}, // Even after whaka-
+		{"tonuhia", "tonu"},
+		{"koutoua", "koutou"}, // A pronoun
+		{"kina", "ki"},        // Too short
+		{"kainga", "kaing"},   // Doesn't end in a vowel
+		{"karangatia", "karangat"},
+		{"kainga", "ing"},
+		{"whakaanga", "nga"},
+	}
+
+	for _, tt := range tests {
+		for _, stem := range MaoriStems(tt.word) {
+			if stem.Word == tt.stem {
+				t.Errorf("MaoriStems(%q) includes %q", tt.word, tt.stem)
+			}
+		}
+	}
+}
+
+func TestMarkMaoriVowelLength(t *testing.T) {
+	tests := map[string]string{
+		"Māori":      "māori",
+		"Maaori":     "māori",
+		"Māori":     "māori",
+		"maori":      "maori",
+		"kāinga":     "kāinga",
+		"whaka-aako": "whakāko",
+	}
+	for text, want := range tests {
+		if got := markMaoriVowelLength(text); got != want {
+			t.Errorf("markMaoriVowelLength(%q) = %q, want %q", text, got, want)
+		}
+		if len([]rune(markMaoriVowelLength(text))) != len([]rune(NormalizeMaori(text))) {
+			t.Errorf("markMaoriVowelLength(%q) doesn't line up with NormalizeMaori", text)
+		}
+	}
+}
+
+func TestFindMatchesInflections(t *testing.T) {
+	entry := func(maori, partOfSpeech string) *models.Vocabulary {
+		v := vocab(maori)
+		v.PartOfSpeech = partOfSpeech
+		return v
+	}
+	vocabulary := []*models.Vocabulary{
+		entry("karanga", "verb"),
+		entry("ako", "verb"),
+		entry("kai", "noun"),
+		entry("mai", "particle"),
+		entry("papa", "noun"),
+		entry("pai", "stative"),
+		entry("mahi", ""),
+		entry("waiata", "noun"),
+		entry("whakarongo", "verb"),
+		entry("whakatau", "verb"),
+		entry("tau", "verb"),
+	}
+	indexer := NewVocabularyIndexer(vocabulary)
+
+	tests := []struct {
+		text string
+		want string // The entry the word matches, empty for none
+	}{
+		{"karangatia", "karanga"},
+		{"karangatanga", "karanga"},
+		{"kaiako", "ako"},
+		{"akona", "ako"},
+		{"kainga", "kai"},   // Written without macrons, "eaten" and "home" can't be told apart
+		{"kāinga", ""},      // Home, not a form of kai
+		{"kaainga", ""},     // Home, with the long vowel doubled
+		{"maia", ""},        // Brave, not a passive of the particle mai
+		{"whakapapa", ""},   // Only verbs and statives take whaka-, and papa is a noun
+		{"whakapai", "pai"}, // A stative does
+		{"paitanga", ""},    // But only verbs and nouns take suffixes
+		{"waiatatia", "waiata"},
+		{"mahia", ""},            // Entries without a part of speech are only matched as written
+		{"whakatau", "whakatau"}, // A headword is never read as a derivation of another
+		{"whakarongona", "whakarongo"},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, match := range indexer.FindMatches(tt.text) {
+			got = append(got, match.Vocabulary.Maori)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("FindMatches(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// combiningMacron is the combining character used in decomposed spellings, written after the vowel
+const combiningMacron = '\u0304'
+
+// maoriJoiners are the apostrophes and hyphens that appear inside words and are ignored when comparing
+const maoriJoiners = "'‘’ʻ`-‐‑–"
+
+// maoriVowels maps each vowel to its plain and macronised spellings, in both cases for the macronised ones
+var maoriVowels = map[rune]string{
+	'a': "aāĀ",
+	'e': "eēĒ",
+	'i': "iīĪ",
+	'o': "oōŌ",
+	'u': "uūŪ",
+}
+
+// NormalizeMaori returns the form of a te reo Māori word or phrase used for comparing spellings.
+// The text is lowercased, macrons are removed whether they are composed or decomposed, apostrophes
+// and hyphens are dropped and doubled vowels are written once, so "Māori", "Maaori", "maori" and
+// the decomposed "Ma\u0304ori" all become "maori". The result is only used for comparing; the
+// original text is what is shown.
+func NormalizeMaori(text string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		if r == combiningMacron || strings.ContainsRune(maoriJoiners, r) {
+			continue
+		}
+		if _, vowel := maoriVowels[r]; vowel && r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return norm.NFC.String(b.String())
+}
+
+// markMaoriVowelLength returns text normalised like NormalizeMaori, except that a long vowel, whether
+// written with a macron or doubled, keeps its macron: "Maaori" and "Māori" become "māori" and
+// "maori" stays as it is. Its letters line up one for one with those NormalizeMaori returns.
+func markMaoriVowelLength(text string) string {
+	var letters []rune
+	long := make(map[int]bool) // Positions in letters of long vowels
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		if strings.ContainsRune(maoriJoiners, r) {
+			continue
+		}
+		last := len(letters) - 1
+		if r == combiningMacron {
+			if last >= 0 {
+				long[last] = true
+			}
+			continue
+		}
+		if _, vowel := maoriVowels[r]; vowel && last >= 0 && r == letters[last] {
+			long[last] = true
+			continue
+		}
+		letters = append(letters, r)
+	}
+
+	for i, r := range letters {
+		if spellings, ok := maoriVowels[r]; ok && long[i] {
+			letters[i] = []rune(spellings)[1]
+		}
+	}
+	return norm.NFC.String(string(letters))
+}
+
+// MaoriSearchPattern returns a case-insensitive regular expression that finds query in stored text
+// however its vowels are spelled, for database searches where the text can't be normalised first.
+// Each vowel matches its plain, macronised, decomposed or doubled spelling, and an apostrophe or
+// hyphen may appear between any two letters. Other characters are matched literally.
+func MaoriSearchPattern(query string) string {
+	var b strings.Builder
+	joiner := "[" + strings.ReplaceAll(regexp.QuoteMeta(maoriJoiners), "-", `\-`) + "]?"
+
+	space := false
+	for i, r := range []rune(NormalizeMaori(strings.TrimSpace(query))) {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteString(`\s+`)
+			}
+			space = true
+			continue
+		}
+		if i > 0 && !space {
+			b.WriteString(joiner)
+		}
+		space = false
+
+		if spellings, ok := maoriVowels[r]; ok {
+			b.WriteString("(?:[" + spellings + "]\\x{0304}?)+")
+		} else {
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
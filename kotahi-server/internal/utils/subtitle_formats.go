@@ -0,0 +1,570 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Subtitle formats that can be imported
+const (
+	SubtitleFormatVTT  = "vtt"
+	SubtitleFormatSRT  = "srt"
+	SubtitleFormatSBV  = "sbv"
+	SubtitleFormatTTML = "ttml"
+)
+
+// subtitleFormatExtensions maps file extensions to subtitle formats
+var subtitleFormatExtensions = map[string]string{
+	".vtt":  SubtitleFormatVTT,
+	".srt":  SubtitleFormatSRT,
+	".sbv":  SubtitleFormatSBV,
+	".ttml": SubtitleFormatTTML,
+	".dfxp": SubtitleFormatTTML,
+	".xml":  SubtitleFormatTTML,
+}
+
+var (
+	// srtTimingRegex matches an SRT timing line, optionally followed by X1/Y1 coordinates
+	srtTimingRegex = regexp.MustCompile(`^\s*(\S+)\s*-->\s*(\S+)(.*)$`)
+	// sbvTimingRegex matches an SBV timing line such as 0:00:01.000,0:00:04.000
+	sbvTimingRegex = regexp.MustCompile(`^\s*(\d+:\d{1,2}:\d{1,2}\.\d{1,3})\s*,\s*(\d+:\d{1,2}:\d{1,2}\.\d{1,3})\s*$`)
+	// srtTagRegex matches HTML-like tags and {\...} override blocks used in SRT files
+	srtTagRegex = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+	// ttmlOffsetRegex matches TTML offset time expressions such as 1.5s, 200ms or 30f
+	ttmlOffsetRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|m|s|ms|f|t)$`)
+	// ttmlClockRegex matches TTML clock time expressions such as 00:00:01.500 or 00:00:01:12
+	ttmlClockRegex = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})(?:(\.\d+)|:(\d+(?:\.\d+)?))?$`)
+)
+
+// SupportedSubtitleExtensions returns the file extensions that can be imported as subtitles
+func SupportedSubtitleExtensions() []string {
+	extensions := make([]string, 0, len(subtitleFormatExtensions))
+	for ext := range subtitleFormatExtensions {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// DetectSubtitleFormat returns the subtitle format for a filename, or an empty string if unsupported
+func DetectSubtitleFormat(filename string) string {
+	return subtitleFormatExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// ParseSubtitle parses subtitle content in the given format into a subtitle document
+func ParseSubtitle(format, content string) (*SubtitleDocument, error) {
+	switch format {
+	case SubtitleFormatVTT:
+		return ParseWebVTT(content)
+	case SubtitleFormatSRT:
+		return ParseSRT(content)
+	case SubtitleFormatSBV:
+		return ParseSBV(content)
+	case SubtitleFormatTTML:
+		return ParseTTML(content)
+	default:
+		return nil, fmt.Errorf("unsupported subtitle format '%s'", format)
+	}
+}
+
+// ConvertToWebVTT parses a subtitle file, detecting its format from the filename, and returns
+// the content as WebVTT. VTT files are returned unchanged; other formats are converted to a canonical VTT.
+func ConvertToWebVTT(filename string, content []byte) (string, *SubtitleDocument, string, error) {
+	format := DetectSubtitleFormat(filename)
+	if format == "" {
+		return "", nil, "", fmt.Errorf("unsupported subtitle file type '%s'", filepath.Ext(filename))
+	}
+
+	doc, err := ParseSubtitle(format, string(content))
+	if err != nil {
+		return "", nil, format, err
+	}
+	if len(doc.Cues) == 0 {
+		return "", doc, format, fmt.Errorf("no cues found in %s file", strings.ToUpper(format))
+	}
+
+	if format == SubtitleFormatVTT {
+		return string(content), doc, format, nil
+	}
+	return WriteWebVTT(doc), doc, format, nil
+}
+
+// WriteWebVTT writes a subtitle document as canonical WebVTT
+func WriteWebVTT(doc *SubtitleDocument) string {
+	var b strings.Builder
+
+	b.WriteString("WEBVTT")
+	if doc.Header != "" {
+		b.WriteString(" " + doc.Header)
+	}
+	b.WriteString("\n\n")
+
+	// Notes are written together, as the document doesn't record where they were between the cues
+	for _, note := range doc.Notes {
+		writeVTTNote(&b, note)
+	}
+	for _, region := range doc.Regions {
+		b.WriteString("REGION\n" + region + "\n\n")
+	}
+	for _, style := range doc.Styles {
+		b.WriteString("STYLE\n" + style + "\n\n")
+	}
+
+	for _, cue := range doc.Cues {
+		if cue.ID != "" {
+			b.WriteString(cue.ID + "\n")
+		}
+
+		b.WriteString(FormatVTTTimestamp(cue.StartTime) + " --> " + FormatVTTTimestamp(cue.EndTime))
+		names := make([]string, 0, len(cue.Settings))
+		for name := range cue.Settings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b.WriteString(" " + name + ":" + cue.Settings[name])
+		}
+		b.WriteString("\n")
+
+		payload := cue.RawText
+		if payload == "" {
+			payload = escapeCueText(cue.Text)
+		}
+		// Blank lines would end the cue early
+		for _, line := range strings.Split(payload, "\n") {
+			if strings.TrimSpace(line) != "" {
+				b.WriteString(line + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// writeVTTNote writes a NOTE block, on one line when the note fits on one
+func writeVTTNote(b *strings.Builder, note string) {
+	var lines []string
+	for _, line := range strings.Split(note, "\n") {
+		// Blank lines would end the note early
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	switch len(lines) {
+	case 0:
+		b.WriteString("NOTE\n\n")
+	case 1:
+		b.WriteString("NOTE " + lines[0] + "\n\n")
+	default:
+		b.WriteString("NOTE\n" + strings.Join(lines, "\n") + "\n\n")
+	}
+}
+
+// ParseSRT parses SubRip (SRT) content into a subtitle document
+func ParseSRT(content string) (*SubtitleDocument, error) {
+	if !utf8.ValidString(content) {
+		return nil, fmt.Errorf("subtitle is not valid UTF-8")
+	}
+
+	doc := &SubtitleDocument{Cues: []Cue{}}
+	droppedTags := make(map[string]bool)
+
+	for _, block := range splitVTTBlocks(normalizeSubtitleLines(content)) {
+		lines := block.lines
+
+		// The numeric counter is optional in practice
+		timingIndex := 0
+		if !strings.Contains(lines[0], "-->") {
+			if len(lines) < 2 || !strings.Contains(lines[1], "-->") {
+				doc.warn(block.line, "block without a timing line skipped")
+				continue
+			}
+			timingIndex = 1
+		}
+
+		match := srtTimingRegex.FindStringSubmatch(lines[timingIndex])
+		timingLine := block.line + timingIndex
+		if match == nil {
+			doc.warn(timingLine, "invalid timing line skipped")
+			continue
+		}
+
+		start, err := ParseTimestamp(match[1])
+		if err != nil {
+			doc.warn(timingLine, "cue skipped: %v", err)
+			continue
+		}
+		end, err := ParseTimestamp(match[2])
+		if err != nil {
+			doc.warn(timingLine, "cue skipped: %v", err)
+			continue
+		}
+		if strings.TrimSpace(match[3]) != "" {
+			doc.warn(timingLine, "SRT position coordinates are not supported and were dropped")
+		}
+		if end <= start {
+			doc.warn(timingLine, "cue end time is not after its start time")
+		}
+
+		var payload []string
+		for _, line := range lines[timingIndex+1:] {
+			payload = append(payload, convertSRTMarkup(line, droppedTags))
+		}
+
+		cue := Cue{
+			Index:     len(doc.Cues) + 1,
+			StartTime: start,
+			EndTime:   end,
+			RawText:   strings.Join(payload, "\n"),
+		}
+		cue.Text, cue.Speaker = stripCueMarkup(cue.RawText)
+		doc.Cues = append(doc.Cues, cue)
+	}
+
+	for _, tag := range sortedKeys(droppedTags) {
+		doc.warn(0, "unsupported formatting %s was removed", tag)
+	}
+
+	return doc, nil
+}
+
+// ParseSBV parses YouTube SubViewer (SBV) content into a subtitle document
+func ParseSBV(content string) (*SubtitleDocument, error) {
+	if !utf8.ValidString(content) {
+		return nil, fmt.Errorf("subtitle is not valid UTF-8")
+	}
+
+	doc := &SubtitleDocument{Cues: []Cue{}}
+
+	for _, block := range splitVTTBlocks(normalizeSubtitleLines(content)) {
+		match := sbvTimingRegex.FindStringSubmatch(block.lines[0])
+		if match == nil {
+			doc.warn(block.line, "block without a timing line skipped")
+			continue
+		}
+
+		start, err := ParseTimestamp(match[1])
+		if err != nil {
+			doc.warn(block.line, "cue skipped: %v", err)
+			continue
+		}
+		end, err := ParseTimestamp(match[2])
+		if err != nil {
+			doc.warn(block.line, "cue skipped: %v", err)
+			continue
+		}
+		if end <= start {
+			doc.warn(block.line, "cue end time is not after its start time")
+		}
+
+		text := strings.Join(block.lines[1:], "\n")
+		cue := Cue{
+			Index:     len(doc.Cues) + 1,
+			StartTime: start,
+			EndTime:   end,
+			RawText:   escapeCueText(text),
+		}
+		cue.Text, _ = stripCueMarkup(cue.RawText)
+		doc.Cues = append(doc.Cues, cue)
+	}
+
+	return doc, nil
+}
+
+// ttmlTiming holds the timing parameters declared on the <tt> element
+type ttmlTiming struct {
+	frameRate float64
+	tickRate  float64
+}
+
+// ttmlParagraph collects the text of a <p> element while it is being parsed
+type ttmlParagraph struct {
+	line     int
+	begin    float64
+	end      float64
+	hasEnd   bool
+	dur      float64
+	hasDur   bool
+	speaker  string
+	text     strings.Builder
+	complete bool
+}
+
+// ParseTTML parses TTML or DFXP content into a subtitle document.
+// Timing on body and div elements is treated as an offset for the paragraphs inside them.
+func ParseTTML(content string) (*SubtitleDocument, error) {
+	doc := &SubtitleDocument{Cues: []Cue{}}
+	timing := ttmlTiming{frameRate: 30, tickRate: 1}
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+
+	offsets := []float64{0}
+	var paragraph *ttmlParagraph
+	depth := 0 // Nesting depth inside the current paragraph
+	sawRoot := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid TTML: %w", err)
+		}
+
+		line, _ := decoder.InputPos()
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "tt":
+				sawRoot = true
+				if value := xmlAttr(t, "frameRate"); value != "" {
+					if rate, err := strconv.ParseFloat(value, 64); err == nil && rate > 0 {
+						timing.frameRate = rate
+					}
+				}
+				if value := xmlAttr(t, "tickRate"); value != "" {
+					if rate, err := strconv.ParseFloat(value, 64); err == nil && rate > 0 {
+						timing.tickRate = rate
+					}
+				}
+			case paragraph != nil:
+				depth++
+				if t.Name.Local == "br" {
+					paragraph.text.WriteString("\n")
+				} else if t.Name.Local == "span" && paragraph.speaker == "" {
+					paragraph.speaker = xmlAttr(t, "agent")
+				}
+			case t.Name.Local == "body" || t.Name.Local == "div":
+				offset := offsets[len(offsets)-1]
+				if value := xmlAttr(t, "begin"); value != "" {
+					begin, err := parseTTMLTime(value, timing)
+					if err != nil {
+						doc.warn(line, "invalid begin time on <%s> ignored: %v", t.Name.Local, err)
+					} else {
+						offset += begin
+					}
+				}
+				offsets = append(offsets, offset)
+			case t.Name.Local == "p":
+				paragraph = &ttmlParagraph{line: line, speaker: xmlAttr(t, "agent")}
+				paragraph.complete = parseTTMLParagraphTiming(doc, t, timing, paragraph)
+				depth = 0
+			}
+		case xml.EndElement:
+			switch {
+			case paragraph != nil && depth > 0:
+				depth--
+			case t.Name.Local == "p" && paragraph != nil:
+				if paragraph.complete {
+					appendTTMLCue(doc, paragraph, offsets[len(offsets)-1])
+				}
+				paragraph = nil
+			case (t.Name.Local == "body" || t.Name.Local == "div") && len(offsets) > 1:
+				offsets = offsets[:len(offsets)-1]
+			}
+		case xml.CharData:
+			if paragraph != nil {
+				paragraph.text.Write(t)
+			}
+		}
+	}
+
+	if !sawRoot {
+		return nil, fmt.Errorf("invalid TTML: missing <tt> root element")
+	}
+
+	return doc, nil
+}
+
+// parseTTMLParagraphTiming reads the begin, end and dur attributes of a <p> element
+func parseTTMLParagraphTiming(doc *SubtitleDocument, element xml.StartElement, timing ttmlTiming, paragraph *ttmlParagraph) bool {
+	value := xmlAttr(element, "begin")
+	if value == "" {
+		doc.warn(paragraph.line, "paragraph without a begin time skipped")
+		return false
+	}
+
+	var err error
+	if paragraph.begin, err = parseTTMLTime(value, timing); err != nil {
+		doc.warn(paragraph.line, "paragraph skipped: %v", err)
+		return false
+	}
+
+	if value := xmlAttr(element, "end"); value != "" {
+		if paragraph.end, err = parseTTMLTime(value, timing); err != nil {
+			doc.warn(paragraph.line, "paragraph skipped: %v", err)
+			return false
+		}
+		paragraph.hasEnd = true
+	}
+	if value := xmlAttr(element, "dur"); value != "" {
+		if paragraph.dur, err = parseTTMLTime(value, timing); err != nil {
+			doc.warn(paragraph.line, "paragraph skipped: %v", err)
+			return false
+		}
+		paragraph.hasDur = true
+	}
+
+	if !paragraph.hasEnd && !paragraph.hasDur {
+		doc.warn(paragraph.line, "paragraph without an end time or duration skipped")
+		return false
+	}
+
+	return true
+}
+
+// appendTTMLCue adds a completed paragraph to the document as a cue
+func appendTTMLCue(doc *SubtitleDocument, paragraph *ttmlParagraph, offset float64) {
+	start := offset + paragraph.begin
+	end := offset + paragraph.end
+	if !paragraph.hasEnd {
+		end = start + paragraph.dur
+	}
+	if end <= start {
+		doc.warn(paragraph.line, "cue end time is not after its start time")
+	}
+
+	// TTML collapses whitespace within each line by default
+	lines := strings.Split(paragraph.text.String(), "\n")
+	var payload []string
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			payload = append(payload, escapeCueText(line))
+		}
+	}
+
+	cue := Cue{
+		Index:     len(doc.Cues) + 1,
+		StartTime: start,
+		EndTime:   end,
+		Speaker:   paragraph.speaker,
+		RawText:   strings.Join(payload, "\n"),
+	}
+	cue.Text, _ = stripCueMarkup(cue.RawText)
+	doc.Cues = append(doc.Cues, cue)
+}
+
+// parseTTMLTime parses a TTML clock time (hh:mm:ss.fff or hh:mm:ss:frames) or offset time (1.5s, 200ms, 30f)
+func parseTTMLTime(value string, timing ttmlTiming) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	if match := ttmlClockRegex.FindStringSubmatch(value); match != nil {
+		hours, _ := strconv.Atoi(match[1])
+		minutes, _ := strconv.Atoi(match[2])
+		seconds, _ := strconv.Atoi(match[3])
+		total := float64(hours*3600 + minutes*60 + seconds)
+		if match[4] != "" {
+			fraction, _ := strconv.ParseFloat(match[4], 64)
+			total += fraction
+		}
+		if match[5] != "" {
+			frames, _ := strconv.ParseFloat(match[5], 64)
+			total += frames / timing.frameRate
+		}
+		return total, nil
+	}
+
+	if match := ttmlOffsetRegex.FindStringSubmatch(value); match != nil {
+		amount, _ := strconv.ParseFloat(match[1], 64)
+		switch match[2] {
+		case "h":
+			return amount * 3600, nil
+		case "m":
+			return amount * 60, nil
+		case "s":
+			return amount, nil
+		case "ms":
+			return amount / 1000, nil
+		case "f":
+			return amount / timing.frameRate, nil
+		case "t":
+			return amount / timing.tickRate, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid time expression '%s'", value)
+}
+
+// xmlAttr returns the value of an attribute by local name, ignoring its namespace
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// normalizeSubtitleLines strips a byte order mark, normalizes line endings and splits the content into lines
+func normalizeSubtitleLines(content string) []string {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+	return strings.Split(content, "\n")
+}
+
+// convertSRTMarkup keeps the <i>, <b> and <u> tags WebVTT supports, removes other markup
+// and escapes the remaining text. Removed tag names are recorded in dropped.
+func convertSRTMarkup(line string, dropped map[string]bool) string {
+	var b strings.Builder
+	last := 0
+
+	for _, loc := range srtTagRegex.FindAllStringIndex(line, -1) {
+		b.WriteString(escapeCueText(line[last:loc[0]]))
+		last = loc[1]
+
+		tag := line[loc[0]:loc[1]]
+		if strings.HasPrefix(tag, "{") {
+			dropped["{\\...} override tags"] = true
+			continue
+		}
+
+		name := strings.ToLower(strings.Trim(tag, "<>/ "))
+		if fields := strings.Fields(name); len(fields) > 0 {
+			name = fields[0]
+		}
+		switch name {
+		case "i", "b", "u":
+			if strings.HasPrefix(tag, "</") {
+				b.WriteString("</" + name + ">")
+			} else {
+				b.WriteString("<" + name + ">")
+			}
+		default:
+			dropped["<"+name+">"] = true
+		}
+	}
+	b.WriteString(escapeCueText(line[last:]))
+
+	return b.String()
+}
+
+// escapeCueText escapes the characters that have a special meaning in WebVTT cue text
+func escapeCueText(text string) string {
+	text = strings.ReplaceAll(text, "&", "&amp;")
+	text = strings.ReplaceAll(text, "<", "&lt;")
+	text = strings.ReplaceAll(text, ">", "&gt;")
+	return text
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
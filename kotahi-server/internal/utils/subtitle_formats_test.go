@@ -0,0 +1,349 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// formatCase is a subtitle file in one of the imported formats and what parsing it should give
+type formatCase struct {
+	name     string
+	content  string
+	cues     []formatCue
+	warnings []string // Each is a substring of one of the document's warnings
+}
+
+// formatCue is the part of a parsed cue a format case checks
+type formatCue struct {
+	Start   float64
+	End     float64
+	Raw     string
+	Text    string
+	Speaker string
+}
+
+// runFormatCases parses each case and compares the cues and warnings
+func runFormatCases(t *testing.T, parse func(string) (*SubtitleDocument, error), tests []formatCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parse(tt.content)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+
+			if len(doc.Cues) != len(tt.cues) {
+				t.Fatalf("got %d cues, want %d: %+v (warnings %+v)", len(doc.Cues), len(tt.cues), doc.Cues, doc.Warnings)
+			}
+			for i, want := range tt.cues {
+				got := doc.Cues[i]
+				if got.Index != i+1 {
+					t.Errorf("cue %d: Index = %d", i, got.Index)
+				}
+				if got.StartTime != want.Start || got.EndTime != want.End || got.RawText != want.Raw ||
+					got.Text != want.Text || got.Speaker != want.Speaker {
+					t.Errorf("cue %d = {Start:%v End:%v Raw:%q Text:%q Speaker:%q}, want %+v",
+						i, got.StartTime, got.EndTime, got.RawText, got.Text, got.Speaker, want)
+				}
+			}
+
+			if tt.warnings == nil && len(doc.Warnings) > 0 {
+				t.Errorf("unexpected warnings %+v", doc.Warnings)
+			}
+			for _, want := range tt.warnings {
+				found := false
+				for _, warning := range doc.Warnings {
+					if strings.Contains(warning.Message, want) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("no warning containing %q in %+v", want, doc.Warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSRT(t *testing.T) {
+	runFormatCases(t, ParseSRT, []formatCase{
+		{
+			name:    "numbered cues",
+			content: "1\n00:00:01,000 --> 00:00:04,000\nKia ora\n\n2\n00:00:05,500 --> 00:00:07,250\nTēnā koe\ne hoa\n",
+			cues: []formatCue{
+				{Start: 1, End: 4, Raw: "Kia ora", Text: "Kia ora"},
+				{Start: 5.5, End: 7.25, Raw: "Tēnā koe\ne hoa", Text: "Tēnā koe\ne hoa"},
+			},
+		},
+		{
+			name:    "byte order mark, CRLF and no counters",
+			content: "\ufeff00:00:01,000 --> 00:00:02,000\r\nKia ora\r\n\r\n01:00:00.500 --> 01:00:01.000\r\nKa kite\r\n",
+			cues: []formatCue{
+				{Start: 1, End: 2, Raw: "Kia ora", Text: "Kia ora"},
+				{Start: 3600.5, End: 3601, Raw: "Ka kite", Text: "Ka kite"},
+			},
+		},
+		{
+			name:     "position coordinates are dropped",
+			content:  "1\n00:00:01,000 --> 00:00:02,000 X1:100 X2:600 Y1:20 Y2:80\nKia ora\n",
+			cues:     []formatCue{{Start: 1, End: 2, Raw: "Kia ora", Text: "Kia ora"}},
+			warnings: []string{"SRT position coordinates are not supported"},
+		},
+		{
+			name:    "supported formatting is kept",
+			content: "1\n00:00:01,000 --> 00:00:02,000\n<i>Kia</i> <B>ora</B> <u>koutou</u>\n",
+			cues:    []formatCue{{Start: 1, End: 2, Raw: "<i>Kia</i> <b>ora</b> <u>koutou</u>", Text: "Kia ora koutou"}},
+		},
+		{
+			name:    "other markup is removed and text is escaped",
+			content: "1\n00:00:01,000 --> 00:00:02,000\n{\\an8}<font color=\"red\">Rock</font> & roll <3\n",
+			cues:    []formatCue{{Start: 1, End: 2, Raw: "Rock &amp; roll &lt;3", Text: "Rock & roll <3"}},
+			warnings: []string{
+				"unsupported formatting <font> was removed",
+				"unsupported formatting {\\...} override tags was removed",
+			},
+		},
+		{
+			name:     "invalid timings are skipped",
+			content:  "1\n00:00:aa,000 --> 00:00:02,000\nBad start\n\n2\njust text\nmore text\n\n3\n00:00:03,000 --> 00:00:04,000\nKept\n",
+			cues:     []formatCue{{Start: 3, End: 4, Raw: "Kept", Text: "Kept"}},
+			warnings: []string{"cue skipped: invalid timestamp", "block without a timing line skipped"},
+		},
+		{
+			name:     "end before start is kept with a warning",
+			content:  "1\n00:00:05,000 --> 00:00:04,000\nBackwards\n",
+			cues:     []formatCue{{Start: 5, End: 4, Raw: "Backwards", Text: "Backwards"}},
+			warnings: []string{"is not after its start time"},
+		},
+	})
+}
+
+func TestParseSRTRejectsInvalidUTF8(t *testing.T) {
+	if _, err := ParseSRT("1\n00:00:01,000 --> 00:00:02,000\n\xff\xfe\n"); err == nil {
+		t.Fatal("ParseSRT() accepted invalid UTF-8")
+	}
+}
+
+func TestParseSBV(t *testing.T) {
+	runFormatCases(t, ParseSBV, []formatCase{
+		{
+			name:    "cues",
+			content: "0:00:01.000,0:00:04.000\nKia ora\ne hoa\n\n0:00:05.5,0:00:07.25\nKa pai\n",
+			cues: []formatCue{
+				{Start: 1, End: 4, Raw: "Kia ora\ne hoa", Text: "Kia ora\ne hoa"},
+				{Start: 5.5, End: 7.25, Raw: "Ka pai", Text: "Ka pai"},
+			},
+		},
+		{
+			name:    "CRLF and spaces around the comma",
+			content: "1:00:00.000 , 1:00:01.000\r\nKia ora\r\n",
+			cues:    []formatCue{{Start: 3600, End: 3601, Raw: "Kia ora", Text: "Kia ora"}},
+		},
+		{
+			name:    "text is escaped rather than read as markup",
+			content: "0:00:01.000,0:00:02.000\n<i>Rock</i> & roll\n",
+			cues:    []formatCue{{Start: 1, End: 2, Raw: "&lt;i&gt;Rock&lt;/i&gt; &amp; roll", Text: "<i>Rock</i> & roll"}},
+		},
+		{
+			name:     "blocks without an SBV timing line are skipped",
+			content:  "00:00:01.000 --> 00:00:02.000\nVTT timing\n\n0:00:03.000,0:00:04.000\nKept\n",
+			cues:     []formatCue{{Start: 3, End: 4, Raw: "Kept", Text: "Kept"}},
+			warnings: []string{"block without a timing line skipped"},
+		},
+		{
+			name:     "out of range times are skipped",
+			content:  "0:00:61.000,0:00:62.000\nBad\n\n0:00:03.000,0:00:04.000\nKept\n",
+			cues:     []formatCue{{Start: 3, End: 4, Raw: "Kept", Text: "Kept"}},
+			warnings: []string{"cue skipped: invalid timestamp"},
+		},
+		{
+			name:     "end before start is kept with a warning",
+			content:  "0:00:05.000,0:00:04.000\nBackwards\n",
+			cues:     []formatCue{{Start: 5, End: 4, Raw: "Backwards", Text: "Backwards"}},
+			warnings: []string{"is not after its start time"},
+		},
+	})
+}
+
+func TestParseTTML(t *testing.T) {
+	const open = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" xmlns:ttp="http://www.w3.org/ns/ttml#parameter"`
+
+	runFormatCases(t, ParseTTML, []formatCase{
+		{
+			name: "clock times",
+			content: open + `><body><div>` +
+				`<p begin="00:00:01.000" end="00:00:04.000">Kia ora</p>` +
+				`<p begin="01:00:00.250" end="01:00:01.5">Ka kite</p>` +
+				`</div></body></tt>`,
+			cues: []formatCue{
+				{Start: 1, End: 4, Raw: "Kia ora", Text: "Kia ora"},
+				{Start: 3600.25, End: 3601.5, Raw: "Ka kite", Text: "Ka kite"},
+			},
+		},
+		{
+			name: "offset times, frames and ticks",
+			content: open + ` ttp:frameRate="25" ttp:tickRate="10000000"><body><div>` +
+				`<p begin="1.5s" dur="2s">Seconds</p>` +
+				`<p begin="200ms" end="0.5m">Milliseconds and minutes</p>` +
+				`<p begin="50f" end="10000000t">Frames and ticks</p>` +
+				`<p begin="00:00:01:05" end="1h">Clock frames and hours</p>` +
+				`</div></body></tt>`,
+			cues: []formatCue{
+				{Start: 1.5, End: 3.5, Raw: "Seconds", Text: "Seconds"},
+				{Start: 0.2, End: 30, Raw: "Milliseconds and minutes", Text: "Milliseconds and minutes"},
+				{Start: 2, End: 1, Raw: "Frames and ticks", Text: "Frames and ticks"},
+				{Start: 1.2, End: 3600, Raw: "Clock frames and hours", Text: "Clock frames and hours"},
+			},
+			warnings: []string{"is not after its start time"},
+		},
+		{
+			name: "body and div begin times offset their paragraphs",
+			content: open + `><body begin="10s"><div begin="5s">` +
+				`<p begin="1s" end="2s">Offset</p>` +
+				`</div><div><p begin="1s" dur="1s">Body only</p></div></body></tt>`,
+			cues: []formatCue{
+				{Start: 16, End: 17, Raw: "Offset", Text: "Offset"},
+				{Start: 11, End: 12, Raw: "Body only", Text: "Body only"},
+			},
+		},
+		{
+			name: "line breaks, spans, speakers and whitespace",
+			content: open + `><body><div>` +
+				`<p begin="1s" end="2s" ttm:agent="Hēmi">  Kia   ora <br/><span tts:color="yellow">e hoa</span></p>` +
+				`<p begin="3s" end="4s"><span ttm:agent="Mere">Tēnā</span> koe</p>` +
+				`</div></body></tt>`,
+			cues: []formatCue{
+				{Start: 1, End: 2, Raw: "Kia ora\ne hoa", Text: "Kia ora\ne hoa", Speaker: "Hēmi"},
+				{Start: 3, End: 4, Raw: "Tēnā koe", Text: "Tēnā koe", Speaker: "Mere"},
+			},
+		},
+		{
+			name:    "text is escaped for WebVTT",
+			content: open + `><body><div><p begin="1s" end="2s">Rock &amp; roll &lt;3</p></div></body></tt>`,
+			cues:    []formatCue{{Start: 1, End: 2, Raw: "Rock &amp; roll &lt;3", Text: "Rock & roll <3"}},
+		},
+		{
+			name: "paragraphs with missing or invalid timing are skipped",
+			content: open + `><body><div begin="soon">` +
+				`<p end="2s">No begin</p>` +
+				`<p begin="1s">No end</p>` +
+				`<p begin="1 second" end="2s">Invalid begin</p>` +
+				`<p begin="3s" end="4s">Kept</p>` +
+				`</div></body></tt>`,
+			cues: []formatCue{{Start: 3, End: 4, Raw: "Kept", Text: "Kept"}},
+			warnings: []string{
+				"invalid begin time on <div> ignored",
+				"paragraph without a begin time skipped",
+				"paragraph without an end time or duration skipped",
+				"invalid time expression '1 second'",
+			},
+		},
+	})
+}
+
+func TestParseTTMLRejectsOtherXML(t *testing.T) {
+	if _, err := ParseTTML(`<html><body><p begin="1s" end="2s">Kia ora</p></body></html>`); err == nil {
+		t.Error("ParseTTML() accepted a document without a <tt> root")
+	}
+	if _, err := ParseTTML(`<tt><body><p begin="1s" end="2s">Kia ora</p></body></tt`); err == nil {
+		t.Error("ParseTTML() accepted truncated XML")
+	}
+}
+
+func TestWriteWebVTTRoundTrip(t *testing.T) {
+	content := "WEBVTT - Te reo Māori\n\n" +
+		"NOTE Translated by Mere\n\n" +
+		"NOTE\nReviewed twice\nbefore release\n\n" +
+		"REGION\nid:speaker width:40% lines:3\n\n" +
+		"STYLE\n::cue { color: yellow }\n\n" +
+		"intro\n00:00:01.000 --> 00:00:04.000 align:start line:0\n<v Hēmi>Kia ora</v>\n<i>e hoa</i>\n\n" +
+		"00:00:05.000 --> 00:00:06.500\nRock &amp; roll &lt;3\n\n" +
+		"NOTE between cues\n\n" +
+		"01:00:00.000 --> 01:00:01.000 region:speaker\n<c.yellow>Ka kite</c>\n"
+
+	doc, err := ParseWebVTT(content)
+	if err != nil {
+		t.Fatalf("ParseWebVTT() error = %v", err)
+	}
+
+	written := WriteWebVTT(doc)
+	reparsed, err := ParseWebVTT(written)
+	if err != nil {
+		t.Fatalf("ParseWebVTT(WriteWebVTT()) error = %v\n%s", err, written)
+	}
+	if len(reparsed.Warnings) > 0 {
+		t.Errorf("written file has warnings %+v:\n%s", reparsed.Warnings, written)
+	}
+
+	if reparsed.Header != doc.Header {
+		t.Errorf("Header = %q, want %q", reparsed.Header, doc.Header)
+	}
+	if !reflect.DeepEqual(reparsed.Notes, doc.Notes) {
+		t.Errorf("Notes = %q, want %q", reparsed.Notes, doc.Notes)
+	}
+	if !reflect.DeepEqual(reparsed.Regions, doc.Regions) {
+		t.Errorf("Regions = %q, want %q", reparsed.Regions, doc.Regions)
+	}
+	if !reflect.DeepEqual(reparsed.Styles, doc.Styles) {
+		t.Errorf("Styles = %q, want %q", reparsed.Styles, doc.Styles)
+	}
+	if !reflect.DeepEqual(reparsed.Cues, doc.Cues) {
+		t.Errorf("Cues = %+v, want %+v", reparsed.Cues, doc.Cues)
+	}
+
+	if again := WriteWebVTT(reparsed); again != written {
+		t.Errorf("writing the reparsed document changed it:\n%s\nwant\n%s", again, written)
+	}
+}
+
+func TestWriteWebVTTConvertedFormats(t *testing.T) {
+	tests := map[string]string{
+		"captions.srt":  "1\n00:00:01,000 --> 00:00:02,000\n<i>Kia ora</i> & <font color=\"red\">haere mai</font>\n\n2\n00:00:03,000 --> 00:00:04,000\nKa kite\n",
+		"captions.sbv":  "0:00:01.000,0:00:02.000\nKia ora & <haere mai>\n\n0:00:03.000,0:00:04.000\nKa kite\n",
+		"captions.ttml": `<tt xmlns="http://www.w3.org/ns/ttml"><body><div><p begin="1s" end="2s">Kia ora &amp; <br/>haere mai</p><p begin="3s" end="4s">Ka kite</p></div></body></tt>`,
+	}
+
+	for filename, content := range tests {
+		t.Run(filename, func(t *testing.T) {
+			vtt, doc, _, err := ConvertToWebVTT(filename, []byte(content))
+			if err != nil {
+				t.Fatalf("ConvertToWebVTT() error = %v", err)
+			}
+
+			reparsed, err := ParseWebVTT(vtt)
+			if err != nil {
+				t.Fatalf("ParseWebVTT() error = %v", err)
+			}
+			if len(reparsed.Warnings) > 0 {
+				t.Errorf("converted file has warnings %+v:\n%s", reparsed.Warnings, vtt)
+			}
+			if len(reparsed.Cues) != len(doc.Cues) {
+				t.Fatalf("converted file has %d cues, want %d:\n%s", len(reparsed.Cues), len(doc.Cues), vtt)
+			}
+			for i, cue := range doc.Cues {
+				got := reparsed.Cues[i]
+				if got.StartTime != cue.StartTime || got.EndTime != cue.EndTime || got.Text != cue.Text {
+					t.Errorf("cue %d = {%v %v %q}, want {%v %v %q}", i, got.StartTime, got.EndTime, got.Text, cue.StartTime, cue.EndTime, cue.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteWebVTTCueText(t *testing.T) {
+	doc := &SubtitleDocument{Cues: []Cue{
+		{StartTime: 1, EndTime: 2, Text: "Rock & roll <3"},
+		{StartTime: 3, EndTime: 4, RawText: "Kia ora\n\n  \ne hoa", Settings: map[string]string{"size": "50%", "align": "end"}},
+	}}
+
+	want := "WEBVTT\n\n" +
+		"00:00:01.000 --> 00:00:02.000\nRock &amp; roll &lt;3\n\n" +
+		"00:00:03.000 --> 00:00:04.000 align:end size:50%\nKia ora\ne hoa\n\n"
+	if got := WriteWebVTT(doc); got != want {
+		t.Errorf("WriteWebVTT() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,273 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"video-player-backend/internal/models"
+)
+
+// Lint severities, from most to least serious
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+	LintSeverityInfo    = "info"
+)
+
+// Lint rules
+const (
+	LintRuleParse        = "parse"
+	LintRuleTiming       = "timing"
+	LintRuleOrder        = "order"
+	LintRuleOverlap      = "overlap"
+	LintRuleDuration     = "duration"
+	LintRuleEmpty        = "empty"
+	LintRuleLineLength   = "line_length"
+	LintRuleReadingSpeed = "reading_speed"
+	LintRuleMacron       = "macron"
+	LintRuleTag          = "tag"
+)
+
+// Default lint limits
+const (
+	DefaultMaxLineLength     = 42
+	DefaultMaxCharsPerSecond = 20.0
+)
+
+// cueTagRegex splits a cue tag into its closing slash, name, classes and annotation
+var cueTagRegex = regexp.MustCompile(`^<(/?)([a-z]+)((?:\.[^\s.>]+)*)(?:[ \t]+([^>]*))?>$`)
+
+// validCueTags are the tags allowed in WebVTT cue text
+var validCueTags = map[string]bool{
+	"c": true, "i": true, "b": true, "u": true, "v": true, "lang": true, "ruby": true, "rt": true,
+}
+
+// LintIssue is a single problem found in a subtitle file
+type LintIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Cue      int    `json:"cue,omitempty"`  // 1-based cue index, 0 for file-level issues
+	Line     int    `json:"line,omitempty"` // Source line, for parse issues
+	Message  string `json:"message"`
+}
+
+// LintReport lists the issues found in a subtitle file
+type LintReport struct {
+	CueCount int         `json:"cue_count"`
+	Errors   int         `json:"errors"`
+	Warnings int         `json:"warnings"`
+	Info     int         `json:"info"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+// HasErrors reports whether any issue has error severity
+func (r *LintReport) HasErrors() bool {
+	return r.Errors > 0
+}
+
+// add records an issue and updates the severity counts
+func (r *LintReport) add(issue LintIssue) {
+	switch issue.Severity {
+	case LintSeverityError:
+		r.Errors++
+	case LintSeverityWarning:
+		r.Warnings++
+	default:
+		r.Info++
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// LintOptions configures the subtitle linter. Zero values use the defaults.
+type LintOptions struct {
+	Duration          float64              // Video duration in seconds, 0 if unknown
+	MaxLineLength     int                  // Longest allowed line in characters
+	MaxCharsPerSecond float64              // Fastest allowed reading speed
+	Vocabulary        []*models.Vocabulary // Known words used to spot missing macrons
+}
+
+// LintSubtitle checks a parsed subtitle document for problems that affect playback or indexing.
+// Parse warnings are included, with skipped content reported as errors.
+func LintSubtitle(doc *SubtitleDocument, opts LintOptions) *LintReport {
+	if opts.MaxLineLength <= 0 {
+		opts.MaxLineLength = DefaultMaxLineLength
+	}
+	if opts.MaxCharsPerSecond <= 0 {
+		opts.MaxCharsPerSecond = DefaultMaxCharsPerSecond
+	}
+
+	report := &LintReport{CueCount: len(doc.Cues), Issues: []LintIssue{}}
+
+	for _, warning := range doc.Warnings {
+		// Cue order and timing are reported by their own rules below
+		if strings.HasPrefix(warning.Message, "cue starts before") || strings.HasPrefix(warning.Message, "cue end time") {
+			continue
+		}
+
+		severity := LintSeverityWarning
+		if strings.Contains(warning.Message, "skipped") {
+			// Skipped cues never reach the player or the vocabulary index
+			severity = LintSeverityError
+		}
+		report.add(LintIssue{Rule: LintRuleParse, Severity: severity, Line: warning.Line, Message: warning.Message})
+	}
+
+	macrons := macronSpellings(opts.Vocabulary)
+
+	for i, cue := range doc.Cues {
+		n := i + 1
+
+		if cue.EndTime <= cue.StartTime {
+			report.add(LintIssue{Rule: LintRuleTiming, Severity: LintSeverityError, Cue: n,
+				Message: fmt.Sprintf("end time %s is not after start time %s", FormatVTTTimestamp(cue.EndTime), FormatVTTTimestamp(cue.StartTime))})
+		}
+
+		if i > 0 {
+			prev := doc.Cues[i-1]
+			if cue.StartTime < prev.StartTime {
+				report.add(LintIssue{Rule: LintRuleOrder, Severity: LintSeverityError, Cue: n,
+					Message: fmt.Sprintf("starts at %s, before cue %d", FormatVTTTimestamp(cue.StartTime), i)})
+			} else if cue.StartTime < prev.EndTime {
+				report.add(LintIssue{Rule: LintRuleOverlap, Severity: LintSeverityWarning, Cue: n,
+					Message: fmt.Sprintf("overlaps cue %d by %.3fs", i, prev.EndTime-cue.StartTime)})
+			}
+		}
+
+		if opts.Duration > 0 {
+			if cue.StartTime >= opts.Duration {
+				report.add(LintIssue{Rule: LintRuleDuration, Severity: LintSeverityError, Cue: n,
+					Message: fmt.Sprintf("starts at %s, after the video ends at %s", FormatVTTTimestamp(cue.StartTime), FormatVTTTimestamp(opts.Duration))})
+			} else if cue.EndTime > opts.Duration {
+				report.add(LintIssue{Rule: LintRuleDuration, Severity: LintSeverityWarning, Cue: n,
+					Message: fmt.Sprintf("ends at %s, after the video ends at %s", FormatVTTTimestamp(cue.EndTime), FormatVTTTimestamp(opts.Duration))})
+			}
+		}
+
+		text := strings.TrimSpace(cue.Text)
+		if text == "" {
+			report.add(LintIssue{Rule: LintRuleEmpty, Severity: LintSeverityWarning, Cue: n, Message: "cue has no text"})
+		}
+
+		for _, line := range strings.Split(text, "\n") {
+			if length := utf8.RuneCountInString(line); length > opts.MaxLineLength {
+				report.add(LintIssue{Rule: LintRuleLineLength, Severity: LintSeverityWarning, Cue: n,
+					Message: fmt.Sprintf("line is %d characters long, the limit is %d", length, opts.MaxLineLength)})
+			}
+		}
+
+		if duration := cue.EndTime - cue.StartTime; duration > 0 && text != "" {
+			chars := utf8.RuneCountInString(strings.ReplaceAll(text, "\n", " "))
+			if cps := float64(chars) / duration; cps > opts.MaxCharsPerSecond {
+				report.add(LintIssue{Rule: LintRuleReadingSpeed, Severity: LintSeverityWarning, Cue: n,
+					Message: fmt.Sprintf("reading speed is %.1f characters per second, the limit is %.0f", cps, opts.MaxCharsPerSecond)})
+			}
+		}
+
+		runes := []rune(text)
+		for _, token := range tokenizeText(text) {
+			if correct, ok := macrons[token.word]; ok {
+				word := runes[token.start:token.end]
+				if unicode.IsUpper(word[0]) {
+					correct = strings.ToUpper(string([]rune(correct)[:1])) + string([]rune(correct)[1:])
+				}
+				report.add(LintIssue{Rule: LintRuleMacron, Severity: LintSeverityWarning, Cue: n,
+					Message: fmt.Sprintf("'%s' may be missing macrons, the vocabulary spells it '%s'", string(word), correct)})
+			}
+		}
+
+		for _, problem := range cueTagProblems(cue.RawText) {
+			report.add(LintIssue{Rule: LintRuleTag, Severity: problem.severity, Cue: n, Message: problem.message})
+		}
+	}
+
+	return report
+}
+
+// macronSpellings maps the macron-less spelling of each vocabulary word that has macrons to its correct spelling
+func macronSpellings(vocabularies []*models.Vocabulary) map[string]string {
+	spellings := make(map[string]string)
+	known := make(map[string]bool)
+	for _, vocab := range vocabularies {
+		for _, token := range tokenizeText(vocab.Maori) {
+			known[token.word] = true
+			if stripped := stripMacrons(token.word); stripped != token.word {
+				spellings[stripped] = token.word
+			}
+		}
+	}
+
+	// Some words are valid both with and without macrons, like kaka and kākā
+	for word := range known {
+		delete(spellings, word)
+	}
+	return spellings
+}
+
+// stripMacrons replaces macronised vowels with plain ones
+func stripMacrons(s string) string {
+	return strings.NewReplacer(
+		"ā", "a", "ē", "e", "ī", "i", "ō", "o", "ū", "u",
+		"Ā", "A", "Ē", "E", "Ī", "I", "Ō", "O", "Ū", "U",
+	).Replace(s)
+}
+
+// tagProblem is an invalid or unbalanced tag in a cue
+type tagProblem struct {
+	severity string
+	message  string
+}
+
+// cueTagProblems checks a cue payload for unknown, unbalanced and malformed tags
+func cueTagProblems(raw string) []tagProblem {
+	var problems []tagProblem
+	var open []string
+
+	for _, tag := range vttTagRegex.FindAllString(raw, -1) {
+		// Timestamp tags such as <00:00:01.000> mark karaoke-style timing
+		if len(tag) > 2 && tag[1] >= '0' && tag[1] <= '9' {
+			if _, err := ParseTimestamp(tag[1 : len(tag)-1]); err != nil {
+				problems = append(problems, tagProblem{LintSeverityError, fmt.Sprintf("invalid timestamp tag %s", tag)})
+			}
+			continue
+		}
+
+		match := cueTagRegex.FindStringSubmatch(tag)
+		if match == nil || !validCueTags[match[2]] {
+			problems = append(problems, tagProblem{LintSeverityError, fmt.Sprintf("invalid tag %s", tag)})
+			continue
+		}
+
+		name := match[2]
+		if match[1] == "/" {
+			if len(open) == 0 || open[len(open)-1] != name {
+				problems = append(problems, tagProblem{LintSeverityWarning, fmt.Sprintf("closing tag %s does not match an open tag", tag)})
+				continue
+			}
+			open = open[:len(open)-1]
+			continue
+		}
+
+		if (name == "v" || name == "lang") && strings.TrimSpace(match[4]) == "" {
+			problems = append(problems, tagProblem{LintSeverityWarning, fmt.Sprintf("tag %s needs an annotation", tag)})
+		}
+		open = append(open, name)
+	}
+
+	for _, name := range open {
+		// A voice tag may be left open until the end of the cue
+		if name == "v" {
+			continue
+		}
+		problems = append(problems, tagProblem{LintSeverityWarning, fmt.Sprintf("tag <%s> is never closed", name)})
+	}
+
+	// A '<' that doesn't start a tag should be escaped as &lt;
+	if strings.Count(raw, "<") > len(vttTagRegex.FindAllString(raw, -1)) {
+		problems = append(problems, tagProblem{LintSeverityWarning, "unescaped '<' in cue text"})
+	}
+
+	return problems
+}
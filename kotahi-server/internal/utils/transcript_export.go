@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"video-player-backend/internal/models"
+)
+
+// Transcript export formats
+const (
+	ExportFormatSRT      = "srt"
+	ExportFormatVTT      = "vtt"
+	ExportFormatText     = "txt"
+	ExportFormatMarkdown = "md"
+	ExportFormatHTML     = "html"
+)
+
+// TranscriptExport is a transcript prepared for printing, with its vocabulary glossary
+type TranscriptExport struct {
+	Title    string                 // Video title
+	Track    models.SubtitleTrack   // Track the cues come from
+	Cues     []models.TranscriptCue // Cues with their vocabulary spans
+	Glossary []models.GlossaryEntry // Vocabulary found in the video, in alphabetical order
+}
+
+// transcriptSegment is a run of cue text that is either a vocabulary match or plain text
+type transcriptSegment struct {
+	text  string
+	vocab *models.VocabularySpan
+}
+
+// WriteSRT writes a subtitle document as SubRip, with cues numbered from 1 and markup removed
+func WriteSRT(doc *SubtitleDocument) string {
+	var b strings.Builder
+
+	n := 0
+	for _, cue := range doc.Cues {
+		var lines []string
+		for _, line := range strings.Split(cue.Text, "\n") {
+			// Blank lines would end the cue early
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		n++
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", n, formatSRTTimestamp(cue.StartTime), formatSRTTimestamp(cue.EndTime))
+		b.WriteString(strings.Join(lines, "\n") + "\n\n")
+	}
+
+	return b.String()
+}
+
+// WriteTranscriptText writes a transcript as plain text, one cue per line with its speaker
+func WriteTranscriptText(export *TranscriptExport) string {
+	var b strings.Builder
+
+	for _, cue := range export.Cues {
+		text := strings.Join(strings.Fields(cue.Text), " ")
+		if text == "" {
+			continue
+		}
+		if cue.Speaker != "" {
+			b.WriteString(cue.Speaker + ": ")
+		}
+		b.WriteString(text + "\n")
+	}
+
+	return b.String()
+}
+
+// WriteTranscriptMarkdown writes a transcript as Markdown with timestamps, speakers and bold
+// vocabulary, followed by a glossary table
+func WriteTranscriptMarkdown(export *TranscriptExport) string {
+	var b strings.Builder
+
+	b.WriteString("# " + escapeMarkdown(export.Title) + "\n\n")
+	if export.Track.Label != "" {
+		b.WriteString("_" + escapeMarkdown(export.Track.Label) + "_\n\n")
+	}
+
+	for _, cue := range export.Cues {
+		segments := transcriptSegments(cue)
+		if len(segments) == 0 {
+			continue
+		}
+
+		b.WriteString("`" + formatClock(cue.StartTime) + "` ")
+		if cue.Speaker != "" {
+			b.WriteString("*" + escapeMarkdown(cue.Speaker) + ":* ")
+		}
+		for _, segment := range segments {
+			if segment.vocab != nil {
+				b.WriteString("**" + escapeMarkdown(segment.text) + "**")
+			} else {
+				b.WriteString(escapeMarkdown(segment.text))
+			}
+		}
+		b.WriteString("\n\n")
+	}
+
+	if len(export.Glossary) > 0 {
+		b.WriteString("## Glossary\n\n")
+		b.WriteString("| Māori | English | Description |\n")
+		b.WriteString("| --- | --- | --- |\n")
+		for _, entry := range export.Glossary {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", escapeMarkdown(entry.Maori), escapeMarkdown(entry.English), escapeMarkdown(entry.Description))
+		}
+	}
+
+	return b.String()
+}
+
+// WriteTranscriptHTML writes a transcript as a printable HTML page with timestamps, speakers and
+// bold vocabulary, followed by a glossary table
+func WriteTranscriptHTML(export *TranscriptExport) string {
+	var b strings.Builder
+	esc := html.EscapeString
+
+	lang := export.Track.Language
+	if lang == "" {
+		lang = models.LanguageMaori
+	}
+
+	b.WriteString("<!DOCTYPE html>\n")
+	fmt.Fprintf(&b, "<html lang=\"%s\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", esc(lang), esc(export.Title))
+	b.WriteString("<style>\n" +
+		"body { font-family: Georgia, serif; line-height: 1.5; max-width: 45em; margin: 2em auto; padding: 0 1em; }\n" +
+		".time { color: #666; font-family: monospace; }\n" +
+		".speaker { font-style: italic; }\n" +
+		"table { border-collapse: collapse; width: 100%; }\n" +
+		"th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; vertical-align: top; }\n" +
+		"@media print { body { margin: 0; max-width: none; } }\n" +
+		"</style>\n</head>\n<body>\n")
+
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", esc(export.Title))
+	if export.Track.Label != "" {
+		fmt.Fprintf(&b, "<p class=\"track\">%s</p>\n", esc(export.Track.Label))
+	}
+
+	for _, cue := range export.Cues {
+		segments := transcriptSegments(cue)
+		if len(segments) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "<p class=\"cue\"><span class=\"time\">%s</span> ", formatClock(cue.StartTime))
+		if cue.Speaker != "" {
+			fmt.Fprintf(&b, "<span class=\"speaker\">%s:</span> ", esc(cue.Speaker))
+		}
+		for _, segment := range segments {
+			if segment.vocab != nil {
+				fmt.Fprintf(&b, "<strong title=\"%s\">%s</strong>", esc(segment.vocab.English), esc(segment.text))
+			} else {
+				b.WriteString(esc(segment.text))
+			}
+		}
+		b.WriteString("</p>\n")
+	}
+
+	if len(export.Glossary) > 0 {
+		b.WriteString("<h2>Glossary</h2>\n<table>\n<thead><tr><th>Māori</th><th>English</th><th>Description</th></tr></thead>\n<tbody>\n")
+		for _, entry := range export.Glossary {
+			fmt.Fprintf(&b, "<tr><td lang=\"mi\">%s</td><td lang=\"en\">%s</td><td>%s</td></tr>\n", esc(entry.Maori), esc(entry.English), esc(entry.Description))
+		}
+		b.WriteString("</tbody>\n</table>\n")
+	}
+
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// transcriptSegments splits a cue's text at its vocabulary spans, with the cue's lines joined by
+// spaces. Where spans overlap the earliest, longest one wins.
+func transcriptSegments(cue models.TranscriptCue) []transcriptSegment {
+	if strings.TrimSpace(cue.Text) == "" {
+		return nil
+	}
+
+	runes := []rune(cue.Text)
+	var segments []transcriptSegment
+
+	appendText := func(text string) {
+		text = strings.ReplaceAll(text, "\n", " ")
+		if text != "" {
+			segments = append(segments, transcriptSegment{text: text})
+		}
+	}
+
+	pos := 0
+	for i := range cue.Spans {
+		span := &cue.Spans[i]
+		if span.Start < pos || span.End > len(runes) || span.Start >= span.End {
+			continue
+		}
+		appendText(string(runes[pos:span.Start]))
+		segments = append(segments, transcriptSegment{text: string(runes[span.Start:span.End]), vocab: span})
+		pos = span.End
+	}
+	appendText(string(runes[pos:]))
+
+	return segments
+}
+
+// escapeMarkdown escapes the characters that Markdown would treat as formatting
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+		"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "~", `\~`, "\n", " ",
+	).Replace(text)
+}
+
+// formatSRTTimestamp formats seconds as an SRT timestamp, HH:MM:SS,mmm
+func formatSRTTimestamp(seconds float64) string {
+	return strings.Replace(FormatVTTTimestamp(seconds), ".", ",", 1)
+}
+
+// formatClock formats seconds as a short clock time for printed transcripts, e.g. 1:05 or 1:02:05
+func formatClock(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	total := int64(seconds)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"video-player-backend/internal/models"
+)
+
+// VideoManifestRow represents a single video entry in a bulk import manifest
+type VideoManifestRow struct {
+	Row     int                 `json:"row"` // Row number in the manifest, used when reporting errors
+	Request models.VideoRequest `json:"request"`
+}
+
+// videoManifestColumns maps accepted CSV header names to video request fields
+var videoManifestColumns = map[string]string{
+	"title":         "title",
+	"description":   "description",
+	"video":         "video",
+	"video_url":     "video",
+	"url":           "video",
+	"thumbnail":     "thumbnail",
+	"thumbnail_url": "thumbnail",
+	"subtitle":      "subtitle",
+	"subtitle_file": "subtitle",
+	"vtt":           "subtitle",
+	"duration":      "duration",
+	"source":        "source",
+	"tags":          "tags",
+	"series":        "series",
+}
+
+// ParseVideoManifestCSV parses a CSV manifest of videos to import
+// The first row must be a header naming the columns, e.g. title,description,video,thumbnail,subtitle,duration
+func ParseVideoManifestCSV(reader io.Reader) ([]VideoManifestRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = -1
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+
+	// Map each column to a video request field using the header row
+	columns := make([]string, len(records[0]))
+	hasVideo := false
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		field, ok := videoManifestColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column '%s' in header row", name)
+		}
+		columns[i] = field
+		hasVideo = hasVideo || field == "video"
+	}
+	if !hasVideo {
+		return nil, fmt.Errorf("header row must include a video column")
+	}
+
+	var rows []VideoManifestRow
+	for i, record := range records[1:] {
+		row := VideoManifestRow{Row: i + 2}
+		for j, value := range record {
+			if j >= len(columns) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch columns[j] {
+			case "title":
+				row.Request.Title = value
+			case "description":
+				row.Request.Description = value
+			case "video":
+				row.Request.Video = value
+			case "thumbnail":
+				row.Request.Thumbnail = value
+			case "subtitle":
+				row.Request.Subtitle = value
+			case "duration":
+				row.Request.Duration = value
+			case "source":
+				row.Request.Source = value
+			case "tags":
+				// Tags are separated by semicolons so they don't clash with the CSV delimiter
+				row.Request.Tags = strings.Split(value, ";")
+			case "series":
+				row.Request.Series = value
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV file only contains headers, no data rows")
+	}
+
+	return rows, nil
+}
+
+// ParseVideoManifestJSON parses a JSON manifest of videos to import
+// The manifest is either an array of video objects or an object with a "videos" array
+func ParseVideoManifestJSON(reader io.Reader) ([]VideoManifestRow, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	var requests []models.VideoRequest
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Videos []models.VideoRequest `json:"videos"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid JSON manifest: %w", err)
+		}
+		requests = wrapper.Videos
+	} else if err := json.Unmarshal(trimmed, &requests); err != nil {
+		return nil, fmt.Errorf("invalid JSON manifest: %w", err)
+	}
+
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("manifest contains no videos")
+	}
+
+	rows := make([]VideoManifestRow, len(requests))
+	for i, req := range requests {
+		rows[i] = VideoManifestRow{Row: i + 1, Request: req}
+	}
+
+	return rows, nil
+}
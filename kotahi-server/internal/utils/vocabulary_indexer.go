@@ -1,23 +1,124 @@
 package utils
 
 import (
-	"fmt"
-	"regexp"
+	"sort"
 	"strings"
+	"unicode"
 
 	"video-player-backend/internal/models"
 )
 
-// VocabularyIndexer handles indexing vocabulary words in transcript text
+// VocabularyIndexer handles indexing vocabulary words in transcript text. The vocabulary is
+// compiled once into a trie of normalised words, so finding matches costs time proportional to the
+// length of the text rather than the size of the vocabulary. An indexer is safe for concurrent use.
 type VocabularyIndexer struct {
-	vocabularies []*models.Vocabulary
+	root  *vocabularyTrieNode
+	words map[string]*vocabularyWord // Every normalised word of every headword, variant and form
+	size  int                        // Number of vocabulary entries
+}
+
+// vocabularyWord is a normalised word of the vocabulary and the inflections of it that are matched
+type vocabularyWord struct {
+	suffixes  bool            // Passives and nominalisations, for verbs and nouns
+	prefixes  bool            // whaka- and kai- derivations, for verbs and statives
+	spellings map[string]bool // Its spellings with long vowels marked, see markMaoriVowelLength
+}
+
+// Parts of speech that take suffixes and prefixes. Words of other entries, including those without
+// a part of speech, are only matched as written.
+var (
+	suffixedPartsOfSpeech = map[string]bool{"verb": true, "noun": true}
+	prefixedPartsOfSpeech = map[string]bool{"verb": true, "stative": true}
+)
+
+// vocabularyTrieNode is a node of the vocabulary trie. Each edge is a normalised word, so the path
+// from the root to a node spells a word or phrase.
+type vocabularyTrieNode struct {
+	children map[string]*vocabularyTrieNode
+	entries  []vocabularyTrieEntry // Headwords, variants and forms that end at this node
+}
+
+// vocabularyTrieEntry is a vocabulary entry reached through one of its spellings
+type vocabularyTrieEntry struct {
+	vocab   *models.Vocabulary
+	form    string // Variant or form spelled by the path, empty for the headword
+	order   int    // Position of the entry in the vocabulary, so results keep its order
+	surface int    // Position of the spelling in vocab.Surfaces(), the headword first
 }
 
 // NewVocabularyIndexer creates a new vocabulary indexer
 func NewVocabularyIndexer(vocabularies []*models.Vocabulary) *VocabularyIndexer {
-	return &VocabularyIndexer{
-		vocabularies: vocabularies,
+	vi := &VocabularyIndexer{
+		root:  &vocabularyTrieNode{},
+		words: make(map[string]*vocabularyWord),
+		size:  len(vocabularies),
+	}
+
+	for order, vocab := range vocabularies {
+		vi.addWords(vocab)
+		for i, surface := range vocab.Surfaces() {
+			words := vocabularyWords(surface)
+			if len(words) == 0 {
+				continue
+			}
+
+			node := vi.root
+			for _, word := range words {
+				child := node.children[word]
+				if child == nil {
+					child = &vocabularyTrieNode{}
+					if node.children == nil {
+						node.children = make(map[string]*vocabularyTrieNode)
+					}
+					node.children[word] = child
+				}
+				node = child
+			}
+
+			entry := vocabularyTrieEntry{vocab: vocab, order: order, surface: i}
+			if i > 0 {
+				entry.form = surface
+			}
+			node.add(entry)
+		}
+	}
+
+	return vi
+}
+
+// addWords records the words of every spelling of an entry, and the inflections they take
+func (vi *VocabularyIndexer) addWords(vocab *models.Vocabulary) {
+	for _, surface := range vocab.Surfaces() {
+		spellings := vocabularySpellings(surface)
+		for i, word := range vocabularyWords(surface) {
+			w := vi.words[word]
+			if w == nil {
+				w = &vocabularyWord{spellings: make(map[string]bool)}
+				vi.words[word] = w
+			}
+			w.suffixes = w.suffixes || suffixedPartsOfSpeech[vocab.PartOfSpeech]
+			w.prefixes = w.prefixes || prefixedPartsOfSpeech[vocab.PartOfSpeech]
+			if i < len(spellings) {
+				w.spellings[spellings[i]] = true
+			}
+		}
+	}
+}
+
+// Len returns the number of vocabulary entries the indexer finds
+func (vi *VocabularyIndexer) Len() int {
+	return vi.size
+}
+
+// add records that an entry ends at the node. A variant spelled like the headword, or like
+// another variant, is only kept once.
+func (n *vocabularyTrieNode) add(entry vocabularyTrieEntry) {
+	for _, existing := range n.entries {
+		if existing.vocab == entry.vocab {
+			return
+		}
 	}
+	n.entries = append(n.entries, entry)
 }
 
 // IndexTranscript indexes vocabulary words found in transcript text
@@ -36,178 +137,320 @@ func (vi *VocabularyIndexer) IndexTranscript(videoID string, transcriptLines []T
 // findVocabularyInLine finds vocabulary words in a single transcript line
 func (vi *VocabularyIndexer) findVocabularyInLine(videoID string, line TranscriptLine, lineNum int) []*models.VocabularyIndex {
 	var indexes []*models.VocabularyIndex
+	seen := make(map[*models.Vocabulary]bool)
 
-	for _, vocab := range vi.vocabularies {
-		// Check if the Māori word appears in this transcript line
-		if vi.isWordInText(vocab.Maori, line.Text) {
-			index := &models.VocabularyIndex{
-				VideoID:     videoID,
-				Vocabulary:  vocab.Maori,
-				English:     vocab.English,
-				Description: vocab.Description,
-				StartTime:   line.StartTime,
-				EndTime:     line.EndTime,
-				Transcript:  line.Text,
-				LineNumber:  lineNum + 1, // 1-based line numbering
-			}
-			indexes = append(indexes, index)
+	for _, match := range vi.FindMatches(line.Text) {
+		// Record each vocabulary item once per line
+		if seen[match.Vocabulary] {
+			continue
+		}
+		seen[match.Vocabulary] = true
+
+		index := &models.VocabularyIndex{
+			VideoID:      videoID,
+			VocabularyID: match.Vocabulary.ID,
+			Vocabulary:   match.Vocabulary.Maori,
+			MatchedForm:  match.Form,
+			MatchType:    match.Type,
+			English:      match.Vocabulary.English,
+			Description:  match.Vocabulary.Description,
+			StartTime:    line.StartTime,
+			EndTime:      line.EndTime,
+			Transcript:   line.Text,
+			LineNumber:   lineNum + 1, // 1-based line numbering
 		}
+		indexes = append(indexes, index)
 	}
 
 	return indexes
 }
 
-// isWordInText checks if a vocabulary word appears in the given text
-func (vi *VocabularyIndexer) isWordInText(word, text string) bool {
-	// Normalize case
-	wordLower := strings.ToLower(strings.TrimSpace(word))
-	textLower := strings.ToLower(text)
+// VocabularyMatch is an occurrence of a vocabulary word or phrase in a piece of text
+type VocabularyMatch struct {
+	Vocabulary *models.Vocabulary
+	Form       string // Variant or inflected form that matched, empty when the headword matched
+	Type       string // models.MatchTypeExact, or models.MatchTypeInflected when found through MaoriStems
+	Start      int    // Offset of the first rune of the match
+	End        int    // Offset just past the last rune of the match
+}
 
-	if wordLower == "" || textLower == "" {
-		return false
+// textToken is a word in a piece of text with punctuation trimmed from both ends
+type textToken struct {
+	word     string // Lowercase word
+	key      string // Word normalised with NormalizeMaori, used for comparing
+	spelling string // Word with long vowels marked, see markMaoriVowelLength
+	stem     string // Vocabulary word the token is an inflection of, set by FindMatches
+	start    int    // Rune offset of the word
+	end      int
+}
+
+// tokenPunctuation is trimmed from both ends of each word before comparing
+const tokenPunctuation = ".,!?;:\"'-()[]{}‘’“”"
+
+// FindMatches finds every occurrence of the vocabulary in text, comparing whole words regardless of
+// case, macrons, doubled vowels, apostrophes and hyphens. Phrases match a run of consecutive words.
+// An entry's variants and listed forms count as occurrences of its headword, and so do words that
+// are recognised as passives, nominalisations or whaka-/kai- derivations of it, when its part of
+// speech takes them. Offsets are in runes.
+func (vi *VocabularyIndexer) FindMatches(text string) []VocabularyMatch {
+	tokens := vi.tokenize(text)
+	if len(tokens) == 0 {
+		return nil
 	}
 
-	// Handle multi-word phrases with simple contains check
-	if strings.Contains(wordLower, " ") {
-		return strings.Contains(textLower, wordLower)
+	var matches []trieMatch
+	for i := range tokens {
+		// Each entry is reported once per starting word, through its earliest spelling
+		found := make(map[*models.Vocabulary]int)
+		vi.root.walk(tokens, i, i, false, func(match trieMatch) {
+			if j, ok := found[match.entry.vocab]; ok {
+				if matches[j].entry.surface > match.entry.surface {
+					matches[j] = match
+				}
+				return
+			}
+			found[match.entry.vocab] = len(matches)
+			matches = append(matches, match)
+		})
 	}
 
-	// For single words, use a more flexible approach that works better with Māori language
-	// Split text into words and check each one
-	words := regexp.MustCompile(`\s+`).Split(textLower, -1)
-	for _, w := range words {
-		// Remove punctuation from both sides for comparison
-		w = strings.Trim(w, ".,!?;:\"'-()[]{}")
-		if w == wordLower {
-			return true
+	// Order by position, longer matches first when they start at the same place, then by vocabulary order
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
 		}
+		if matches[i].End != matches[j].End {
+			return matches[i].End > matches[j].End
+		}
+		return matches[i].entry.order < matches[j].entry.order
+	})
+
+	result := make([]VocabularyMatch, len(matches))
+	for i, match := range matches {
+		result[i] = match.VocabularyMatch
 	}
-	return false
+	return result
 }
 
-// TranscriptLine represents a single line in a transcript
-type TranscriptLine struct {
-	StartTime float64
-	EndTime   float64
-	Text      string
+// tokenize splits text into tokens and sets the stem of each word that isn't in the vocabulary
+// but may be an inflection of a word that is
+func (vi *VocabularyIndexer) tokenize(text string) []textToken {
+	tokens := tokenizeText(text)
+	for i := range tokens {
+		if vi.words[tokens[i].key] != nil {
+			continue
+		}
+		for _, stem := range MaoriStems(tokens[i].key) {
+			if vi.inflects(tokens[i], stem) {
+				tokens[i].stem = stem.Word
+				break
+			}
+		}
+	}
+	return tokens
 }
 
-// ParseVTTToLines parses VTT content into transcript lines
-func ParseVTTToLines(vttContent string) ([]TranscriptLine, error) {
-	lines := strings.Split(vttContent, "\n")
-	var transcriptLines []TranscriptLine
-	var currentLine *TranscriptLine
+// inflects reports whether a token may be an inflection of the vocabulary word stem. The word's part
+// of speech must take the affixes removed, and where the token marks a long vowel the stem must be
+// spelled as the word is, so "kāinga" (home) is not taken for a form of "kai".
+func (vi *VocabularyIndexer) inflects(token textToken, stem MaoriStem) bool {
+	word := vi.words[stem.Word]
+	if word == nil || (stem.Suffixed && !word.suffixes) || (stem.Prefixed && !word.prefixes) {
+		return false
+	}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	spelling := []rune(token.spelling)
+	end := stem.Start + len([]rune(stem.Word))
+	if len(spelling) != len([]rune(token.key)) || end > len(spelling) {
+		return true // The spellings don't line up, so vowel length can't be compared
+	}
+	stemSpelling := string(spelling[stem.Start:end])
+	return stemSpelling == stem.Word || word.spellings[stemSpelling]
+}
 
-		// Skip empty lines and VTT headers
-		if line == "" || line == "WEBVTT" || strings.HasPrefix(line, "NOTE") {
-			continue
-		}
+// trieMatch is a match together with the trie entry it came from
+type trieMatch struct {
+	VocabularyMatch
+	entry vocabularyTrieEntry
+}
 
-		// Check if this line contains timing information (format: 00:00:00.000 --> 00:00:00.000)
-		if strings.Contains(line, "-->") {
-			// Parse timing information
-			startTime, endTime, err := parseVTTTiming(line)
-			if err != nil {
-				continue // Skip malformed timing lines
-			}
+// walk follows the trie from the node along the tokens from next on, by each token's normalised
+// word or its stem, and reports every entry it reaches. A path that used a stem is an inflected
+// match, and its form is the words as they appear in the text.
+func (n *vocabularyTrieNode) walk(tokens []textToken, first, next int, inflected bool, report func(trieMatch)) {
+	if next >= len(tokens) || n.children == nil {
+		return
+	}
 
-			currentLine = &TranscriptLine{
-				StartTime: startTime,
-				EndTime:   endTime,
-			}
+	token := tokens[next]
+	for _, step := range []struct {
+		word      string
+		inflected bool
+	}{{token.key, inflected}, {token.stem, true}} {
+		if step.word == "" {
+			continue
+		}
+		child := n.children[step.word]
+		if child == nil {
 			continue
 		}
 
-		// If we have a current line and this is text content
-		if currentLine != nil && line != "" {
-			currentLine.Text = line
-			transcriptLines = append(transcriptLines, *currentLine)
-			currentLine = nil
+		for _, entry := range child.entries {
+			match := trieMatch{
+				VocabularyMatch: VocabularyMatch{
+					Vocabulary: entry.vocab,
+					Form:       entry.form,
+					Type:       models.MatchTypeExact,
+					Start:      tokens[first].start,
+					End:        token.end,
+				},
+				entry: entry,
+			}
+			if step.inflected {
+				forms := make([]string, 0, next-first+1)
+				for _, t := range tokens[first : next+1] {
+					forms = append(forms, t.word)
+				}
+				match.Type = models.MatchTypeInflected
+				match.Form = strings.Join(forms, " ")
+			}
+			report(match)
 		}
+		child.walk(tokens, first, next+1, step.inflected, report)
 	}
-
-	return transcriptLines, nil
 }
 
-// parseVTTTiming parses VTT timing format (00:00:00.000 --> 00:00:00.000)
-func parseVTTTiming(timingLine string) (startTime, endTime float64, err error) {
-	parts := strings.Split(timingLine, " --> ")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("invalid timing format")
-	}
+// tokenizeText splits text on whitespace into lowercase words with their rune offsets
+func tokenizeText(text string) []textToken {
+	runes := []rune(text)
+	var tokens []textToken
 
-	startTime, err = parseVTTTime(parts[0])
-	if err != nil {
-		return 0, 0, err
-	}
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			i++
+		}
+		end := i
 
-	endTime, err = parseVTTTime(parts[1])
-	if err != nil {
-		return 0, 0, err
+		// Trim punctuation from both sides
+		for start < end && strings.ContainsRune(tokenPunctuation, runes[start]) {
+			start++
+		}
+		for end > start && strings.ContainsRune(tokenPunctuation, runes[end-1]) {
+			end--
+		}
+		if start == end {
+			continue
+		}
+
+		word := strings.ToLower(string(runes[start:end]))
+		key := NormalizeMaori(word)
+		if key == "" {
+			continue
+		}
+		tokens = append(tokens, textToken{
+			word:     word,
+			key:      key,
+			spelling: markMaoriVowelLength(word),
+			start:    start,
+			end:      end,
+		})
 	}
 
-	return startTime, endTime, nil
+	return tokens
 }
 
-// parseVTTTime parses a single VTT time format (00:00:00.000)
-func parseVTTTime(timeStr string) (float64, error) {
-	// Remove any additional formatting (like positioning info)
-	timeStr = strings.Split(timeStr, " ")[0]
+// VocabularyWords is a set of normalised words of vocabulary entries
+type VocabularyWords map[string]bool
 
-	parts := strings.Split(timeStr, ":")
-	if len(parts) != 3 {
-		return 0, fmt.Errorf("invalid time format")
+// WordsOf returns the normalised words of every spelling of the entries
+func WordsOf(entries ...*models.Vocabulary) VocabularyWords {
+	words := make(VocabularyWords)
+	for _, vocab := range entries {
+		for _, surface := range vocab.Surfaces() {
+			for _, word := range vocabularyWords(surface) {
+				words[word] = true
+			}
+		}
 	}
+	return words
+}
 
-	// Parse hours
-	hours, err := parseFloat(parts[0])
-	if err != nil {
-		return 0, err
+// Mentioned reports whether any of the lines has one of the words, or a word that may be an
+// inflection of one. When some entries are added, edited or removed, only lines that mention one
+// of their words, before or after the change, can be indexed differently: every other word is
+// matched to the same entries, exactly or as an inflection, with or without them.
+func (words VocabularyWords) Mentioned(transcriptLines []TranscriptLine) bool {
+	for _, line := range transcriptLines {
+		for _, token := range tokenizeText(line.Text) {
+			if words[token.key] {
+				return true
+			}
+			for _, stem := range MaoriStems(token.key) {
+				if words[stem.Word] {
+					return true
+				}
+			}
+		}
 	}
+	return false
+}
 
-	// Parse minutes
-	minutes, err := parseFloat(parts[1])
-	if err != nil {
-		return 0, err
+// vocabularyWords splits a vocabulary word or phrase into words normalised with NormalizeMaori
+func vocabularyWords(maori string) []string {
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(maori)) {
+		if word = NormalizeMaori(strings.Trim(word, tokenPunctuation)); word != "" {
+			words = append(words, word)
+		}
 	}
+	return words
+}
 
-	// Parse seconds (which may include milliseconds)
-	seconds, err := parseFloat(parts[2])
-	if err != nil {
-		return 0, err
+// vocabularySpellings splits a vocabulary word or phrase like vocabularyWords, but with long vowels
+// marked by markMaoriVowelLength
+func vocabularySpellings(maori string) []string {
+	var spellings []string
+	for _, word := range strings.Fields(strings.ToLower(maori)) {
+		if word = markMaoriVowelLength(strings.Trim(word, tokenPunctuation)); word != "" {
+			spellings = append(spellings, word)
+		}
 	}
+	return spellings
+}
 
-	totalSeconds := hours*3600 + minutes*60 + seconds
-	return totalSeconds, nil
+// TranscriptLine represents a single line in a transcript
+type TranscriptLine struct {
+	StartTime float64
+	EndTime   float64
+	Text      string
 }
 
-// parseFloat is a simple float parser (you might want to use strconv.ParseFloat instead)
-func parseFloat(s string) (float64, error) {
-	// This is a simplified version - in production you'd use strconv.ParseFloat
-	var result float64
-	var decimal float64 = 1
-	var inDecimal bool
+// ParseVTTToLines parses VTT content into transcript lines, one per cue.
+// Multi-line cues are joined into a single line of plain text.
+func ParseVTTToLines(vttContent string) ([]TranscriptLine, error) {
+	doc, err := ParseWebVTT(vttContent)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, char := range s {
-		if char == '.' {
-			inDecimal = true
-			continue
-		}
-		if char >= '0' && char <= '9' {
-			digit := float64(char - '0')
-			if inDecimal {
-				decimal /= 10
-				result += digit * decimal
-			} else {
-				result = result*10 + digit
-			}
-		} else {
-			return 0, fmt.Errorf("invalid character in number")
+	var transcriptLines []TranscriptLine
+	for _, cue := range doc.Cues {
+		text := cue.PlainText()
+		if text == "" {
+			continue // Skip cues without text
 		}
+
+		transcriptLines = append(transcriptLines, TranscriptLine{
+			StartTime: cue.StartTime,
+			EndTime:   cue.EndTime,
+			Text:      text,
+		})
 	}
 
-	return result, nil
+	return transcriptLines, nil
 }
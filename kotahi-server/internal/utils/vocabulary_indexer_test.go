@@ -0,0 +1,505 @@
+package utils
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"video-player-backend/internal/models"
+)
+
+// vocab returns a vocabulary entry with its ID set to its headword
+func vocab(maori string, variants ...string) *models.Vocabulary {
+	return &models.Vocabulary{ID: maori, Maori: maori, Variants: variants}
+}
+
+// verb returns a vocabulary entry for a verb
+func verb(maori string) *models.Vocabulary {
+	v := vocab(maori)
+	v.PartOfSpeech = "verb"
+	return v
+}
+
+// matchSummary is a match written as "headword[start:end]" with its form and type when inflected
+func matchSummary(match VocabularyMatch) string {
+	summary := fmt.Sprintf("%s[%d:%d]", match.Vocabulary.Maori, match.Start, match.End)
+	if match.Form != "" {
+		summary += " " + match.Form
+	}
+	if match.Type == models.MatchTypeInflected {
+		summary += " (inflected)"
+	}
+	return summary
+}
+
+func summarize(matches []VocabularyMatch) []string {
+	summaries := make([]string, len(matches))
+	for i, match := range matches {
+		summaries[i] = matchSummary(match)
+	}
+	return summaries
+}
+
+func TestFindMatches(t *testing.T) {
+	vocabulary := []*models.Vocabulary{
+		vocab("ora"),
+		vocab("kia ora"),
+		vocab("kia ora koutou"),
+		vocab("koutou"),
+		vocab("te reo"),
+		vocab("reo Māori"),
+		vocab("te reo Māori"),
+		verb("karanga"),
+		verb("whakaako"),
+		vocab("tēnā koe", "tena koe"),
+		vocab("whānau", "fānau"),
+	}
+	indexer := NewVocabularyIndexer(vocabulary)
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "longest match first, then the phrases and words inside it",
+			text: "Kia ora koutou katoa",
+			want: []string{"kia ora koutou[0:14]", "kia ora[0:7]", "ora[4:7]", "koutou[8:14]"},
+		},
+		{
+			name: "overlapping multi-word entries",
+			text: "Ko te reo Māori tēnei",
+			want: []string{"te reo Māori[3:15]", "te reo[3:9]", "reo Māori[6:15]"},
+		},
+		{
+			name: "a phrase split by other words doesn't match",
+			text: "kia mau te ora",
+			want: []string{"ora[11:14]"},
+		},
+		{
+			name: "punctuation, case and spelling are ignored",
+			text: "“KIA ORA!” Te Reo Maaori.",
+			want: []string{"kia ora[1:8]", "ora[5:8]", "te reo Māori[11:24]", "te reo[11:17]", "reo Māori[14:24]"},
+		},
+		{
+			name: "a variant spelled like the headword matches as the headword",
+			text: "tena koe",
+			want: []string{"tēnā koe[0:8]"},
+		},
+		{
+			name: "a variant matches the headword",
+			text: "tōku fānau",
+			want: []string{"whānau[5:10] fānau"},
+		},
+		{
+			name: "inflections match the headword",
+			text: "I karangatia rātou, ā, i whakaakona",
+			want: []string{"karanga[2:12] karangatia (inflected)", "whakaako[25:35] whakaakona (inflected)"},
+		},
+		{
+			name: "only whole words match",
+			text: "Ōrākei koutoumaha",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(indexer.FindMatches(tt.text))
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("FindMatches(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexTranscriptRecordsEachEntryOncePerLine(t *testing.T) {
+	indexer := NewVocabularyIndexer([]*models.Vocabulary{vocab("ora"), vocab("kia ora")})
+	lines := []TranscriptLine{
+		{StartTime: 1, EndTime: 2, Text: "Kia ora, kia ora tātou"},
+		{StartTime: 3, EndTime: 4, Text: "Ka ora anō"},
+	}
+
+	indexes, err := indexer.IndexTranscript("video", lines)
+	if err != nil {
+		t.Fatalf("IndexTranscript() error = %v", err)
+	}
+
+	var got []string
+	for _, index := range indexes {
+		got = append(got, fmt.Sprintf("%d:%s", index.LineNumber, index.Vocabulary))
+	}
+	want := "1:kia ora, 1:ora, 2:ora"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("IndexTranscript() = %q, want %q", got, want)
+	}
+}
+
+// TestTrieMatchesPerEntry checks that the trie finds the same matches as comparing every entry
+// with the text in turn
+func TestTrieMatchesPerEntry(t *testing.T) {
+	vocabulary, transcript := benchmarkCorpus(500, 200)
+	indexer := NewVocabularyIndexer(vocabulary)
+
+	for _, line := range transcript {
+		got := summarize(indexer.FindMatches(line.Text))
+		want := summarize(perEntryMatches(indexer, vocabulary, line.Text))
+		sort.Strings(got)
+		sort.Strings(want)
+		if strings.Join(got, ", ") != strings.Join(want, ", ") {
+			t.Fatalf("FindMatches(%q) = %q, per entry %q", line.Text, got, want)
+		}
+	}
+}
+
+// perEntryMatches finds matches the way the indexer did before the trie, by comparing every
+// spelling of every entry with each position in the text. It is the baseline for the benchmarks.
+func perEntryMatches(vi *VocabularyIndexer, vocabularies []*models.Vocabulary, text string) []VocabularyMatch {
+	tokens := vi.tokenize(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var matches []VocabularyMatch
+	for _, vocab := range vocabularies {
+		covered := make(map[int]bool)
+		for i, surface := range vocab.Surfaces() {
+			words := vocabularyWords(surface)
+			if len(words) == 0 {
+				continue
+			}
+
+			for start := 0; start+len(words) <= len(tokens); start++ {
+				matched, inflected := true, false
+				for j, word := range words {
+					token := tokens[start+j]
+					if token.key == word {
+						continue
+					}
+					if token.stem != word {
+						matched = false
+						break
+					}
+					inflected = true
+				}
+				if !matched || covered[tokens[start].start] {
+					continue
+				}
+				covered[tokens[start].start] = true
+
+				match := VocabularyMatch{
+					Vocabulary: vocab,
+					Type:       models.MatchTypeExact,
+					Start:      tokens[start].start,
+					End:        tokens[start+len(words)-1].end,
+				}
+				if i > 0 {
+					match.Form = surface
+				}
+				if inflected {
+					forms := make([]string, len(words))
+					for j := range words {
+						forms[j] = tokens[start+j].word
+					}
+					match.Type = models.MatchTypeInflected
+					match.Form = strings.Join(forms, " ")
+				}
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+	return matches
+}
+
+// benchmarkWords are common words used to fill out the generated transcript lines
+var benchmarkWords = []string{
+	"ko", "te", "ngā", "he", "i", "ki", "kei", "ka", "e", "kua", "kāore", "ana", "ia", "rātou",
+	"tātou", "mātou", "tēnei", "tēnā", "tērā", "nō", "nā", "mō", "mā", "o", "a", "hoki", "anō",
+}
+
+// benchmarkCorpus generates a vocabulary of about size entries, some of them phrases and some with
+// variants, and a transcript of lines lines mixing its words, inflections of them and common words.
+// The same arguments always generate the same corpus.
+func benchmarkCorpus(size, lines int) ([]*models.Vocabulary, []TranscriptLine) {
+	random := rand.New(rand.NewSource(1))
+	syllables := []string{
+		"ka", "ke", "ki", "ko", "ku", "ta", "te", "ti", "to", "tu", "ma", "me", "mi", "mo", "mu",
+		"na", "ne", "pa", "pe", "pi", "po", "pu", "ra", "re", "ri", "ro", "ru", "wa", "wi", "ha",
+		"he", "hi", "ho", "hu", "nga", "ngo", "wha", "whe", "whi", "ā", "ō", "ī",
+	}
+	word := func() string {
+		var b strings.Builder
+		for n := 2 + random.Intn(3); n > 0; n-- {
+			b.WriteString(syllables[random.Intn(len(syllables))])
+		}
+		return b.String()
+	}
+
+	seen := make(map[string]bool)
+	var vocabulary []*models.Vocabulary
+	for len(vocabulary) < size {
+		maori := word()
+		if random.Intn(10) == 0 {
+			maori += " " + word()
+		}
+		key := NormalizeMaori(maori)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		entry := vocab(maori)
+		if random.Intn(3) > 0 {
+			entry.PartOfSpeech = "verb"
+		}
+		if random.Intn(5) == 0 {
+			entry.Variants = []string{strings.ReplaceAll(maori, "a", "ā")}
+		}
+		vocabulary = append(vocabulary, entry)
+	}
+
+	suffixes := []string{"tia", "ngia", "hia", "tanga", "nga", "ina"}
+	transcript := make([]TranscriptLine, lines)
+	for i := range transcript {
+		words := make([]string, 8+random.Intn(8))
+		for j := range words {
+			switch n := random.Intn(10); {
+			case n < 3:
+				words[j] = vocabulary[random.Intn(len(vocabulary))].Maori
+			case n < 4:
+				words[j] = vocabulary[random.Intn(len(vocabulary))].Maori + suffixes[random.Intn(len(suffixes))]
+			case n < 5:
+				words[j] = word()
+			default:
+				words[j] = benchmarkWords[random.Intn(len(benchmarkWords))]
+			}
+		}
+		transcript[i] = TranscriptLine{
+			StartTime: float64(i * 3),
+			EndTime:   float64(i*3 + 3),
+			Text:      strings.Join(words, " ") + ".",
+		}
+	}
+
+	return vocabulary, transcript
+}
+
+// BenchmarkIndexTrie indexes a transcript of 500 lines against 5,000 entries with the trie
+func BenchmarkIndexTrie(b *testing.B) {
+	vocabulary, transcript := benchmarkCorpus(5000, 500)
+	indexer := NewVocabularyIndexer(vocabulary)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range transcript {
+			indexer.FindMatches(line.Text)
+		}
+	}
+}
+
+// BenchmarkIndexPerEntry indexes the same transcript as BenchmarkIndexTrie by comparing every
+// entry with every line, as the indexer did before the trie
+func BenchmarkIndexPerEntry(b *testing.B) {
+	vocabulary, transcript := benchmarkCorpus(5000, 500)
+	indexer := NewVocabularyIndexer(vocabulary)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range transcript {
+			perEntryMatches(indexer, vocabulary, line.Text)
+		}
+	}
+}
+
+// BenchmarkIndexBuild builds the trie for 5,000 entries, which is done once per vocabulary version
+func BenchmarkIndexBuild(b *testing.B) {
+	vocabulary, _ := benchmarkCorpus(5000, 0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewVocabularyIndexer(vocabulary)
+	}
+}
+
+// vocabularyChange is a change to a vocabulary: entries added, replaced by another with the same
+// ID, or, when the replacement is nil, removed
+type vocabularyChange struct {
+	name    string
+	added   []*models.Vocabulary
+	edited  map[string]*models.Vocabulary
+	removed []string
+}
+
+// apply returns the vocabulary after the change, and every changed entry as it was before the
+// change and as it is after, as the service passes them to IndexVocabulary
+func (c vocabularyChange) apply(vocabulary []*models.Vocabulary) (after, changed []*models.Vocabulary) {
+	removed := make(map[string]bool)
+	for _, id := range c.removed {
+		removed[id] = true
+	}
+	for _, vocab := range vocabulary {
+		switch edit, ok := c.edited[vocab.ID]; {
+		case removed[vocab.ID]:
+			changed = append(changed, vocab)
+		case ok:
+			after = append(after, edit)
+			changed = append(changed, vocab, edit)
+		default:
+			after = append(after, vocab)
+		}
+	}
+	after = append(after, c.added...)
+	changed = append(changed, c.added...)
+	return after, changed
+}
+
+// indexRows writes each index entry of the transcripts as a string, in a stable order
+func indexRows(t *testing.T, indexer *VocabularyIndexer, transcripts [][]TranscriptLine, skip func(video int) bool) []string {
+	var rows []string
+	for i, lines := range transcripts {
+		if skip != nil && skip(i) {
+			continue
+		}
+		indexes, err := indexer.IndexTranscript(fmt.Sprint(i), lines)
+		if err != nil {
+			t.Fatalf("IndexTranscript() error = %v", err)
+		}
+		for _, index := range indexes {
+			rows = append(rows, fmt.Sprintf("%s:%d %s %s %q %s %s", index.VideoID, index.LineNumber,
+				index.VocabularyID, index.Vocabulary, index.MatchedForm, index.MatchType, index.English))
+		}
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+// incrementalRows indexes the transcripts after a change the way the index service updates it:
+// transcripts that mention a changed entry are indexed again with the new vocabulary, and the
+// others keep their entries from before the change, less those of the changed entries
+func incrementalRows(t *testing.T, before, after, changed []*models.Vocabulary, transcripts [][]TranscriptLine) []string {
+	words := WordsOf(changed...)
+	changedIDs := make(map[string]bool)
+	for _, vocab := range changed {
+		changedIDs[vocab.ID] = true
+	}
+	mentioned := func(video int) bool { return words.Mentioned(transcripts[video]) }
+
+	rows := indexRows(t, NewVocabularyIndexer(after), transcripts, func(video int) bool { return !mentioned(video) })
+	for _, row := range indexRows(t, NewVocabularyIndexer(before), transcripts, mentioned) {
+		if !changedIDs[strings.Fields(row)[1]] {
+			rows = append(rows, row)
+		}
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+// TestIncrementalIndexMatchesFullIndex checks what incremental indexing relies on: after entries
+// are added, edited or removed, indexing again only the transcripts that mention one of them gives
+// the same index as indexing every transcript
+func TestIncrementalIndexMatchesFullIndex(t *testing.T) {
+	entry := func(maori, partOfSpeech, english string) *models.Vocabulary {
+		v := vocab(maori)
+		v.PartOfSpeech = partOfSpeech
+		v.English = english
+		return v
+	}
+	vocabulary := []*models.Vocabulary{
+		entry("karanga", "verb", "call"),
+		entry("ako", "verb", "learn"),
+		entry("kai", "noun", "food"),
+		entry("kia ora", "phrase", "hello"),
+		entry("ora", "stative", "well"),
+		entry("pai", "stative", "good"),
+		entry("whakarongo", "verb", "listen"),
+		entry("waiata", "noun", "song"),
+	}
+	transcripts := [][]TranscriptLine{
+		{{Text: "Kia ora koutou"}, {Text: "I karangatia rātou ki te kai"}},
+		{{Text: "Ka whakaakona ngā tamariki"}, {Text: "He kaiako pai ia"}},
+		{{Text: "Whakarongona te waiata"}, {Text: "Ka waiatatia e rātou"}},
+		{{Text: "He kāinga pai tēnei"}, {Text: "Kua kainga te kai"}},
+		{{Text: "Tēnā koutou katoa"}},
+	}
+
+	changes := []vocabularyChange{
+		{name: "add a word", added: []*models.Vocabulary{entry("koutou", "pronoun", "you")}},
+		{name: "add a headword that was an inflection", added: []*models.Vocabulary{entry("karangatia", "verb", "be called")}},
+		{name: "add a word inflections now match", added: []*models.Vocabulary{entry("whakaako", "verb", "teach")}},
+		{name: "add a phrase", added: []*models.Vocabulary{entry("kia ora koutou", "phrase", "hello all")}},
+		{name: "remove a word", removed: []string{"kai"}},
+		{name: "remove a phrase sharing a word", removed: []string{"kia ora"}},
+		{name: "respell a word", edited: map[string]*models.Vocabulary{"ako": {ID: "ako", Maori: "āko", PartOfSpeech: "verb"}}},
+		{name: "change a part of speech", edited: map[string]*models.Vocabulary{"waiata": entry("waiata", "particle", "song")}},
+		{name: "change a meaning", edited: map[string]*models.Vocabulary{"pai": entry("pai", "stative", "fine")}},
+		{name: "change a spelling to another word", edited: map[string]*models.Vocabulary{"ora": {ID: "ora", Maori: "koutou", PartOfSpeech: "pronoun"}}},
+	}
+
+	for _, change := range changes {
+		t.Run(change.name, func(t *testing.T) {
+			after, changed := change.apply(vocabulary)
+			want := indexRows(t, NewVocabularyIndexer(after), transcripts, nil)
+			got := incrementalRows(t, vocabulary, after, changed, transcripts)
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("incremental index:\n%s\nfull index:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+
+	// Shadowing needs the change to reverse cleanly too
+	t.Run("remove a headword that shadowed an inflection", func(t *testing.T) {
+		before := append(append([]*models.Vocabulary{}, vocabulary...), entry("karangatia", "verb", "be called"))
+		after, changed := vocabularyChange{removed: []string{"karangatia"}}.apply(before)
+		want := indexRows(t, NewVocabularyIndexer(after), transcripts, nil)
+		got := incrementalRows(t, before, after, changed, transcripts)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("incremental index:\n%s\nfull index:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	})
+}
+
+// TestIncrementalIndexMatchesFullIndexOnCorpus repeats TestIncrementalIndexMatchesFullIndex with
+// random changes to a generated vocabulary
+func TestIncrementalIndexMatchesFullIndexOnCorpus(t *testing.T) {
+	vocabulary, lines := benchmarkCorpus(300, 300)
+	var transcripts [][]TranscriptLine
+	for i := 0; i < len(lines); i += 10 {
+		transcripts = append(transcripts, lines[i:i+10])
+	}
+
+	random := rand.New(rand.NewSource(2))
+	for i := 0; i < 20; i++ {
+		change := vocabularyChange{edited: make(map[string]*models.Vocabulary)}
+		for j := 0; j < 3; j++ {
+			target := vocabulary[random.Intn(len(vocabulary))]
+			other := vocabulary[random.Intn(len(vocabulary))]
+			switch random.Intn(3) {
+			case 0:
+				// A new headword made from an existing one and a suffix, which it may have matched
+				change.added = append(change.added, verb(other.Maori+"tia"))
+			case 1:
+				change.removed = append(change.removed, target.ID)
+			default:
+				edit := *target
+				edit.Maori = other.Maori + "nga"
+				change.edited[target.ID] = &edit
+			}
+		}
+
+		after, changed := change.apply(vocabulary)
+		want := indexRows(t, NewVocabularyIndexer(after), transcripts, nil)
+		got := incrementalRows(t, vocabulary, after, changed, transcripts)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("change %d: incremental index differs from a full index", i)
+		}
+	}
+}
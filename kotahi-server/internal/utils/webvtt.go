@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Cue represents a single timed subtitle cue
+type Cue struct {
+	Index     int               `json:"index"`              // 1-based position of the cue in the file
+	ID        string            `json:"id,omitempty"`       // Optional cue identifier
+	StartTime float64           `json:"start_time"`         // Start time in seconds
+	EndTime   float64           `json:"end_time"`           // End time in seconds
+	Settings  map[string]string `json:"settings,omitempty"` // Cue settings such as align, line, position, size, vertical and region
+	Speaker   string            `json:"speaker,omitempty"`  // Speaker from the first <v> tag
+	RawText   string            `json:"raw_text"`           // Cue payload with markup, lines separated by "\n"
+	Text      string            `json:"text"`               // Plain text with markup removed, lines separated by "\n"
+}
+
+// PlainText returns the cue text with its lines joined by spaces
+func (c *Cue) PlainText() string {
+	return strings.Join(strings.Fields(c.Text), " ")
+}
+
+// ParseWarning describes a problem the parser recovered from
+type ParseWarning struct {
+	Line    int    `json:"line"` // 1-based line number, 0 if it applies to the whole file
+	Message string `json:"message"`
+}
+
+// SubtitleDocument is a parsed subtitle file
+type SubtitleDocument struct {
+	Header   string         `json:"header,omitempty"` // Text after the WEBVTT signature
+	Styles   []string       `json:"styles,omitempty"` // Contents of STYLE blocks
+	Regions  []string       `json:"regions,omitempty"`
+	Notes    []string       `json:"notes,omitempty"`
+	Cues     []Cue          `json:"cues"`
+	Warnings []ParseWarning `json:"warnings,omitempty"`
+}
+
+// warn records a recoverable parse problem
+func (d *SubtitleDocument) warn(line int, format string, args ...interface{}) {
+	d.Warnings = append(d.Warnings, ParseWarning{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	// vttTimestampRegex matches both the mm:ss.ttt and hh:mm:ss.ttt forms, leniently
+	vttTimestampRegex = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{1,2})(?:[.,](\d{1,3}))?$`)
+	// vttStrictTimestampRegex matches timestamps exactly as the WebVTT spec writes them
+	vttStrictTimestampRegex = regexp.MustCompile(`^(?:\d{2,}:)?\d{2}:\d{2}\.\d{3}$`)
+	// vttTagRegex matches cue markup such as <i>, </b>, <c.yellow>, <v Speaker> and <00:00:01.000>
+	vttTagRegex = regexp.MustCompile(`<[^>]*>`)
+	// vttVoiceRegex captures the speaker name from a <v> tag
+	vttVoiceRegex = regexp.MustCompile(`<v(?:\.[^\s>]*)?[ \t]+([^>]*)>`)
+)
+
+// knownCueSettings lists the cue settings defined by the WebVTT spec
+var knownCueSettings = map[string]bool{
+	"vertical": true,
+	"line":     true,
+	"position": true,
+	"size":     true,
+	"align":    true,
+	"region":   true,
+}
+
+// vttBlock is a run of non-blank lines and the line number it starts on
+type vttBlock struct {
+	line  int
+	lines []string
+}
+
+// ParseWebVTT parses WebVTT content into a subtitle document.
+// It handles a byte order mark, CRLF line endings, NOTE, STYLE and REGION blocks,
+// cue identifiers, cue settings, multi-line cues, inline markup and both timestamp forms.
+// Problems that can be recovered from, such as a missing WEBVTT signature or a malformed
+// cue, are reported as warnings rather than errors.
+func ParseWebVTT(content string) (*SubtitleDocument, error) {
+	if !utf8.ValidString(content) {
+		return nil, fmt.Errorf("subtitle is not valid UTF-8")
+	}
+
+	content = strings.ReplaceAll(content, "\x00", "\ufffd")
+
+	doc := &SubtitleDocument{Cues: []Cue{}}
+	blocks := splitVTTBlocks(normalizeSubtitleLines(content))
+
+	// The signature line and any header lines after it form the first block
+	if len(blocks) > 0 && isVTTKeyword(blocks[0].lines[0], "WEBVTT") {
+		header := blocks[0]
+		doc.Header = strings.TrimSpace(strings.TrimPrefix(header.lines[0], "WEBVTT"))
+		blocks = blocks[1:]
+
+		// A cue directly after the header lines still gets parsed
+		for j := 1; j < len(header.lines); j++ {
+			if strings.Contains(header.lines[j], "-->") {
+				doc.warn(header.line+j, "cue must be separated from the WEBVTT header by a blank line")
+				start := j
+				if j > 1 {
+					start = j - 1 // The line before the timing may be a cue identifier
+				}
+				blocks = append([]vttBlock{{line: header.line + start, lines: header.lines[start:]}}, blocks...)
+				break
+			}
+		}
+	} else {
+		doc.warn(1, "missing WEBVTT signature")
+	}
+
+	for i := 0; i < len(blocks); i++ {
+		block := blocks[i]
+		first := block.lines[0]
+
+		switch {
+		case isVTTKeyword(first, "NOTE"):
+			note := strings.TrimSpace(strings.TrimPrefix(first, "NOTE"))
+			doc.Notes = append(doc.Notes, strings.TrimSpace(strings.Join(append([]string{note}, block.lines[1:]...), "\n")))
+			continue
+		case isVTTKeyword(first, "STYLE") && !blockHasTiming(block):
+			if len(doc.Cues) > 0 {
+				doc.warn(block.line, "STYLE block after the first cue is ignored")
+				continue
+			}
+			doc.Styles = append(doc.Styles, strings.Join(block.lines[1:], "\n"))
+			continue
+		case isVTTKeyword(first, "REGION") && !blockHasTiming(block):
+			if len(doc.Cues) > 0 {
+				doc.warn(block.line, "REGION block after the first cue is ignored")
+				continue
+			}
+			doc.Regions = append(doc.Regions, strings.Join(block.lines[1:], " "))
+			continue
+		}
+
+		// A cue is an optional identifier line, a timing line and the payload
+		timingIndex := 0
+		if !strings.Contains(first, "-->") {
+			if len(block.lines) < 2 || !strings.Contains(block.lines[1], "-->") {
+				doc.warn(block.line, "block without a cue timing line skipped")
+				continue
+			}
+			timingIndex = 1
+		}
+
+		// Payload lines cannot contain "-->", so such a line starts a new cue
+		payload := block.lines[timingIndex+1:]
+		for j, line := range payload {
+			if strings.Contains(line, "-->") {
+				doc.warn(block.line+timingIndex+1+j, "cue is missing a blank line before the next cue")
+				blocks = append(blocks[:i+1], append([]vttBlock{{
+					line:  block.line + timingIndex + 1 + j,
+					lines: payload[j:],
+				}}, blocks[i+1:]...)...)
+				payload = payload[:j]
+				break
+			}
+		}
+
+		cue := Cue{Settings: map[string]string{}}
+		if timingIndex == 1 {
+			cue.ID = strings.TrimSpace(first)
+		}
+
+		timingLine := block.line + timingIndex
+		if err := parseCueTiming(doc, timingLine, block.lines[timingIndex], &cue); err != nil {
+			doc.warn(timingLine, "cue skipped: %v", err)
+			continue
+		}
+
+		if cue.EndTime <= cue.StartTime {
+			doc.warn(timingLine, "cue end time %s is not after its start time %s",
+				FormatVTTTimestamp(cue.EndTime), FormatVTTTimestamp(cue.StartTime))
+		}
+		if len(doc.Cues) > 0 && cue.StartTime < doc.Cues[len(doc.Cues)-1].StartTime {
+			doc.warn(timingLine, "cue starts before the previous cue")
+		}
+
+		cue.RawText = strings.Join(payload, "\n")
+		cue.Text, cue.Speaker = stripCueMarkup(cue.RawText)
+		if len(cue.Settings) == 0 {
+			cue.Settings = nil
+		}
+		cue.Index = len(doc.Cues) + 1
+		doc.Cues = append(doc.Cues, cue)
+	}
+
+	return doc, nil
+}
+
+// splitVTTBlocks groups lines into blocks separated by blank lines
+func splitVTTBlocks(lines []string) []vttBlock {
+	var blocks []vttBlock
+	var current *vttBlock
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if current != nil {
+				blocks = append(blocks, *current)
+				current = nil
+			}
+			continue
+		}
+		if current == nil {
+			current = &vttBlock{line: i + 1}
+		}
+		current.lines = append(current.lines, line)
+	}
+	if current != nil {
+		blocks = append(blocks, *current)
+	}
+
+	return blocks
+}
+
+// isVTTKeyword reports whether a line is the keyword alone or followed by a space or tab
+func isVTTKeyword(line, keyword string) bool {
+	if !strings.HasPrefix(line, keyword) {
+		return false
+	}
+	rest := line[len(keyword):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// blockHasTiming reports whether any line of a block is a cue timing line
+func blockHasTiming(block vttBlock) bool {
+	for _, line := range block.lines {
+		if strings.Contains(line, "-->") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCueTiming parses a timing line such as "00:01.000 --> 00:04.000 align:start" into the cue
+func parseCueTiming(doc *SubtitleDocument, lineNumber int, line string, cue *Cue) error {
+	parts := strings.SplitN(line, "-->", 2)
+	start := strings.TrimSpace(parts[0])
+	rest := strings.Fields(parts[1])
+	if start == "" || len(rest) == 0 {
+		return fmt.Errorf("invalid timing line '%s'", strings.TrimSpace(line))
+	}
+
+	var err error
+	if cue.StartTime, err = parseVTTTimestamp(doc, lineNumber, start); err != nil {
+		return err
+	}
+	if cue.EndTime, err = parseVTTTimestamp(doc, lineNumber, rest[0]); err != nil {
+		return err
+	}
+
+	for _, setting := range rest[1:] {
+		name, value, ok := strings.Cut(setting, ":")
+		if !ok || name == "" || value == "" {
+			doc.warn(lineNumber, "invalid cue setting '%s' ignored", setting)
+			continue
+		}
+		if !knownCueSettings[name] {
+			doc.warn(lineNumber, "unknown cue setting '%s' ignored", name)
+			continue
+		}
+		cue.Settings[name] = value
+	}
+
+	return nil
+}
+
+// parseVTTTimestamp parses a timestamp in hh:mm:ss.ttt or mm:ss.ttt form into seconds.
+// Non-standard forms such as a comma separator or missing digits are accepted with a warning.
+func parseVTTTimestamp(doc *SubtitleDocument, lineNumber int, value string) (float64, error) {
+	seconds, err := ParseTimestamp(value)
+	if err != nil {
+		return 0, err
+	}
+	if doc != nil && !vttStrictTimestampRegex.MatchString(value) {
+		doc.warn(lineNumber, "non-standard timestamp '%s'", value)
+	}
+	return seconds, nil
+}
+
+// ParseTimestamp parses a subtitle timestamp in hh:mm:ss.ttt or mm:ss.ttt form into seconds.
+// A comma is accepted as the decimal separator.
+func ParseTimestamp(value string) (float64, error) {
+	match := vttTimestampRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid timestamp '%s'", value)
+	}
+
+	hours := 0
+	if match[1] != "" {
+		hours, _ = strconv.Atoi(match[1])
+	}
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.Atoi(match[3])
+	if minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("invalid timestamp '%s'", value)
+	}
+
+	millis := 0
+	if match[4] != "" {
+		// Pad fractions like ".5" to milliseconds
+		millis, _ = strconv.Atoi((match[4] + "00")[:3])
+	}
+
+	return float64(hours*3600+minutes*60+seconds) + float64(millis)/1000, nil
+}
+
+// FormatVTTTimestamp formats seconds as a WebVTT timestamp (hh:mm:ss.ttt)
+func FormatVTTTimestamp(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	total := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", total/3600000, total/60000%60, total/1000%60, total%1000)
+}
+
+// stripCueMarkup removes tags from a cue payload, decodes character references
+// and returns the plain text along with the speaker of the first voice tag
+func stripCueMarkup(raw string) (string, string) {
+	speaker := ""
+	if match := vttVoiceRegex.FindStringSubmatch(raw); match != nil {
+		speaker = strings.TrimSpace(match[1])
+	}
+
+	text := vttTagRegex.ReplaceAllString(raw, "")
+	text = html.UnescapeString(text)
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+
+	return strings.Join(lines, "\n"), speaker
+}
@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// expectedCue is the part of a parsed cue a conformance case checks
+type expectedCue struct {
+	ID       string
+	Start    float64
+	End      float64
+	Settings map[string]string
+	Speaker  string
+	Text     string
+}
+
+func TestParseWebVTTConformance(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		header   string
+		cues     []expectedCue
+		styles   []string
+		regions  []string
+		notes    []string
+		warnings []string // Each is a substring of one of the document's warnings
+	}{
+		{
+			name:    "minimal file",
+			content: "WEBVTT\n\n00:00:01.000 --> 00:00:04.000\nKia ora\n",
+			cues:    []expectedCue{{Start: 1, End: 4, Text: "Kia ora"}},
+		},
+		{
+			name:    "byte order mark",
+			content: "\ufeffWEBVTT\n\n00:01.000 --> 00:02.000\nKia ora\n",
+			cues:    []expectedCue{{Start: 1, End: 2, Text: "Kia ora"}},
+		},
+		{
+			name:    "CRLF line endings",
+			content: "WEBVTT\r\n\r\n00:01.000 --> 00:02.000\r\nTēnā koe\r\nHow are you\r\n\r\n00:03.000 --> 00:04.000\r\nKa pai\r\n",
+			cues: []expectedCue{
+				{Start: 1, End: 2, Text: "Tēnā koe\nHow are you"},
+				{Start: 3, End: 4, Text: "Ka pai"},
+			},
+		},
+		{
+			name:    "CR line endings",
+			content: "WEBVTT\r\r00:01.000 --> 00:02.000\rTēnā koe\r\r00:03.000 --> 00:04.000\rKa pai\r",
+			cues: []expectedCue{
+				{Start: 1, End: 2, Text: "Tēnā koe"},
+				{Start: 3, End: 4, Text: "Ka pai"},
+			},
+		},
+		{
+			name:    "header text after the signature",
+			content: "WEBVTT - Te reo Māori\nKind: captions\n\n00:01.000 --> 00:02.000\nKia ora\n",
+			header:  "- Te reo Māori",
+			cues:    []expectedCue{{Start: 1, End: 2, Text: "Kia ora"}},
+		},
+		{
+			name: "NOTE, STYLE and REGION blocks",
+			content: "WEBVTT\n\n" +
+				"STYLE\n::cue { color: yellow }\n\n" +
+				"REGION\nid:speaker width:40%\nlines:3\n\n" +
+				"NOTE This is a comment\n\n" +
+				"NOTE\nspans\ntwo lines\n\n" +
+				"00:01.000 --> 00:02.000\nKia ora\n",
+			styles:  []string{"::cue { color: yellow }"},
+			regions: []string{"id:speaker width:40% lines:3"},
+			notes:   []string{"This is a comment", "spans\ntwo lines"},
+			cues:    []expectedCue{{Start: 1, End: 2, Text: "Kia ora"}},
+		},
+		{
+			name:     "STYLE after the first cue is ignored",
+			content:  "WEBVTT\n\n00:01.000 --> 00:02.000\nKia ora\n\nSTYLE\n::cue { color: red }\n",
+			cues:     []expectedCue{{Start: 1, End: 2, Text: "Kia ora"}},
+			warnings: []string{"STYLE block after the first cue is ignored"},
+		},
+		{
+			name:    "cue identifiers",
+			content: "WEBVTT\n\nintro\n00:01.000 --> 00:02.000\nKia ora\n\n2\n00:03.000 --> 00:04.000\nKa kite\n",
+			cues: []expectedCue{
+				{ID: "intro", Start: 1, End: 2, Text: "Kia ora"},
+				{ID: "2", Start: 3, End: 4, Text: "Ka kite"},
+			},
+		},
+		{
+			name:    "cue settings",
+			content: "WEBVTT\n\n00:01.000 --> 00:02.000 align:start line:0 position:10% size:50% vertical:rl region:speaker\nKia ora\n",
+			cues: []expectedCue{{
+				Start: 1, End: 2, Text: "Kia ora",
+				Settings: map[string]string{
+					"align": "start", "line": "0", "position": "10%", "size": "50%", "vertical": "rl", "region": "speaker",
+				},
+			}},
+		},
+		{
+			name:     "unknown and malformed cue settings are ignored",
+			content:  "WEBVTT\n\n00:01.000 --> 00:02.000 colour:red align: align:end\nKia ora\n",
+			cues:     []expectedCue{{Start: 1, End: 2, Text: "Kia ora", Settings: map[string]string{"align": "end"}}},
+			warnings: []string{"unknown cue setting 'colour'", "invalid cue setting 'align:'"},
+		},
+		{
+			name:    "voice tags",
+			content: "WEBVTT\n\n00:01.000 --> 00:02.000\n<v Hēmi>Kia ora</v>\n<v.loud Mere>Tēnā koe</v>\n",
+			cues:    []expectedCue{{Start: 1, End: 2, Speaker: "Hēmi", Text: "Kia ora\nTēnā koe"}},
+		},
+		{
+			name:    "formatting, class, ruby and timestamp tags",
+			content: "WEBVTT\n\n00:01.000 --> 00:05.000\n<i>Kia</i> <b>ora</b> <u>koutou</u> <c.yellow.bg_blue>katoa</c>\n<ruby>tēnā<rt>hello</rt></ruby> <00:00:03.000>koutou <lang mi>anō</lang>\n",
+			cues:    []expectedCue{{Start: 1, End: 5, Text: "Kia ora koutou katoa\ntēnāhello koutou anō"}},
+		},
+		{
+			name:    "character references",
+			content: "WEBVTT\n\n00:01.000 --> 00:02.000\nRock &amp; roll &lt;3&gt; &quot;yes&quot;&nbsp;now &#257;\n",
+			cues:    []expectedCue{{Start: 1, End: 2, Text: "Rock & roll <3> \"yes\" now ā"}},
+		},
+		{
+			name:    "multi-line cues",
+			content: "WEBVTT\n\n00:01.000 --> 00:04.000\nKo te reo\nte mauri\no te mana Māori\n",
+			cues:    []expectedCue{{Start: 1, End: 4, Text: "Ko te reo\nte mauri\no te mana Māori"}},
+		},
+		{
+			name:    "both timestamp forms",
+			content: "WEBVTT\n\n01:02.500 --> 01:04.000\nShort form\n\n01:00:00.000 --> 01:00:02.250\nLong form\n\n100:00:00.000 --> 100:00:01.000\nMany hours\n",
+			cues: []expectedCue{
+				{Start: 62.5, End: 64, Text: "Short form"},
+				{Start: 3600, End: 3602.25, Text: "Long form"},
+				{Start: 360000, End: 360001, Text: "Many hours"},
+			},
+		},
+		{
+			name:     "non-standard timestamps are accepted with a warning",
+			content:  "WEBVTT\n\n00:00:01,500 --> 0:0:2.5\nKia ora\n",
+			cues:     []expectedCue{{Start: 1.5, End: 2.5, Text: "Kia ora"}},
+			warnings: []string{"non-standard timestamp '00:00:01,500'", "non-standard timestamp '0:0:2.5'"},
+		},
+		{
+			name:     "missing signature",
+			content:  "00:01.000 --> 00:02.000\nKia ora\n",
+			cues:     []expectedCue{{Start: 1, End: 2, Text: "Kia ora"}},
+			warnings: []string{"missing WEBVTT signature"},
+		},
+		{
+			name:     "cue without a blank line after the header",
+			content:  "WEBVTT\n00:01.000 --> 00:02.000\nKia ora\n",
+			cues:     []expectedCue{{Start: 1, End: 2, Text: "Kia ora"}},
+			warnings: []string{"separated from the WEBVTT header"},
+		},
+		{
+			name:    "cues without a blank line between them",
+			content: "WEBVTT\n\n00:01.000 --> 00:02.000\nKia ora\n00:03.000 --> 00:04.000\nKa kite\n",
+			cues: []expectedCue{
+				{Start: 1, End: 2, Text: "Kia ora"},
+				{Start: 3, End: 4, Text: "Ka kite"},
+			},
+			warnings: []string{"missing a blank line before the next cue"},
+		},
+		{
+			name: "malformed timings are rejected",
+			content: "WEBVTT\n\n" +
+				"00:61.000 --> 00:62.000\nSeconds out of range\n\n" +
+				"00:01:60.000 --> 00:01:61.000\nSeconds out of range again\n\n" +
+				"00:60:00.000 --> 00:61:00.000\nMinutes out of range\n\n" +
+				"1:2:3:4.000 --> 00:05.000\nToo many parts\n\n" +
+				"00:01.000 -->\nNo end\n\n" +
+				"--> 00:02.000\nNo start\n\n" +
+				"aa:bb.ccc --> 00:02.000\nNot a number\n\n" +
+				"00:01.0000 --> 00:02.000\nToo many fraction digits\n\n" +
+				"00:03.000 --> 00:04.000\nKept\n",
+			cues: []expectedCue{{Start: 3, End: 4, Text: "Kept"}},
+			warnings: []string{
+				"invalid timestamp '00:61.000'",
+				"invalid timestamp '00:01:60.000'",
+				"invalid timestamp '00:60:00.000'",
+				"invalid timestamp '1:2:3:4.000'",
+				"invalid timing line '00:01.000 -->'",
+				"invalid timing line '--> 00:02.000'",
+				"invalid timestamp 'aa:bb.ccc'",
+				"invalid timestamp '00:01.0000'",
+			},
+		},
+		{
+			name:     "end before start is kept with a warning",
+			content:  "WEBVTT\n\n00:05.000 --> 00:04.000\nBackwards\n",
+			cues:     []expectedCue{{Start: 5, End: 4, Text: "Backwards"}},
+			warnings: []string{"is not after its start time"},
+		},
+		{
+			name:     "block without a timing line is skipped",
+			content:  "WEBVTT\n\njust some text\nand more\n\n00:01.000 --> 00:02.000\nKia ora\n",
+			cues:     []expectedCue{{Start: 1, End: 2, Text: "Kia ora"}},
+			warnings: []string{"block without a cue timing line skipped"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseWebVTT(tt.content)
+			if err != nil {
+				t.Fatalf("ParseWebVTT() error = %v", err)
+			}
+
+			if doc.Header != tt.header {
+				t.Errorf("Header = %q, want %q", doc.Header, tt.header)
+			}
+			if !reflect.DeepEqual(doc.Styles, tt.styles) {
+				t.Errorf("Styles = %q, want %q", doc.Styles, tt.styles)
+			}
+			if !reflect.DeepEqual(doc.Regions, tt.regions) {
+				t.Errorf("Regions = %q, want %q", doc.Regions, tt.regions)
+			}
+			if !reflect.DeepEqual(doc.Notes, tt.notes) {
+				t.Errorf("Notes = %q, want %q", doc.Notes, tt.notes)
+			}
+
+			if len(doc.Cues) != len(tt.cues) {
+				t.Fatalf("got %d cues, want %d: %+v (warnings %+v)", len(doc.Cues), len(tt.cues), doc.Cues, doc.Warnings)
+			}
+			for i, want := range tt.cues {
+				got := doc.Cues[i]
+				if got.Index != i+1 {
+					t.Errorf("cue %d: Index = %d", i, got.Index)
+				}
+				if got.ID != want.ID || got.StartTime != want.Start || got.EndTime != want.End ||
+					got.Speaker != want.Speaker || got.Text != want.Text {
+					t.Errorf("cue %d = {ID:%q Start:%v End:%v Speaker:%q Text:%q}, want %+v",
+						i, got.ID, got.StartTime, got.EndTime, got.Speaker, got.Text, want)
+				}
+				if !reflect.DeepEqual(got.Settings, want.Settings) {
+					t.Errorf("cue %d: Settings = %v, want %v", i, got.Settings, want.Settings)
+				}
+			}
+
+			if tt.warnings == nil && len(doc.Warnings) > 0 {
+				t.Errorf("unexpected warnings %+v", doc.Warnings)
+			}
+			for _, want := range tt.warnings {
+				found := false
+				for _, warning := range doc.Warnings {
+					if strings.Contains(warning.Message, want) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("no warning containing %q in %+v", want, doc.Warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestParseWebVTTRejectsInvalidUTF8(t *testing.T) {
+	if _, err := ParseWebVTT("WEBVTT\n\n00:01.000 --> 00:02.000\n\xff\xfe\n"); err == nil {
+		t.Fatal("ParseWebVTT() accepted invalid UTF-8")
+	}
+}
+
+func TestParseVTTToLinesJoinsMultiLineCues(t *testing.T) {
+	lines, err := ParseVTTToLines("WEBVTT\n\n00:01.000 --> 00:03.000\n<v Hēmi>Kia ora</v>\ne hoa\n\n00:04.000 --> 00:05.000\n<i></i>\n")
+	if err != nil {
+		t.Fatalf("ParseVTTToLines() error = %v", err)
+	}
+
+	want := []TranscriptLine{{StartTime: 1, EndTime: 3, Text: "Kia ora e hoa"}}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("ParseVTTToLines() = %+v, want %+v", lines, want)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+		ok    bool
+	}{
+		{"00:01.000", 1, true},
+		{"01:02.5", 62.5, true},
+		{"00:00:01.000", 1, true},
+		{"10:00:00.001", 36000.001, true},
+		{"00:00:01,250", 1.25, true},
+		{"00:01", 1, true},
+		{"00:60.000", 0, false},
+		{"60:00.000", 0, false},
+		{"1:2:3:4", 0, false},
+		{"", 0, false},
+		{"1.000", 0, false},
+		{"-00:01.000", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseTimestamp(%q) error = %v, want ok %v", tt.value, err, tt.ok)
+			continue
+		}
+		if tt.ok && got != tt.want {
+			t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFormatVTTTimestamp(t *testing.T) {
+	tests := map[float64]string{
+		0:         "00:00:00.000",
+		1.5:       "00:00:01.500",
+		62.25:     "00:01:02.250",
+		3661.001:  "01:01:01.001",
+		-3:        "00:00:00.000",
+		360000.75: "100:00:00.750",
+	}
+	for seconds, want := range tests {
+		if got := FormatVTTTimestamp(seconds); got != want {
+			t.Errorf("FormatVTTTimestamp(%v) = %q, want %q", seconds, got, want)
+		}
+	}
+}
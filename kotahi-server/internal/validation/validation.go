@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -41,14 +42,64 @@ func ValidateVideoRequest(req *models.VideoRequest) *errors.ValidationErrors {
 		ve.Add("duration", "Duration must be in MM:SS or HH:MM:SS format")
 	}
 
-	// Validate subtitle path (optional but if provided, should start with /)
-	if strings.TrimSpace(req.Subtitle) == "" {
+	// Validate tags and series (optional)
+	if len(req.Tags) > 20 {
+		ve.Add("tags", "A video can have at most 20 tags")
+	}
+	for _, tag := range req.Tags {
+		if len(tag) > 50 {
+			ve.Add("tags", "Tags must be less than 50 characters")
+			break
+		}
+	}
+	if len(req.Series) > 200 {
+		ve.Add("series", "Series must be less than 200 characters")
+	}
+
+	// Validate subtitles, either a single subtitle URL or a list of tracks is required
+	if len(req.Tracks) > 0 {
+		validateSubtitleTracks(req.Tracks, ve)
+	} else if strings.TrimSpace(req.Subtitle) == "" {
 		ve.Add("subtitle", "Subtitle URL is required")
 	}
 
 	return ve
 }
 
+// validateSubtitleTracks validates the subtitle tracks of a video request
+func validateSubtitleTracks(tracks []models.SubtitleTrack, ve *errors.ValidationErrors) {
+	defaults := 0
+	for i, track := range tracks {
+		field := fmt.Sprintf("tracks[%d]", i)
+
+		if !isValidLanguageCode(track.Language) {
+			ve.Add(field+".language", "Language must be a valid language code, e.g. 'mi' or 'en'")
+		}
+
+		if strings.TrimSpace(track.Label) == "" {
+			ve.Add(field+".label", "Label is required")
+		} else if len(track.Label) > 100 {
+			ve.Add(field+".label", "Label must be less than 100 characters")
+		}
+
+		if track.Kind != "" && track.Kind != models.SubtitleKindSubtitles && track.Kind != models.SubtitleKindCaptions {
+			ve.Add(field+".kind", "Kind must be 'subtitles' or 'captions'")
+		}
+
+		if strings.TrimSpace(track.URL) == "" {
+			ve.Add(field+".url", "Subtitle URL is required")
+		}
+
+		if track.Default {
+			defaults++
+		}
+	}
+
+	if defaults > 1 {
+		ve.Add("tracks", "Only one track can be the default")
+	}
+}
+
 // ValidateVideoID validates a video ID
 func ValidateVideoID(id string) *errors.ValidationErrors {
 	ve := &errors.ValidationErrors{}
@@ -62,9 +113,14 @@ func ValidateVideoID(id string) *errors.ValidationErrors {
 	return ve
 }
 
-// isValidURL performs basic URL validation
+// isValidURL performs basic URL validation, allowing paths to media hosted by this server
 func isValidURL(url string) bool {
-	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || isHostedMediaPath(url)
+}
+
+// isHostedMediaPath checks whether the URL points at a media file uploaded to this server
+func isHostedMediaPath(url string) bool {
+	return strings.HasPrefix(url, "/api/v1/media/") && len(url) > len("/api/v1/media/")
 }
 
 // isValidDuration validates duration format (MM:SS or HH:MM:SS)
@@ -148,6 +204,12 @@ func isValidEmail(email string) bool {
 	return emailRegex.MatchString(email)
 }
 
+// isValidLanguageCode validates a BCP 47 style language code such as "mi", "en" or "en-NZ"
+func isValidLanguageCode(code string) bool {
+	languageRegex := regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	return languageRegex.MatchString(code)
+}
+
 // isValidUsername validates username format (alphanumeric and underscores only)
 func isValidUsername(username string) bool {
 	usernameRegex := regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
@@ -179,9 +241,95 @@ func ValidateVocabularyRequest(req *models.VocabularyRequest) *errors.Validation
 		ve.Add("description", "Description must be less than 1000 characters")
 	}
 
+	for _, e := range ValidateVocabularyDetails(req).Errors {
+		ve.Add(e.Field, e.Message)
+	}
+
+	return ve
+}
+
+// maxVocabularyListItems limits each list on a vocabulary entry, such as variants or examples
+const maxVocabularyListItems = 20
+
+// ValidateVocabularyDetails validates the optional fields of a vocabulary request
+func ValidateVocabularyDetails(req *models.VocabularyRequest) *errors.ValidationErrors {
+	ve := &errors.ValidationErrors{}
+
+	if req.PartOfSpeech != "" && !isValidPartOfSpeech(req.PartOfSpeech) {
+		ve.Add("part_of_speech", "Part of speech must be one of: "+strings.Join(models.PartsOfSpeech, ", "))
+	}
+
+	validateTextList(ve, "variants", "Variant", req.Variants, 200)
+	for _, variant := range req.Variants {
+		if strings.EqualFold(strings.TrimSpace(variant), strings.TrimSpace(req.Maori)) {
+			ve.Add("variants", "Variants must differ from the headword")
+			break
+		}
+	}
+
+	if len(req.Forms) > maxVocabularyListItems {
+		ve.Add("forms", fmt.Sprintf("At most %d forms are allowed", maxVocabularyListItems))
+	}
+	for i, form := range req.Forms {
+		if strings.TrimSpace(form.Kind) == "" || len(form.Kind) > 50 {
+			ve.Add(fmt.Sprintf("forms[%d].kind", i), "Form kind is required and must be less than 50 characters")
+		}
+		if strings.TrimSpace(form.Form) == "" || len(form.Form) > 200 {
+			ve.Add(fmt.Sprintf("forms[%d].form", i), "Form is required and must be less than 200 characters")
+		}
+	}
+
+	validateTextList(ve, "senses", "Sense", req.Senses, 200)
+
+	if len(req.Examples) > maxVocabularyListItems {
+		ve.Add("examples", fmt.Sprintf("At most %d examples are allowed", maxVocabularyListItems))
+	}
+	for i, example := range req.Examples {
+		if strings.TrimSpace(example.Maori) == "" || len(example.Maori) > 500 {
+			ve.Add(fmt.Sprintf("examples[%d].maori", i), "Example sentence is required and must be less than 500 characters")
+		}
+		if len(example.English) > 500 {
+			ve.Add(fmt.Sprintf("examples[%d].english", i), "Example translation must be less than 500 characters")
+		}
+	}
+
+	validateTextList(ve, "tags", "Tag", req.Tags, 50)
+
+	if req.Difficulty < 0 || req.Difficulty > 5 {
+		ve.Add("difficulty", "Difficulty must be between 1 and 5, or 0 when not set")
+	}
+
+	if req.AudioURL != "" && !isValidURL(req.AudioURL) {
+		ve.Add("audio_url", "Audio URL must be a valid URL")
+	}
+
 	return ve
 }
 
+// validateTextList checks a list of short strings for empty or overlong items
+func validateTextList(ve *errors.ValidationErrors, field, label string, items []string, maxLength int) {
+	if len(items) > maxVocabularyListItems {
+		ve.Add(field, fmt.Sprintf("At most %d items are allowed", maxVocabularyListItems))
+	}
+	for i, item := range items {
+		if strings.TrimSpace(item) == "" {
+			ve.Add(fmt.Sprintf("%s[%d]", field, i), label+" must not be empty")
+		} else if len(item) > maxLength {
+			ve.Add(fmt.Sprintf("%s[%d]", field, i), fmt.Sprintf("%s must be less than %d characters", label, maxLength))
+		}
+	}
+}
+
+// isValidPartOfSpeech checks a part of speech against the accepted values
+func isValidPartOfSpeech(partOfSpeech string) bool {
+	for _, known := range models.PartsOfSpeech {
+		if partOfSpeech == known {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateVocabularyID validates a vocabulary ID
 func ValidateVocabularyID(id string) *errors.ValidationErrors {
 	ve := &errors.ValidationErrors{}